@@ -2,11 +2,12 @@ package cmd
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/ssoor/sql-calculator/diff"
 	"github.com/ssoor/sql-calculator/utils"
+	"github.com/ssoor/sql-calculator/virtualdb"
 
 	"github.com/spf13/cobra"
 )
@@ -19,18 +20,20 @@ var DiffCmd = &cobra.Command{
 		"Output:\n   ALTER TABLE `qk_t2` COMMENT = '注释被修改'",
 	Long: "SQL Diff - Compare the differences between the two SQL content and output the synchronization script",
 	Run: func(cmd *cobra.Command, args []string) {
-		sourceSql, err := ioutil.ReadFile(args[0])
+		sourceFile, err := os.Open(args[0])
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		targetSql, err := ioutil.ReadFile(args[1])
+		defer sourceFile.Close()
+		targetFile, err := os.Open(args[1])
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
+		defer targetFile.Close()
 
-		alters, err := diff.GetDiffFromSqlFile("", string(sourceSql), string(targetSql))
+		alters, err := diff.GetDiffFromReader("", sourceFile, targetFile)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
@@ -39,6 +42,11 @@ var DiffCmd = &cobra.Command{
 		modifySql := ""
 		for _, alter := range alters {
 			sql, _ := utils.RestoreToSql(alter)
+			// 触发器、存储过程等的语句体中有分号时需要修改分隔符
+			if _, ok := alter.(*virtualdb.ObjectStmt); ok && strings.Contains(sql, ";") {
+				modifySql += "DELIMITER ;;\n" + sql + ";;\nDELIMITER ;\n"
+				continue
+			}
 			modifySql += sql + ";\n"
 		}
 		fmt.Println(modifySql)
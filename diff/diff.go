@@ -1,6 +1,9 @@
 package diff
 
 import (
+	"io"
+	"strings"
+
 	"github.com/ssoor/sql-calculator/utils"
 	"github.com/ssoor/sql-calculator/virtualdb"
 
@@ -14,23 +17,107 @@ func GetDiffFromSqlFile(dbName, sourceSqlFile, targetSqlFile string, ignores ...
 	return GetDiffSQLWithOpt(dbName, sourceSqlFile, targetSqlFile, DiffOption{IgnoreOpts: ignores})
 }
 
+// GetDiffFromReader 与 GetDiffFromSqlFile 相同，但逐条读取并执行语句，适用于很大的 SQL 文件和 mysqldump 导出文件
+func GetDiffFromReader(dbName string, source, target io.Reader, ignores ...DiffIgnoreType) ([]ast.StmtNode, error) {
+	ignores = append(ignores, DefaultDiffIgnoreTypes...)
+
+	return GetDiffReaderWithOpt(dbName, source, target, DiffOption{IgnoreOpts: ignores})
+}
+
+func GetDiffReaderWithOpt(dbName string, source, target io.Reader, opt DiffOption) ([]ast.StmtNode, error) {
+	sourceDb := virtualdb.NewVirtualDBWithOpt(dbName, opt.dbOption())
+	if err := sourceDb.ExecReader(source, virtualdb.ExecOption{}); err != nil {
+		return nil, err
+	}
+
+	targetDb := virtualdb.NewVirtualDBWithOpt(dbName, opt.dbOption())
+	if err := targetDb.ExecReader(target, virtualdb.ExecOption{}); err != nil {
+		return nil, err
+	}
+
+	return GetDiffDBWithOpt(dbName, sourceDb, targetDb, opt), nil
+}
+
 func GetDiffSQLWithOpt(dbName, sourceSqlFile, targetSqlFile string, opt DiffOption) ([]ast.StmtNode, error) {
-	sourceDb := virtualdb.NewVirtualDB(dbName)
+	sourceDb := virtualdb.NewVirtualDBWithOpt(dbName, opt.dbOption())
 	if err := sourceDb.ExecSQL(sourceSqlFile); err != nil {
 		return nil, err
 	}
 
-	targetDb := virtualdb.NewVirtualDB(dbName)
+	targetDb := virtualdb.NewVirtualDBWithOpt(dbName, opt.dbOption())
 	if err := targetDb.ExecSQL(targetSqlFile); err != nil {
 		return nil, err
 	}
 
+	return GetDiffDBWithOpt(dbName, sourceDb, targetDb, opt), nil
+}
+
+// GetDiffDBWithOpt 对比两个虚拟库中 dbName 库的表结构、视图、触发器、存储过程、函数和事件，返回将 sourceDb 同步为 targetDb 的语句
+// 删除视图和对象的语句在表结构变更之前，创建的语句在之后
+func GetDiffDBWithOpt(dbName string, sourceDb, targetDb *virtualdb.VirtualDB, opt DiffOption) []ast.StmtNode {
+	opt.sourceSchema, _ = sourceDb.GetSchema(dbName)
+	opt.targetSchema, _ = targetDb.GetSchema(dbName)
+	if opt.ServerVersion <= 0 {
+		opt.ServerVersion = targetDb.ServerVersion()
+	}
+
+	allDDL := []ast.StmtNode{}
+	if alter := GetDiffDatabase(opt.sourceSchema, opt.targetSchema, opt); alter != nil {
+		allDDL = append(allDDL, alter)
+	}
+
+	objects := getDiffObjects(dbName, sourceDb, targetDb, opt)
+	allDDL = append(allDDL, objects.drops...)
+	allDDL = append(allDDL, getDiffTables(dbName, sourceDb, targetDb, opt)...)
+
+	return append(allDDL, objects.creates...)
+}
+
+// GetDiffDatabase 对比库生效的字符集、排序规则和加密选项，不同时返回 ALTER DATABASE，库名以源库为准
+func GetDiffDatabase(sourceSchema, targetSchema *ast.CreateDatabaseStmt, opt DiffOption) ast.StmtNode {
+	if opt.Has(DiffIgnoreDatabaseOption) || sourceSchema == nil || targetSchema == nil {
+		return nil
+	}
+
+	options := []*ast.DatabaseOption{}
+	sourceCharset, sourceCollation := virtualdb.SchemaCharset(sourceSchema, opt.serverVersion())
+	targetCharset, targetCollation := virtualdb.SchemaCharset(targetSchema, opt.serverVersion())
+	if sourceCharset != targetCharset || sourceCollation != targetCollation {
+		options = append(options,
+			&ast.DatabaseOption{Tp: ast.DatabaseOptionCharset, Value: targetCharset},
+			&ast.DatabaseOption{Tp: ast.DatabaseOptionCollate, Value: targetCollation},
+		)
+	}
+	if encryption := databaseEncryption(targetSchema); databaseEncryption(sourceSchema) != encryption {
+		options = append(options, &ast.DatabaseOption{Tp: ast.DatabaseOptionEncryption, Value: encryption})
+	}
+
+	if len(options) == 0 {
+		return nil
+	}
+	return &ast.AlterDatabaseStmt{Name: sourceSchema.Name, Options: options}
+}
+
+// databaseEncryption 返回库的加密选项，未指定时为 N
+func databaseEncryption(schema *ast.CreateDatabaseStmt) string {
+	for _, dbOpt := range schema.Options {
+		if dbOpt.Tp == ast.DatabaseOptionEncryption {
+			return strings.ToUpper(dbOpt.Value)
+		}
+	}
+
+	return "N"
+}
+
+// getDiffTables 对比两个虚拟库中 dbName 库的表结构
+func getDiffTables(dbName string, sourceDb, targetDb *virtualdb.VirtualDB, opt DiffOption) []ast.StmtNode {
 	allDDL := []ast.StmtNode{}
 	sourceTables, _ := sourceDb.GetTableStmts(dbName)
 	targetTables, _ := targetDb.GetTableStmts(dbName)
 
 	for name, sourceTable := range sourceTables {
 		var alter ast.StmtNode
+		var partitions []ast.StmtNode
 
 		targetTable, exist := targetTables[name]
 		if !exist {
@@ -41,18 +128,18 @@ func GetDiffSQLWithOpt(dbName, sourceSqlFile, targetSqlFile string, opt DiffOpti
 			delete(targetTables, name) // 存在，从目标中删除并处理差异
 			if !opt.Has(DiffIgnoreTableDiff) {
 				alter = GetDiffTable(sourceTable.Table, targetTable.Table, opt)
+				partitions = GetDiffPartition(sourceTable.Table, targetTable.Table, opt)
 			}
 		}
 
-		if alter == nil {
-			continue
+		if alter != nil {
+			allDDL = append(allDDL, alter)
 		}
-
-		allDDL = append(allDDL, alter)
+		allDDL = append(allDDL, partitions...)
 	}
 
 	if opt.Has(DiffIgnoreTableAppend) {
-		return allDDL, nil
+		return allDDL
 	}
 
 	// 创建剩余的表
@@ -60,16 +147,26 @@ func GetDiffSQLWithOpt(dbName, sourceSqlFile, targetSqlFile string, opt DiffOpti
 		allDDL = append(allDDL, table.Table)
 	}
 
-	return allDDL, nil
+	return allDDL
 }
 
 func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption) ast.StmtNode {
+	// 字段上的主键与表上的主键相同
+	sourceTable, targetTable = virtualdb.NormalizePrimaryKey(sourceTable), virtualdb.NormalizePrimaryKey(targetTable)
+
 	columnMap := make(map[string]*ast.ColumnDef)
 	for _, col := range targetTable.Cols {
 		columnMap[col.Name.Name.String()] = col
 	}
 
+	// 忽略表字符集时，源字段按目标表计算继承的字符集，只对比显式指定的差异
+	sourceSchema, sourceInherit, targetSchema := opt.sourceSchema, sourceTable, opt.targetSchema
+	if opt.Has(DiffIgnoreTableOptionCharset) {
+		sourceSchema, sourceInherit = targetSchema, targetTable
+	}
+
 	alterSpecs := []*ast.AlterTableSpec{}
+	modifyColumns := make(map[string]struct{})
 	removeColumns := []*ast.ColumnDef{}
 	for _, sourceCol := range sourceTable.Cols {
 		targetName := ""
@@ -88,12 +185,27 @@ func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption)
 			continue
 		}
 
-		if compareColumn(col, sourceCol, opt) {
+		if compareColumn(normalizeColumn(targetSchema, targetTable, col, opt),
+			normalizeColumn(sourceSchema, sourceInherit, sourceCol, opt), opt) {
 			continue
 		}
 
 		if !opt.Has(DiffIgnoreColumnDiff) {
 			// fmt.Printf("DIFF: %s.%s\n", sourceTable.Table.Name.String(), col.Name.Name.String())
+			modifyColumns[col.Name.Name.L] = struct{}{}
+
+			// MySQL 不能修改虚拟列的 STORED 属性，需要删除后在原位置重新添加
+			if isGeneratedKindChanged(sourceCol, col) {
+				alterSpecs = append(alterSpecs, &ast.AlterTableSpec{
+					Tp:            ast.AlterTableDropColumn,
+					OldColumnName: sourceCol.Name,
+				}, &ast.AlterTableSpec{
+					Tp:         ast.AlterTableAddColumns,
+					NewColumns: []*ast.ColumnDef{col},
+					Position:   columnPosition(targetTable, col),
+				})
+				continue
+			}
 
 			alterSpecs = append(alterSpecs, &ast.AlterTableSpec{
 				Tp:         ast.AlterTableModifyColumn,
@@ -139,19 +251,19 @@ func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption)
 
 	constraintMap := make(map[string]*ast.Constraint)
 	for _, con := range targetTable.Constraints {
-		constraintMap[con.Name] = con
+		constraintMap[constraintName(con)] = con
 	}
 
 	removeIndexs := []*ast.Constraint{}
 	for _, sourceCon := range sourceTable.Constraints {
 		targetName := ""
-		con, exist := constraintMap[sourceCon.Name]
+		con, exist := constraintMap[constraintName(sourceCon)]
 		if exist {
-			targetName = con.Name
-			delete(constraintMap, sourceCon.Name) // 存在，从目标中删除并处理差异
+			targetName = constraintName(con)
+			delete(constraintMap, targetName) // 存在，从目标中删除并处理差异
 		}
 
-		if !opt.IndexNameDiff(sourceCon.Name, targetName) {
+		if !opt.IndexNameDiff(constraintName(sourceCon), targetName) {
 			continue
 		}
 
@@ -164,6 +276,18 @@ func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption)
 			continue
 		}
 
+		// 只有 ENFORCED 不同的 CHECK 约束可以直接修改
+		if sourceCon.Tp == ast.ConstraintCheck && con.Tp == ast.ConstraintCheck && sourceCon.Enforced != con.Enforced {
+			enforced := *sourceCon
+			enforced.Enforced = con.Enforced
+			if compareConstraint(&enforced, con, opt) {
+				if !opt.Has(DiffIgnoreIndexDiff) {
+					alterSpecs = append(alterSpecs, &ast.AlterTableSpec{Tp: ast.AlterTableAlterCheck, Constraint: con})
+				}
+				continue
+			}
+		}
+
 		addDDL := &ast.AlterTableSpec{
 			Tp:         ast.AlterTableAddConstraint,
 			Constraint: con,
@@ -178,9 +302,14 @@ func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption)
 			delDDL = &ast.AlterTableSpec{
 				Tp: ast.AlterTableDropPrimaryKey,
 			}
+		case ast.ConstraintCheck:
+			delDDL = dropConstraintSpec(sourceCon)
 		}
 
 		if !opt.Has(DiffIgnoreIndexDiff) {
+			if sourceCon.Tp == ast.ConstraintPrimaryKey {
+				alterSpecs = append(alterSpecs, autoIncrementSpecs(sourceTable, targetTable, modifyColumns)...)
+			}
 			alterSpecs = append(alterSpecs, delDDL)
 			alterSpecs = append(alterSpecs, addDDL)
 		}
@@ -191,19 +320,22 @@ func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption)
 		for _, con := range removeIndexs {
 			// fmt.Printf("DEL: %s.%s\n", sourceTable.Table.Name.String(), col.Name.Name.String())
 
-			alterSpecs = append(alterSpecs, &ast.AlterTableSpec{
-				Tp:   ast.AlterTableDropIndex,
-				Name: con.Name,
-			})
+			if con.Tp == ast.ConstraintPrimaryKey {
+				alterSpecs = append(alterSpecs, autoIncrementSpecs(sourceTable, targetTable, modifyColumns)...)
+			}
+			alterSpecs = append(alterSpecs, dropConstraintSpec(con))
 		}
 	}
 
 	// 创建剩余的约束
 	if !opt.Has(DiffIgnoreIndexAppend) {
 		for _, con := range constraintMap {
-			if !opt.IndexNameDiff("", con.Name) {
+			if !opt.IndexNameDiff("", constraintName(con)) {
 				continue
 			}
+			if con.Tp == ast.ConstraintPrimaryKey {
+				alterSpecs = append(alterSpecs, autoIncrementSpecs(sourceTable, targetTable, modifyColumns)...)
+			}
 
 			alterSpecs = append(alterSpecs, &ast.AlterTableSpec{
 				Tp:         ast.AlterTableAddConstraint,
@@ -212,10 +344,11 @@ func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption)
 		}
 	}
 
-	if !compareTableOptions(targetTable, sourceTable, opt) {
+	charsetDiff := !compareTableCharset(sourceTable, targetTable, opt)
+	if !compareTableOptions(targetTable, sourceTable, opt) || charsetDiff {
 		alterSpecs = append(alterSpecs, &ast.AlterTableSpec{
 			Tp:      ast.AlterTableOption,
-			Options: targetTable.Options,
+			Options: tableOptionsWithCharset(targetTable, charsetDiff, opt),
 		})
 	}
 
@@ -223,12 +356,132 @@ func GetDiffTable(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption)
 		return nil
 	}
 
+	// 修改源库中已有的表，表名以源库为准
 	return &ast.AlterTableStmt{
-		Table: targetTable.Table,
+		Table: sourceTable.Table,
 		Specs: alterSpecs,
 	}
 }
 
+// constraintName 返回约束的名称，主键的名称总是 PRIMARY
+func constraintName(con *ast.Constraint) string {
+	if con.Tp == ast.ConstraintPrimaryKey {
+		return "PRIMARY"
+	}
+
+	return con.Name
+}
+
+// dropConstraintSpec 返回删除约束的语句，主键使用 DROP PRIMARY KEY，CHECK 约束使用 DROP CHECK，其余约束按索引删除
+func dropConstraintSpec(con *ast.Constraint) *ast.AlterTableSpec {
+	switch con.Tp {
+	case ast.ConstraintPrimaryKey:
+		return &ast.AlterTableSpec{Tp: ast.AlterTableDropPrimaryKey}
+	case ast.ConstraintCheck:
+		return &ast.AlterTableSpec{
+			Tp:         ast.AlterTableDropCheck,
+			Constraint: &ast.Constraint{Name: con.Name},
+		}
+	}
+
+	return &ast.AlterTableSpec{
+		Tp:   ast.AlterTableDropIndex,
+		Name: con.Name,
+	}
+}
+
+// autoIncrementSpecs 返回主键变化时需要同时修改的字段。MySQL 要求 AUTO_INCREMENT 字段是索引的第一个字段，
+// 所以 AUTO_INCREMENT 不同的主键字段需要和主键在同一条语句中修改，即使字段差异被忽略
+func autoIncrementSpecs(sourceTable, targetTable *ast.CreateTableStmt, modifyColumns map[string]struct{}) []*ast.AlterTableSpec {
+	pkColumns := make(map[string]struct{})
+	for _, table := range []*ast.CreateTableStmt{sourceTable, targetTable} {
+		for _, con := range table.Constraints {
+			if con.Tp != ast.ConstraintPrimaryKey {
+				continue
+			}
+			for _, key := range con.Keys {
+				if key.Column != nil {
+					pkColumns[key.Column.Name.L] = struct{}{}
+				}
+			}
+		}
+	}
+
+	sourceCols := make(map[string]*ast.ColumnDef)
+	for _, col := range sourceTable.Cols {
+		sourceCols[col.Name.Name.L] = col
+	}
+
+	specs := []*ast.AlterTableSpec{}
+	for _, col := range targetTable.Cols {
+		sourceCol, exist := sourceCols[col.Name.Name.L]
+		if _, isPk := pkColumns[col.Name.Name.L]; !exist || !isPk {
+			continue
+		}
+		if _, modified := modifyColumns[col.Name.Name.L]; modified || isAutoIncrement(sourceCol) == isAutoIncrement(col) {
+			continue
+		}
+
+		modifyColumns[col.Name.Name.L] = struct{}{}
+		specs = append(specs, &ast.AlterTableSpec{
+			Tp:         ast.AlterTableModifyColumn,
+			NewColumns: []*ast.ColumnDef{col},
+			Position:   &ast.ColumnPosition{Tp: ast.ColumnPositionNone},
+		})
+	}
+
+	return specs
+}
+
+func isAutoIncrement(col *ast.ColumnDef) bool {
+	for _, columnOpt := range col.Options {
+		if columnOpt.Tp == ast.ColumnOptionAutoIncrement {
+			return true
+		}
+	}
+
+	return false
+}
+
+// generatedOption 返回字段的 GENERATED ALWAYS AS 选项，不是生成列时返回 nil
+func generatedOption(col *ast.ColumnDef) *ast.ColumnOption {
+	for _, columnOpt := range col.Options {
+		if columnOpt.Tp == ast.ColumnOptionGenerated {
+			return columnOpt
+		}
+	}
+
+	return nil
+}
+
+// isGeneratedKindChanged 判断字段是否在虚拟列与存储列或普通字段之间转换
+func isGeneratedKindChanged(source, target *ast.ColumnDef) bool {
+	sourceOpt, targetOpt := generatedOption(source), generatedOption(target)
+	isVirtual := func(columnOpt *ast.ColumnOption) bool {
+		return columnOpt != nil && !columnOpt.Stored
+	}
+	if !isVirtual(sourceOpt) && !isVirtual(targetOpt) {
+		return false
+	}
+
+	return sourceOpt == nil || targetOpt == nil || sourceOpt.Stored != targetOpt.Stored
+}
+
+// columnPosition 返回字段在目标表中的位置
+func columnPosition(table *ast.CreateTableStmt, col *ast.ColumnDef) *ast.ColumnPosition {
+	for i, tableCol := range table.Cols {
+		if tableCol != col {
+			continue
+		}
+		if i == 0 {
+			return &ast.ColumnPosition{Tp: ast.ColumnPositionFirst}
+		}
+		return &ast.ColumnPosition{Tp: ast.ColumnPositionAfter, RelativeColumn: table.Cols[i-1].Name}
+	}
+
+	return &ast.ColumnPosition{Tp: ast.ColumnPositionNone}
+}
+
 func compareTableOptions(source, target *ast.CreateTableStmt, opt DiffOption) bool {
 	rawOpts := [][]*ast.TableOption{
 		source.Options,
@@ -238,7 +491,7 @@ func compareTableOptions(source, target *ast.CreateTableStmt, opt DiffOption) bo
 	restoreOpts := make([][]*ast.TableOption, len(rawOpts))
 	for i, opts := range rawOpts {
 		for _, tableOpt := range opts {
-			if opt.HasIgnoreTableOption(tableOpt.Tp) {
+			if opt.HasIgnoreTableOption(tableOpt.Tp) || isCharsetTableOption(tableOpt) {
 				continue
 			}
 
@@ -246,12 +499,82 @@ func compareTableOptions(source, target *ast.CreateTableStmt, opt DiffOption) bo
 		}
 	}
 
+	// 只比较表选项，表名可能只有大小写不同
 	s, _ := utils.RestoreToSql(&ast.CreateTableStmt{Table: source.Table, Options: restoreOpts[0]})
-	t, _ := utils.RestoreToSql(&ast.CreateTableStmt{Table: target.Table, Options: restoreOpts[1]})
+	t, _ := utils.RestoreToSql(&ast.CreateTableStmt{Table: source.Table, Options: restoreOpts[1]})
 
 	return s == t
 }
 
+// isCharsetTableOption 字符集和排序规则由 compareTableCharset 按生效值对比
+func isCharsetTableOption(tableOpt *ast.TableOption) bool {
+	return tableOpt.Tp == ast.TableOptionCharset || tableOpt.Tp == ast.TableOptionCollate
+}
+
+// compareTableCharset 对比表生效的字符集和排序规则，未指定时继承自库或服务器默认值
+func compareTableCharset(source, target *ast.CreateTableStmt, opt DiffOption) bool {
+	if opt.Has(DiffIgnoreTableOptionCharset) {
+		return true
+	}
+
+	sourceCharset, sourceCollation := virtualdb.TableCharset(opt.sourceSchema, source, opt.serverVersion())
+	targetCharset, targetCollation := virtualdb.TableCharset(opt.targetSchema, target, opt.serverVersion())
+
+	return sourceCharset == targetCharset && sourceCollation == targetCollation
+}
+
+// tableOptionsWithCharset 返回表选项，字符集不同时显式指定生效的字符集和排序规则，相同时不修改字符集
+func tableOptionsWithCharset(table *ast.CreateTableStmt, charsetDiff bool, opt DiffOption) []*ast.TableOption {
+	if opt.Has(DiffIgnoreTableOptionCharset) {
+		return table.Options
+	}
+
+	options := []*ast.TableOption{}
+	for _, tableOpt := range table.Options {
+		if !isCharsetTableOption(tableOpt) {
+			options = append(options, tableOpt)
+		}
+	}
+
+	if !charsetDiff {
+		return options
+	}
+	charset, collation := virtualdb.TableCharset(opt.targetSchema, table, opt.serverVersion())
+	return append(options,
+		&ast.TableOption{Tp: ast.TableOptionCharset, StrValue: charset},
+		&ast.TableOption{Tp: ast.TableOptionCollate, StrValue: collation},
+	)
+}
+
+// normalizeColumn 返回字段在对比的服务器中的规范形式，等价的定义如 INT(11) 与 INT、DEFAULT '0' 与 DEFAULT 0 不会产生差异
+func normalizeColumn(schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt, col *ast.ColumnDef,
+	opt DiffOption) *ast.ColumnDef {
+
+	return virtualdb.NormalizeColumn(columnWithCharset(schema, table, col, opt), opt.Flavor, opt.serverVersion())
+}
+
+// columnWithCharset 返回字段的浅拷贝，字符集和排序规则替换为生效值，使隐式继承与显式指定相同值的字段相等
+func columnWithCharset(schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt, col *ast.ColumnDef,
+	opt DiffOption) *ast.ColumnDef {
+
+	charset, collation := virtualdb.ColumnCharset(schema, table, col, opt.serverVersion())
+	if charset == "" {
+		return col
+	}
+
+	tp := *col.Tp
+	tp.Charset, tp.Collate = charset, collation
+
+	options := []*ast.ColumnOption{}
+	for _, columnOpt := range col.Options {
+		if columnOpt.Tp != ast.ColumnOptionCollate {
+			options = append(options, columnOpt)
+		}
+	}
+
+	return &ast.ColumnDef{Name: col.Name, Tp: &tp, Options: options}
+}
+
 func compareColumn(source, target *ast.ColumnDef, opt DiffOption) bool {
 	rawOpts := [][]*ast.ColumnOption{
 		source.Options,
@@ -2,11 +2,31 @@ package diff
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/ssoor/sql-calculator/utils"
+	"github.com/ssoor/sql-calculator/virtualdb"
+
+	"github.com/stretchr/testify/assert"
 )
 
+// diffSQL 在库 db1 中执行 source 和 target，返回两者差异的语句
+func diffSQL(t *testing.T, source, target string, ignores ...DiffIgnoreType) []string {
+	return diffSQLWithOpt(t, source, target, DiffOption{IgnoreOpts: ignores})
+}
+
+func diffSQLWithOpt(t *testing.T, source, target string, opt DiffOption) []string {
+	alters, err := GetDiffSQLWithOpt("db1", source, target, opt)
+	assert.NoError(t, err)
+	actual := []string{}
+	for _, alter := range alters {
+		sql, _ := utils.RestoreToSql(alter)
+		actual = append(actual, sql)
+	}
+	return actual
+}
+
 func TestGetDiffFromSqlFile(t *testing.T) {
 	sourceTable := `
 	CREATE TABLE qk_t1 (
@@ -67,3 +87,261 @@ func TestGetDiffFromSqlFile(t *testing.T) {
 		fmt.Println(sql)
 	}
 }
+
+func TestGetDiffFromReader(t *testing.T) {
+	source := strings.NewReader("CREATE TABLE t1 (id INT);\nLOCK TABLES t1 WRITE;\nINSERT INTO t1 VALUES (1),(2);\nUNLOCK TABLES;\n")
+	target := strings.NewReader("/*!40101 SET NAMES utf8mb4 */;\nCREATE TABLE t1 (id INT, name VARCHAR(20));\n")
+
+	alters, err := GetDiffFromReader("db1", source, target)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	actual := []string{}
+	for _, alter := range alters {
+		sql, _ := utils.RestoreToSql(alter)
+		actual = append(actual, sql)
+	}
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t1` ADD COLUMN (`name` VARCHAR(20))"}, actual)
+}
+
+func TestGetDiffLowerCaseTableNames(t *testing.T) {
+	source := "CREATE TABLE Users (id INT);"
+	target := "CREATE TABLE users (id INT, name VARCHAR(20));"
+
+	opt := DiffOption{IgnoreOpts: DefaultDiffIgnoreTypes, LowerCaseTableNames: virtualdb.CaseSensitive}
+	assert.Equal(t, 2, len(diffSQLWithOpt(t, source, target, opt)))
+	opt.LowerCaseTableNames = virtualdb.LowerCaseCompared
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`Users` ADD COLUMN (`name` VARCHAR(20))"}, diffSQLWithOpt(t, source, target, opt))
+	opt.LowerCaseTableNames = virtualdb.LowerCaseStored
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`users` ADD COLUMN (`name` VARCHAR(20))"}, diffSQLWithOpt(t, source, target, opt))
+}
+
+func TestGetDiffCharset(t *testing.T) {
+	// 隐式继承与显式指定相同的字符集没有差异
+	assert.Empty(t, diffSQL(t,
+		"CREATE TABLE t (a VARCHAR(10)) CHARSET=utf8mb4;",
+		"CREATE TABLE t (a VARCHAR(10) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;"))
+	assert.Empty(t, diffSQL(t,
+		"CREATE TABLE t (a VARCHAR(10));",
+		"CREATE TABLE t (a VARCHAR(10) COLLATE utf8mb4_0900_ai_ci);",
+		DefaultDiffIgnoreTypes...))
+
+	// utf8 与 utf8mb4 是真实的差异
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` MODIFY COLUMN `a` VARCHAR(10) CHARACTER SET UTF8MB4"}, diffSQL(t,
+		"CREATE TABLE t (a VARCHAR(10) CHARACTER SET utf8);",
+		"CREATE TABLE t (a VARCHAR(10) CHARACTER SET utf8mb4);",
+		DefaultDiffIgnoreTypes...))
+	// 修改表的默认字符集不会转换已有字段，继承的字段需要修改
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` MODIFY COLUMN `a` VARCHAR(10), CHARACTER SET UTF8MB4 COLLATE UTF8MB4_0900_AI_CI"}, diffSQL(t,
+		"CREATE TABLE t (a VARCHAR(10)) CHARSET=utf8;",
+		"CREATE TABLE t (a VARCHAR(10));"))
+
+	// 只修改其他表选项时不输出字符集
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` COMMENT = 'new'"}, diffSQL(t,
+		"CREATE TABLE t (a INT) CHARSET=utf8mb4 COMMENT='old';",
+		"CREATE TABLE t (a INT) COMMENT='new';"))
+
+	// 库的字符集被表和字段继承，库本身的差异由 ALTER DATABASE 修改
+	ignoreDatabase := DiffOption{IgnoreOpts: []DiffIgnoreType{DiffIgnoreDatabaseOption}}
+	sourceDb, targetDb := virtualdb.NewVirtualDB(""), virtualdb.NewVirtualDB("")
+	assert.NoError(t, sourceDb.ExecSQL("CREATE DATABASE db1 CHARSET=latin1; CREATE TABLE db1.t (a VARCHAR(10));"))
+	assert.NoError(t, targetDb.ExecSQL("CREATE DATABASE db1; CREATE TABLE db1.t (a VARCHAR(10) CHARACTER SET latin1) CHARSET=latin1;"))
+	assert.Empty(t, GetDiffDBWithOpt("db1", sourceDb, targetDb, ignoreDatabase))
+
+	// 5.7 的服务器默认字符集为 latin1
+	defaultDb := virtualdb.NewVirtualDB("")
+	assert.NoError(t, defaultDb.ExecSQL("CREATE DATABASE db1; CREATE TABLE db1.t (a VARCHAR(10));"))
+	assert.Equal(t, 1, len(GetDiffDBWithOpt("db1", sourceDb, defaultDb, ignoreDatabase)))
+	ignoreDatabase.ServerVersion = 50744
+	assert.Empty(t, GetDiffDBWithOpt("db1", sourceDb, defaultDb, ignoreDatabase))
+	assert.Empty(t, GetDiffDBWithOpt("db1", sourceDb, defaultDb, DiffOption{ServerVersion: 50744}))
+}
+
+func TestGetDiffDatabase(t *testing.T) {
+	// 按生效的字符集和排序规则对比
+	assert.Empty(t, diffSQL(t, "ALTER DATABASE db1 CHARSET utf8mb4;", "ALTER DATABASE db1 COLLATE utf8mb4_0900_ai_ci;"))
+	assert.Equal(t, []string{
+		"ALTER DATABASE `db1` CHARACTER SET = utf8mb4 COLLATE = utf8mb4_bin ENCRYPTION = 'Y'",
+	}, diffSQL(t, "ALTER DATABASE db1 CHARSET utf8mb4;", "ALTER DATABASE db1 COLLATE utf8mb4_bin ENCRYPTION 'Y';"))
+	assert.Equal(t, []string{
+		"ALTER DATABASE `db1` CHARACTER SET = latin1 COLLATE = latin1_swedish_ci",
+	}, diffSQL(t, "", "ALTER DATABASE CHARACTER SET latin1;"))
+}
+
+func TestGetDiffNormalizeColumn(t *testing.T) {
+	// MySQL 5.7 导出的表结构
+	source := "CREATE TABLE t (id INT(11) NOT NULL, flag TINYINT(1) DEFAULT NULL, amount NUMERIC(10,2) DEFAULT '0.00', num INTEGER DEFAULT '0', PRIMARY KEY (id));"
+	// MySQL 8.0 导出的表结构
+	target := "CREATE TABLE t (id INT NOT NULL, flag BOOL, amount DECIMAL(10,2) DEFAULT 0, num INT DEFAULT 0, PRIMARY KEY (id));"
+
+	alters, err := GetDiffSQLWithOpt("db1", source, target, DiffOption{ServerVersion: 80023})
+	assert.NoError(t, err)
+	assert.Empty(t, alters)
+
+	// 8.0.19 之前显示宽度不同是真实的差异
+	alters, err = GetDiffSQLWithOpt("db1", "CREATE TABLE t (id INT(10));", "CREATE TABLE t (id INT);", DiffOption{ServerVersion: 50744})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(alters))
+	alters, err = GetDiffSQLWithOpt("db1", "CREATE TABLE t (id INT(11));", "CREATE TABLE t (id INT);", DiffOption{ServerVersion: 50744})
+	assert.NoError(t, err)
+	assert.Empty(t, alters)
+}
+
+func TestGetDiffObjects(t *testing.T) {
+	tables := "CREATE TABLE t (a INT, b INT);"
+	view := "CREATE DEFINER=`root`@`%` VIEW v AS SELECT a FROM t;"
+	trigger := "CREATE DEFINER=`root`@`%` TRIGGER tr BEFORE INSERT ON t FOR EACH ROW SET NEW.b = 1;"
+	procedure := "CREATE PROCEDURE p() SQL SECURITY DEFINER SELECT 1;"
+
+	assert.Empty(t, diffSQL(t, tables+view+trigger+procedure, tables+view+trigger+procedure))
+
+	// 新增的视图在新增的表之后创建，删除的对象在删除表之前删除
+	assert.Equal(t, []string{
+		"CREATE ALGORITHM = UNDEFINED DEFINER = `root`@`%` SQL SECURITY DEFINER VIEW `db1`.`v` AS SELECT `a` FROM `db1`.`t`",
+	}, diffSQL(t, "", tables+view)[1:])
+	assert.Equal(t, []string{
+		"DROP VIEW `db1`.`v`",
+		"DROP TRIGGER `db1`.`tr`",
+		"DROP TABLE `db1`.`t`",
+	}, diffSQL(t, tables+view+trigger, ""))
+
+	// 函数在调用它的视图之前创建，触发器在视图之后创建
+	function := "CREATE FUNCTION f(x INT) RETURNS INT DETERMINISTIC RETURN x + 1;"
+	assert.Equal(t, []string{
+		"CREATE FUNCTION `db1`.`f` (x INT) RETURNS INT DETERMINISTIC RETURN x + 1",
+		"CREATE ALGORITHM = UNDEFINED DEFINER = `root`@`%` SQL SECURITY DEFINER VIEW `db1`.`v` AS SELECT F(`a`) AS `c` FROM `db1`.`t`",
+		"CREATE DEFINER=`root`@`%` TRIGGER `db1`.`tr` BEFORE INSERT ON `db1`.`t` FOR EACH ROW SET NEW.b = 1",
+	}, diffSQL(t, tables, tables+function+"CREATE DEFINER=`root`@`%` VIEW v AS SELECT f(a) AS c FROM t;"+trigger))
+
+	// 修改的视图使用 CREATE OR REPLACE，触发器和存储过程先删除再创建
+	assert.Equal(t, []string{
+		"CREATE OR REPLACE ALGORITHM = UNDEFINED DEFINER = `root`@`%` SQL SECURITY DEFINER VIEW `db1`.`v` AS SELECT `b` FROM `db1`.`t`",
+	}, diffSQL(t, tables+view, tables+"CREATE DEFINER=`root`@`%` VIEW v AS SELECT b FROM t;"))
+	assert.Equal(t, []string{
+		"DROP TRIGGER `db1`.`tr`",
+		"CREATE DEFINER=`root`@`%` TRIGGER `db1`.`tr` BEFORE INSERT ON `db1`.`t` FOR EACH ROW SET NEW.b = 2",
+	}, diffSQL(t, tables+trigger, tables+"CREATE DEFINER=`root`@`%` TRIGGER tr BEFORE INSERT ON t FOR EACH ROW SET NEW.b = 2;"))
+	assert.Equal(t, []string{
+		"DROP PROCEDURE `db1`.`p`",
+		"CREATE PROCEDURE `db1`.`p` () SQL SECURITY INVOKER SELECT 1",
+	}, diffSQL(t, procedure, "CREATE PROCEDURE p () SQL SECURITY INVOKER SELECT 1;"))
+
+	// 空白、关键字大小写和默认的 SQL SECURITY 不产生差异
+	assert.Empty(t, diffSQL(t, tables+trigger, tables+"create definer=`root`@`%` trigger tr before insert on t\n  for each row\n  set new.b = 1;"))
+	assert.Empty(t, diffSQL(t, procedure, "CREATE PROCEDURE p() SELECT 1;"))
+
+	// DEFINER 和 SQL SECURITY 可以通过选项忽略
+	otherDefiner := "CREATE DEFINER=`app`@`%` TRIGGER tr BEFORE INSERT ON t FOR EACH ROW SET NEW.b = 1;"
+	assert.Equal(t, 2, len(diffSQL(t, tables+trigger, tables+otherDefiner)))
+	assert.Empty(t, diffSQL(t, tables+trigger, tables+otherDefiner, DiffIgnoreDefiner))
+	assert.Empty(t, diffSQL(t, tables+view, tables+"CREATE DEFINER=`app`@`%` VIEW v AS SELECT a FROM t;", DiffIgnoreDefiner))
+	assert.Empty(t, diffSQL(t, procedure, "CREATE PROCEDURE p() SQL SECURITY INVOKER SELECT 1;", DiffIgnoreSQLSecurity))
+	assert.Empty(t, diffSQL(t, tables+view, tables+"CREATE DEFINER=`root`@`%` SQL SECURITY INVOKER VIEW v AS SELECT a FROM t;", DiffIgnoreSQLSecurity))
+
+	assert.Empty(t, diffSQL(t, tables+view, tables, DiffIgnoreObjectRemove))
+	assert.Empty(t, diffSQL(t, tables, tables+view, DiffIgnoreObjectAppend))
+}
+
+func TestGetDiffPartition(t *testing.T) {
+	monthly := func(months ...string) string {
+		sql := "CREATE TABLE t (a INT, d DATE) PARTITION BY RANGE (TO_DAYS(d)) ("
+		for _, month := range months {
+			sql += "PARTITION p" + strings.Replace(month, "-", "", -1) + " VALUES LESS THAN (TO_DAYS('" + month + "-01')),"
+		}
+		return sql + "PARTITION pmax VALUES LESS THAN MAXVALUE);"
+	}
+	noMax := func(months ...string) string {
+		return strings.Replace(monthly(months...), ",PARTITION pmax VALUES LESS THAN MAXVALUE", "", 1)
+	}
+
+	assert.Empty(t, diffSQL(t, monthly("2020-02", "2020-03"), monthly("2020-02", "2020-03")))
+
+	// 末尾新增的分区
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` ADD PARTITION (PARTITION `p202004` VALUES LESS THAN (TO_DAYS('2020-04-01')), PARTITION `p202005` VALUES LESS THAN (TO_DAYS('2020-05-01')))",
+	}, diffSQL(t, noMax("2020-02", "2020-03"), noMax("2020-02", "2020-03", "2020-04", "2020-05")))
+
+	// 在 MAXVALUE 分区之前新增分区需要重组，目标中不存在的分区被删除
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` DROP PARTITION `p202002`",
+		"ALTER TABLE `db1`.`t` REORGANIZE PARTITION `pmax` INTO (PARTITION `p202004` VALUES LESS THAN (TO_DAYS('2020-04-01')), PARTITION `pmax` VALUES LESS THAN (MAXVALUE))",
+	}, diffSQL(t, monthly("2020-02", "2020-03"), monthly("2020-03", "2020-04")))
+
+	// HASH 分区按数量对比
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` ADD PARTITION PARTITIONS 2"},
+		diffSQL(t, "CREATE TABLE t (a INT) PARTITION BY HASH (a) PARTITIONS 2;", "CREATE TABLE t (a INT) PARTITION BY HASH (a) PARTITIONS 4;"))
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` COALESCE PARTITION 3"},
+		diffSQL(t, "CREATE TABLE t (a INT) PARTITION BY HASH (a) PARTITIONS 4;", "CREATE TABLE t (a INT) PARTITION BY HASH (a);"))
+
+	// 分区函数不同时重新分区，目标没有分区时移除分区
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` PARTITION BY KEY (`a`) PARTITIONS 4"},
+		diffSQL(t, "CREATE TABLE t (a INT) PARTITION BY HASH (a) PARTITIONS 4;", "CREATE TABLE t (a INT) PARTITION BY KEY (a) PARTITIONS 4;"))
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` PARTITION BY HASH (`a`) PARTITIONS 4"},
+		diffSQL(t, "CREATE TABLE t (a INT);", "CREATE TABLE t (a INT) PARTITION BY HASH (a) PARTITIONS 4;"))
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`t` REMOVE PARTITIONING"},
+		diffSQL(t, "CREATE TABLE t (a INT) PARTITION BY HASH (a) PARTITIONS 4;", "CREATE TABLE t (a INT);"))
+
+	assert.Empty(t, diffSQL(t, monthly("2020-02"), monthly("2020-03"), DiffIgnorePartition))
+}
+
+func TestGetDiffGeneratedAndCheck(t *testing.T) {
+	// 修改生成表达式
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` MODIFY COLUMN `b` INT GENERATED ALWAYS AS(`a`*2) STORED",
+	}, diffSQL(t, "CREATE TABLE t (a INT, b INT AS (a + 1) STORED);", "CREATE TABLE t (a INT, b INT AS (a * 2) STORED);"))
+
+	// 虚拟列改为存储列需要删除后重新添加
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` DROP COLUMN `b`, ADD COLUMN `b` INT GENERATED ALWAYS AS(`a`+1) STORED AFTER `a`",
+	}, diffSQL(t, "CREATE TABLE t (a INT, b INT AS (a + 1), c INT);", "CREATE TABLE t (a INT, b INT AS (a + 1) STORED, c INT);"))
+
+	// 字段上的 CHECK 约束与表上的 CHECK 约束相同
+	assert.Empty(t, diffSQL(t, "CREATE TABLE t (a INT CHECK (a > 0));", "CREATE TABLE t (a INT, CHECK (a > 0));"))
+
+	// CHECK 约束使用 DROP CHECK 删除，只有 ENFORCED 不同时直接修改
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` DROP CHECK `c1`",
+	}, diffSQL(t, "CREATE TABLE t (a INT, CONSTRAINT c1 CHECK (a > 0));", "CREATE TABLE t (a INT);"))
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` DROP CHECK `c1`, ADD CONSTRAINT `c1` CHECK(`a`>1) ENFORCED",
+	}, diffSQL(t, "CREATE TABLE t (a INT, CONSTRAINT c1 CHECK (a > 0));", "CREATE TABLE t (a INT, CONSTRAINT c1 CHECK (a > 1));"))
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` ALTER CHECK `c1` NOT ENFORCED",
+	}, diffSQL(t, "CREATE TABLE t (a INT, CONSTRAINT c1 CHECK (a > 0));", "CREATE TABLE t (a INT, CONSTRAINT c1 CHECK (a > 0) NOT ENFORCED);"))
+}
+
+func TestGetDiffPrimaryKey(t *testing.T) {
+	// 字段上的主键与表上的主键相同，主键字段总是 NOT NULL
+	assert.Empty(t, diffSQL(t, "CREATE TABLE t (id INT AUTO_INCREMENT PRIMARY KEY, a INT);",
+		"CREATE TABLE t (id INT NOT NULL AUTO_INCREMENT, a INT, CONSTRAINT pk PRIMARY KEY (id));"))
+
+	// 修改主键
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` DROP PRIMARY KEY, ADD PRIMARY KEY(`id`, `a`)",
+	}, diffSQL(t, "CREATE TABLE t (id INT PRIMARY KEY, a INT NOT NULL);", "CREATE TABLE t (id INT, a INT NOT NULL, PRIMARY KEY (id, a));"))
+
+	// 删除和新增主键
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` MODIFY COLUMN `id` INT NOT NULL, DROP PRIMARY KEY",
+	}, diffSQL(t, "CREATE TABLE t (id INT AUTO_INCREMENT PRIMARY KEY);", "CREATE TABLE t (id INT NOT NULL);"))
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` MODIFY COLUMN `id` INT NOT NULL AUTO_INCREMENT, ADD PRIMARY KEY(`id`)",
+	}, diffSQL(t, "CREATE TABLE t (id INT NOT NULL);", "CREATE TABLE t (id INT AUTO_INCREMENT PRIMARY KEY);"))
+
+	// 忽略字段差异时 AUTO_INCREMENT 字段仍然和主键一起修改
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` MODIFY COLUMN `id` INT NOT NULL AUTO_INCREMENT, MODIFY COLUMN `a` INT NOT NULL, DROP PRIMARY KEY, ADD PRIMARY KEY(`id`)",
+	}, diffSQL(t, "CREATE TABLE t (id INT NOT NULL, a INT NOT NULL AUTO_INCREMENT, PRIMARY KEY (a));",
+		"CREATE TABLE t (id INT AUTO_INCREMENT PRIMARY KEY, a INT NOT NULL);", DiffIgnoreColumnDiff))
+}
+
+func TestGetDiffUnnamedIndex(t *testing.T) {
+	// 未命名的索引按 MySQL 的规则命名后对比
+	assert.Empty(t, diffSQL(t, "CREATE TABLE t (a INT, b INT, INDEX (a), UNIQUE (b));",
+		"CREATE TABLE t (a INT, b INT, KEY a (a), UNIQUE KEY b (b));"))
+	assert.Equal(t, []string{
+		"ALTER TABLE `db1`.`t` DROP INDEX `b`, ADD UNIQUE `b`(`b`, `a`), ADD INDEX `a_2`(`a`, `b`)",
+	}, diffSQL(t, "CREATE TABLE t (a INT, b INT, INDEX (a), UNIQUE (b));",
+		"CREATE TABLE t (a INT, b INT, INDEX (a), UNIQUE (b, a), INDEX (a, b));"))
+}
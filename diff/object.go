@@ -0,0 +1,160 @@
+package diff
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ssoor/sql-calculator/utils"
+	"github.com/ssoor/sql-calculator/virtualdb"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/auth"
+	"github.com/pingcap/parser/model"
+)
+
+// 在视图之前创建的函数和存储过程类型，视图可能调用函数
+var routineObjectTypes = []virtualdb.ObjectType{
+	virtualdb.ObjectFunction,
+	virtualdb.ObjectProcedure,
+}
+
+// 在视图之后创建的触发器和事件类型，它们的定义可能引用视图
+var triggerObjectTypes = []virtualdb.ObjectType{
+	virtualdb.ObjectTrigger,
+	virtualdb.ObjectEvent,
+}
+
+var sqlSecurityPattern = regexp.MustCompile(`(^| )SQL SECURITY (DEFINER|INVOKER)( |$)`)
+
+// objectDiff 为视图、触发器、存储过程、函数和事件的差异，drops 需要在表结构变更之前执行，creates 在之后执行
+type objectDiff struct {
+	drops   []ast.StmtNode
+	creates []ast.StmtNode
+}
+
+// getDiffObjects 对比两个虚拟库中 dbName 库的视图、触发器、存储过程、函数和事件
+func getDiffObjects(dbName string, sourceDb, targetDb *virtualdb.VirtualDB, opt DiffOption) objectDiff {
+	diff := objectDiff{}
+	// 按创建时的依赖顺序对比：先函数和存储过程，再视图，最后触发器和事件
+	for _, tp := range routineObjectTypes {
+		getDiffObjectsOfType(dbName, sourceDb, targetDb, tp, opt, &diff)
+	}
+	getDiffViews(dbName, sourceDb, targetDb, opt, &diff)
+	for _, tp := range triggerObjectTypes {
+		getDiffObjectsOfType(dbName, sourceDb, targetDb, tp, opt, &diff)
+	}
+
+	return diff
+}
+
+// getDiffViews 对比视图，修改的视图使用 CREATE OR REPLACE VIEW
+func getDiffViews(dbName string, sourceDb, targetDb *virtualdb.VirtualDB, opt DiffOption, diff *objectDiff) {
+	sourceViews, _ := sourceDb.GetViews(dbName)
+	targetViews, _ := targetDb.GetViews(dbName)
+
+	// 按依赖的反序删除视图
+	sourceNames := sourceDb.GetViewNames(dbName)
+	for i := len(sourceNames) - 1; i >= 0; i-- {
+		sourceView := sourceViews[sourceNames[i]].View
+		if _, exist := targetViews[sourceNames[i]]; !exist && !opt.Has(DiffIgnoreObjectRemove) {
+			diff.drops = append(diff.drops, &ast.DropTableStmt{IsView: true, Tables: []*ast.TableName{sourceView.ViewName}})
+		}
+	}
+
+	// 按依赖顺序创建视图
+	for _, name := range targetDb.GetViewNames(dbName) {
+		targetView := targetViews[name].View
+		sourceInfo, exist := sourceViews[name]
+		if !exist {
+			if !opt.Has(DiffIgnoreObjectAppend) {
+				diff.creates = append(diff.creates, targetView)
+			}
+			continue
+		}
+
+		if opt.Has(DiffIgnoreObjectDiff) || compareView(sourceInfo.View, targetView, opt) {
+			continue
+		}
+		view := *targetView
+		view.OrReplace = true
+		diff.creates = append(diff.creates, &view)
+	}
+}
+
+// compareView 对比视图定义，忽略 DEFINER 或 SQL SECURITY 时将其视为默认值
+func compareView(source, target *ast.CreateViewStmt, opt DiffOption) bool {
+	normalize := func(view *ast.CreateViewStmt) string {
+		newView := *view
+		newView.ViewName = &ast.TableName{Name: model.NewCIStr(view.ViewName.Name.L)}
+		if opt.Has(DiffIgnoreDefiner) {
+			newView.Definer = &auth.UserIdentity{CurrentUser: true}
+		}
+		if opt.Has(DiffIgnoreSQLSecurity) {
+			newView.Security = model.SecurityDefiner
+		}
+
+		sql, _ := utils.RestoreToSql(&newView)
+		return sql
+	}
+
+	return normalize(source) == normalize(target)
+}
+
+// getDiffObjectsOfType 对比一种触发器、存储过程、函数或事件，它们不能原地修改，修改时先删除再创建
+func getDiffObjectsOfType(dbName string, sourceDb, targetDb *virtualdb.VirtualDB, tp virtualdb.ObjectType, opt DiffOption, diff *objectDiff) {
+	sourceObjects, _ := sourceDb.GetObjects(dbName, tp)
+	targetObjects, _ := targetDb.GetObjects(dbName, tp)
+
+	for _, name := range sortedObjectNames(sourceObjects) {
+		if _, exist := targetObjects[name]; !exist && !opt.Has(DiffIgnoreObjectRemove) {
+			diff.drops = append(diff.drops, &virtualdb.ObjectStmt{SQL: sourceObjects[name].DropSQL(dbName)})
+		}
+	}
+
+	for _, name := range sortedObjectNames(targetObjects) {
+		target := targetObjects[name]
+		source, exist := sourceObjects[name]
+		if exist {
+			if opt.Has(DiffIgnoreObjectDiff) || objectText(dbName, source, opt) == objectText(dbName, target, opt) {
+				continue
+			}
+			diff.drops = append(diff.drops, &virtualdb.ObjectStmt{SQL: source.DropSQL(dbName)})
+		} else if opt.Has(DiffIgnoreObjectAppend) {
+			continue
+		}
+
+		diff.creates = append(diff.creates, &virtualdb.ObjectStmt{SQL: target.CreateSQL(dbName)})
+		for _, alter := range target.AlterSQLs(dbName) {
+			diff.creates = append(diff.creates, &virtualdb.ObjectStmt{SQL: alter})
+		}
+	}
+}
+
+// objectText 返回用于对比的对象定义，忽略空白和关键字的大小写，SQL SECURITY DEFINER 为默认值
+func objectText(dbName string, object *virtualdb.ObjectInfo, opt DiffOption) string {
+	newObject := *object
+	newObject.Name = strings.ToLower(object.Name)
+	if opt.Has(DiffIgnoreDefiner) {
+		newObject.Definer = ""
+	}
+
+	sqls := append([]string{newObject.CreateSQL(dbName)}, newObject.AlterSQLs(dbName)...)
+	text := virtualdb.CanonicalText(strings.Join(sqls, ";"))
+	return sqlSecurityPattern.ReplaceAllStringFunc(text, func(security string) string {
+		if opt.Has(DiffIgnoreSQLSecurity) || strings.Contains(security, "DEFINER") {
+			return " "
+		}
+		return security
+	})
+}
+
+func sortedObjectNames(objects map[string]*virtualdb.ObjectInfo) []string {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -1,6 +1,10 @@
 package diff
 
-import "github.com/pingcap/parser/ast"
+import (
+	"github.com/ssoor/sql-calculator/virtualdb"
+
+	"github.com/pingcap/parser/ast"
+)
 
 type DiffIgnoreType int
 
@@ -23,6 +27,13 @@ const (
 	DiffIgnoreIndexDiff
 	DiffIgnoreIndexRemove
 	DiffIgnoreIndexAppend
+	DiffIgnoreObjectDiff
+	DiffIgnoreObjectRemove
+	DiffIgnoreObjectAppend
+	DiffIgnoreDefiner
+	DiffIgnoreSQLSecurity
+	DiffIgnorePartition
+	DiffIgnoreDatabaseOption
 )
 
 func (m DiffIgnoreType) GetTableOption() ast.TableOptionType {
@@ -58,6 +69,7 @@ var DefaultDiffIgnoreTypes = []DiffIgnoreType{
 	DiffIgnoreTableOptionAutoIncrement,
 	DiffIgnoreIndexOption,
 	DiffIgnoreColumnOptionNull,
+	DiffIgnoreDefiner,
 }
 
 // TableOption is used for parsing table option from SQL.
@@ -65,6 +77,28 @@ type DiffOption struct {
 	IgnoreOpts           []DiffIgnoreType
 	IndexNameCustomDiff  func(sourceName string, targetName string) bool
 	ColumnNameCustomDiff func(sourceName string, targetName string) bool
+	// LowerCaseTableNames 与 MySQL 的 lower_case_table_names 相同，决定库名和表名是否区分大小写
+	LowerCaseTableNames virtualdb.LowerCaseTableNames
+	// ServerVersion 为对比时模拟的 MySQL 版本，决定默认字符集和排序规则，0 表示 virtualdb.DefaultServerVersion
+	ServerVersion int
+	// Flavor 为对比时模拟的服务器类型，与 ServerVersion 一起决定字段定义的规范形式
+	Flavor virtualdb.Flavor
+
+	// 对比的库，用于计算字段继承的字符集和排序规则
+	sourceSchema *ast.CreateDatabaseStmt
+	targetSchema *ast.CreateDatabaseStmt
+}
+
+func (m DiffOption) dbOption() virtualdb.DBOption {
+	return virtualdb.DBOption{LowerCaseTableNames: m.LowerCaseTableNames, ServerVersion: m.ServerVersion}
+}
+
+func (m DiffOption) serverVersion() int {
+	if m.ServerVersion > 0 {
+		return m.ServerVersion
+	}
+
+	return virtualdb.DefaultServerVersion
 }
 
 func (m DiffOption) Has(ty DiffIgnoreType) bool {
@@ -0,0 +1,115 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/ssoor/sql-calculator/utils"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	"github.com/pingcap/parser/model"
+)
+
+// GetDiffPartition 对比表的分区，返回分区维护语句。MySQL 的一条 ALTER TABLE 只能有一个分区操作，所以每个操作是一条语句
+// RANGE 和 LIST 分区按名称对比，目标中没有的分区 DROP PARTITION，末尾新增的分区 ADD PARTITION，其余变化 REORGANIZE PARTITION
+// HASH 和 KEY 分区按数量对比，分区函数不同时重新分区
+func GetDiffPartition(sourceTable, targetTable *ast.CreateTableStmt, opt DiffOption) []ast.StmtNode {
+	source, target := sourceTable.Partition, targetTable.Partition
+	if opt.Has(DiffIgnorePartition) || (source == nil && target == nil) {
+		return nil
+	}
+
+	alter := func(spec *ast.AlterTableSpec) ast.StmtNode {
+		return &ast.AlterTableStmt{Table: sourceTable.Table, Specs: []*ast.AlterTableSpec{spec}}
+	}
+
+	switch {
+	case target == nil:
+		return []ast.StmtNode{alter(&ast.AlterTableSpec{Tp: ast.AlterTableRemovePartitioning})}
+	case source == nil || partitionMethodSQL(source) != partitionMethodSQL(target):
+		return []ast.StmtNode{alter(&ast.AlterTableSpec{Tp: ast.AlterTablePartition, Partition: target})}
+	}
+
+	if source.Tp != model.PartitionTypeRange && source.Tp != model.PartitionTypeList {
+		sourceCount, targetCount := partitionCount(source), partitionCount(target)
+		switch {
+		case sourceCount < targetCount:
+			return []ast.StmtNode{alter(&ast.AlterTableSpec{Tp: ast.AlterTableAddPartitions, Num: uint64(targetCount - sourceCount)})}
+		case sourceCount > targetCount:
+			return []ast.StmtNode{alter(&ast.AlterTableSpec{Tp: ast.AlterTableCoalescePartitions, Num: uint64(sourceCount - targetCount)})}
+		}
+		return nil
+	}
+
+	allDDL := []ast.StmtNode{}
+	targetDefs := make(map[string]*ast.PartitionDefinition)
+	for _, def := range target.Definitions {
+		targetDefs[def.Name.L] = def
+	}
+
+	// 删除目标中不存在的分区
+	dropNames := []model.CIStr{}
+	sourceDefs := []*ast.PartitionDefinition{}
+	for _, def := range source.Definitions {
+		if _, exist := targetDefs[def.Name.L]; !exist {
+			dropNames = append(dropNames, def.Name)
+			continue
+		}
+		sourceDefs = append(sourceDefs, def)
+	}
+	if len(dropNames) != 0 {
+		allDDL = append(allDDL, alter(&ast.AlterTableSpec{Tp: ast.AlterTableDropPartition, PartitionNames: dropNames}))
+	}
+
+	// 从第一个不同的分区开始，之后的源分区都需要重组，没有不同的源分区时只需新增
+	i := 0
+	for i < len(sourceDefs) && i < len(target.Definitions) &&
+		partitionDefinitionSQL(sourceDefs[i]) == partitionDefinitionSQL(target.Definitions[i]) {
+		i++
+	}
+	switch {
+	case i < len(sourceDefs):
+		reorganizeNames := []model.CIStr{}
+		for _, def := range sourceDefs[i:] {
+			reorganizeNames = append(reorganizeNames, def.Name)
+		}
+		allDDL = append(allDDL, alter(&ast.AlterTableSpec{
+			Tp:              ast.AlterTableReorganizePartition,
+			PartitionNames:  reorganizeNames,
+			PartDefinitions: target.Definitions[i:],
+		}))
+	case i < len(target.Definitions):
+		allDDL = append(allDDL, alter(&ast.AlterTableSpec{Tp: ast.AlterTableAddPartitions, PartDefinitions: target.Definitions[i:]}))
+	}
+
+	return allDDL
+}
+
+// partitionMethodSQL 返回分区函数和子分区函数，不包括分区数量和分区定义，子分区数量不能单独修改所以包括在内
+func partitionMethodSQL(partition *ast.PartitionOptions) string {
+	method := *partition
+	method.Num = 0
+	method.Definitions = nil
+
+	sql, _ := utils.RestoreToSql(&method)
+	return sql
+}
+
+func partitionDefinitionSQL(def *ast.PartitionDefinition) string {
+	var sb strings.Builder
+	_ = def.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &sb))
+
+	return sb.String()
+}
+
+// partitionCount 返回分区数量，HASH 和 KEY 分区可以只指定数量
+func partitionCount(partition *ast.PartitionOptions) int {
+	if len(partition.Definitions) != 0 {
+		return len(partition.Definitions)
+	}
+	if partition.Num == 0 {
+		return 1
+	}
+
+	return int(partition.Num)
+}
@@ -0,0 +1,265 @@
+package migration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ssoor/sql-calculator/diff"
+	"github.com/ssoor/sql-calculator/virtualdb"
+
+	"github.com/pingcap/parser/ast"
+)
+
+// Layout is the naming convention of the migration files in a directory.
+type Layout int
+
+// Layout types.
+const (
+	// LayoutPlain applies all the .sql files sorted by file name.
+	LayoutPlain Layout = iota
+	// LayoutGolangMigrate applies the {version}_{title}.up.sql files of golang-migrate.
+	LayoutGolangMigrate
+	// LayoutFlyway applies the V{version}__{description}.sql files of Flyway, the
+	// undo (U) and repeatable (R) migrations are not supported and skipped.
+	LayoutFlyway
+)
+
+var (
+	golangMigratePattern = regexp.MustCompile(`^(\d+)_(.*)\.up\.sql$`)
+	flywayPattern        = regexp.MustCompile(`^V(\d+(?:[._]\d+)*)__(.*)\.sql$`)
+)
+
+// Migration is one migration file.
+type Migration struct {
+	// Version is the version as written in the file name, such as "0001" or "1.2".
+	Version     string
+	Description string
+	Path        string
+}
+
+// History is the schema states of a directory of migrations, one state after
+// each version. The states are clones which share the unchanged tables, so a
+// version costs only the tables it changes.
+type History struct {
+	dbName     string
+	migrations []*Migration
+	initial    *virtualdb.VirtualDB
+	states     []*virtualdb.VirtualDB
+}
+
+// DetectLayout returns the layout of the file names: Flyway if there are V__
+// files, golang-migrate if there are .up.sql files, or else plain.
+func DetectLayout(names []string) Layout {
+	layout := LayoutPlain
+	for _, name := range names {
+		if flywayPattern.MatchString(name) {
+			return LayoutFlyway
+		}
+		if golangMigratePattern.MatchString(name) {
+			layout = LayoutGolangMigrate
+		}
+	}
+	return layout
+}
+
+// ListMigrations returns the migrations of the directory in the order they are applied.
+func ListMigrations(dir string) ([]*Migration, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, file := range files {
+		if !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+
+	migrations := []*Migration{}
+	layout := DetectLayout(names)
+	for _, name := range names {
+		m := &Migration{Path: filepath.Join(dir, name)}
+		switch layout {
+		case LayoutFlyway:
+			matches := flywayPattern.FindStringSubmatch(name)
+			if matches == nil {
+				continue
+			}
+			m.Version, m.Description = matches[1], matches[2]
+		case LayoutGolangMigrate:
+			matches := golangMigratePattern.FindStringSubmatch(name)
+			if matches == nil {
+				continue
+			}
+			m.Version, m.Description = matches[1], matches[2]
+		default:
+			if !strings.HasSuffix(strings.ToLower(name), ".sql") {
+				continue
+			}
+			m.Version = name[:len(name)-len(".sql")]
+		}
+		migrations = append(migrations, m)
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return CompareVersion(migrations[i].Version, migrations[j].Version) < 0
+	})
+	if layout == LayoutPlain {
+		return migrations, nil
+	}
+	for i := 1; i < len(migrations); i++ {
+		if CompareVersion(migrations[i-1].Version, migrations[i].Version) == 0 {
+			return nil, fmt.Errorf("duplicate migration version %s: %s, %s",
+				migrations[i].Version, migrations[i-1].Path, migrations[i].Path)
+		}
+	}
+	return migrations, nil
+}
+
+// CompareVersion compares two versions by their parts separated by "." or "_",
+// numeric parts are compared as numbers, so "0042" equals "42" and "1.10" is
+// after "1.9".
+func CompareVersion(a, b string) int {
+	partsA := splitVersion(a)
+	partsB := splitVersion(b)
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		if i >= len(partsA) {
+			return -1
+		}
+		if i >= len(partsB) {
+			return 1
+		}
+		numA, errA := strconv.ParseUint(partsA[i], 10, 64)
+		numB, errB := strconv.ParseUint(partsB[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case partsA[i] != partsB[i]:
+			if partsA[i] < partsB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func splitVersion(version string) []string {
+	return strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '_'
+	})
+}
+
+// LoadDir replays the migrations of the directory on a VirtualDB whose default
+// schema is dbName, and keeps the schema state after each version.
+func LoadDir(dir, dbName string) (*History, error) {
+	migrations, err := ListMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	return Load(migrations, dbName)
+}
+
+// Load replays the migrations in order.
+func Load(migrations []*Migration, dbName string) (*History, error) {
+	return LoadWithOpt(migrations, dbName, virtualdb.DBOption{})
+}
+
+// LoadWithOpt is like Load with the options of the VirtualDB, such as
+// lower_case_table_names of the server.
+func LoadWithOpt(migrations []*Migration, dbName string, opt virtualdb.DBOption) (*History, error) {
+	h := &History{
+		dbName:     dbName,
+		migrations: migrations,
+		initial:    virtualdb.NewVirtualDBWithOpt(dbName, opt),
+	}
+	db, err := h.initial.Clone()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range migrations {
+		sql, err := ioutil.ReadFile(m.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.ExecSQL(string(sql)); err != nil {
+			return nil, fmt.Errorf("migration %s (%s): %w", m.Version, m.Path, err)
+		}
+		state, err := db.Clone()
+		if err != nil {
+			return nil, err
+		}
+		h.states = append(h.states, state)
+	}
+	return h, nil
+}
+
+// Migrations returns the migrations in the order they are applied.
+func (h *History) Migrations() []*Migration {
+	return h.migrations
+}
+
+// state returns the schema state after the version, the empty version is the
+// state before the first migration.
+func (h *History) state(version string) (*virtualdb.VirtualDB, error) {
+	if version == "" {
+		return h.initial, nil
+	}
+	for i, m := range h.migrations {
+		if m.Version == version || CompareVersion(m.Version, version) == 0 {
+			return h.states[i], nil
+		}
+	}
+	return nil, fmt.Errorf("not exist migration version: %s", version)
+}
+
+// At returns a copy of the schema state after the version.
+func (h *History) At(version string) (*virtualdb.VirtualDB, error) {
+	state, err := h.state(version)
+	if err != nil {
+		return nil, err
+	}
+	return state.Clone()
+}
+
+// Table returns the definition of the table at the version.
+func (h *History) Table(version, schemaName, tableName string) (*ast.CreateTableStmt, bool, error) {
+	state, err := h.state(version)
+	if err != nil {
+		return nil, false, err
+	}
+	table, exist := state.GetTable(schemaName, tableName)
+	if !exist {
+		return nil, false, nil
+	}
+	return table.Table, true, nil
+}
+
+// Diff returns the statements which migrate the schema from one version to another.
+func (h *History) Diff(from, to string, ignores ...diff.DiffIgnoreType) ([]ast.StmtNode, error) {
+	ignores = append(ignores, diff.DefaultDiffIgnoreTypes...)
+	return h.DiffWithOpt(from, to, diff.DiffOption{IgnoreOpts: ignores})
+}
+
+// DiffWithOpt is like Diff with the options of diff.
+func (h *History) DiffWithOpt(from, to string, opt diff.DiffOption) ([]ast.StmtNode, error) {
+	source, err := h.state(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := h.state(to)
+	if err != nil {
+		return nil, err
+	}
+	return diff.GetDiffDBWithOpt(h.dbName, source, target, opt), nil
+}
@@ -0,0 +1,123 @@
+package migration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ssoor/sql-calculator/utils"
+	"github.com/ssoor/sql-calculator/virtualdb"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func migrationVersions(migrations []*Migration) []string {
+	versions := []string{}
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	return versions
+}
+
+func TestListMigrations(t *testing.T) {
+	migrations, err := ListMigrations("testdata/golang-migrate")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0001", "0002", "0010"}, migrationVersions(migrations))
+	assert.Equal(t, "add_email", migrations[1].Description)
+
+	migrations, err = ListMigrations("testdata/flyway")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "1.9", "1.10"}, migrationVersions(migrations))
+
+	migrations, err = ListMigrations("testdata/plain")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"001_init", "002_add_b"}, migrationVersions(migrations))
+
+	// the plain files are sorted by their numbers too.
+	dir, err := ioutil.TempDir("", "migration")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"10_c.sql", "9_b.sql", "1_a.sql"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	migrations, err = ListMigrations(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1_a", "9_b", "10_c"}, migrationVersions(migrations))
+}
+
+func TestCompareVersion(t *testing.T) {
+	assert.Equal(t, 0, CompareVersion("0042", "42"))
+	assert.Equal(t, -1, CompareVersion("1.9", "1.10"))
+	assert.Equal(t, 1, CompareVersion("2", "1_5"))
+	assert.Equal(t, -1, CompareVersion("1", "1.1"))
+	assert.Equal(t, 0, CompareVersion("1_1", "1.1"))
+}
+
+func TestHistory(t *testing.T) {
+	h, err := LoadDir("testdata/golang-migrate", "db1")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	table, exist, err := h.Table("2", "db1", "users")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	sql, _ := utils.RestoreToSql(table)
+	assert.Equal(t, "CREATE TABLE `db1`.`users` (`id` INT,`name` VARCHAR(20),`email` VARCHAR(255),PRIMARY KEY(`id`),INDEX `idx_email`(`email`))", sql)
+
+	_, exist, err = h.Table("", "db1", "users")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	_, _, err = h.Table("3", "db1", "users")
+	assert.EqualError(t, err, "not exist migration version: 3")
+
+	db, err := h.At("0010")
+	assert.NoError(t, err)
+	col, exist, err := db.GetColumn("db1", "users", "name")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	sql, _ = utils.RestoreToSql(col)
+	assert.Equal(t, "`name` VARCHAR(64) NOT NULL", sql)
+
+	stmts, err := h.Diff("1", "10")
+	assert.NoError(t, err)
+	actual := []string{}
+	for _, stmt := range stmts {
+		sql, _ := utils.RestoreToSql(stmt)
+		actual = append(actual, sql)
+	}
+	assert.Equal(t, []string{"ALTER TABLE `db1`.`users` MODIFY COLUMN `name` VARCHAR(64) NOT NULL, ADD COLUMN (`email` VARCHAR(255)), ADD INDEX `idx_email`(`email`)"}, actual)
+
+	stmts, err = h.Diff("", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(stmts))
+}
+
+func TestHistoryLowerCaseTableNames(t *testing.T) {
+	migrations, err := ListMigrations("testdata/golang-migrate")
+	if !assert.NoError(t, err) {
+		return
+	}
+	h, err := LoadWithOpt(migrations, "db1", virtualdb.DBOption{LowerCaseTableNames: virtualdb.LowerCaseStored})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the table names are matched like the statements.
+	_, exist, err := h.Table("2", "DB1", "Users")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestLoadDirError(t *testing.T) {
+	migrations := []*Migration{
+		{Version: "1", Path: "testdata/plain/002_add_b.sql"},
+	}
+	_, err := Load(migrations, "db1")
+	assert.EqualError(t, err, "migration 1 (testdata/plain/002_add_b.sql): "+
+		"statement #1 at line 1, column 1 (alter table t1 add column b int;): not exist table: db1.t1")
+}
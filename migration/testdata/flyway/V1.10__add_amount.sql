@@ -0,0 +1 @@
+alter table orders add column amount decimal(10, 2);
@@ -0,0 +1 @@
+alter table orders add index idx_user(user_id);
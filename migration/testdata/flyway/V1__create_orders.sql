@@ -0,0 +1 @@
+create table orders(id int, user_id int);
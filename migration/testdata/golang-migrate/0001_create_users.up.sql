@@ -0,0 +1 @@
+create table users(id int, name varchar(20), primary key(id));
@@ -0,0 +1,2 @@
+alter table users add column email varchar(255);
+alter table users add index idx_email(email);
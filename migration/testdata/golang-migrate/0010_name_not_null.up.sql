@@ -0,0 +1 @@
+alter table users modify name varchar(64) not null;
@@ -0,0 +1 @@
+create table t1(a int);
@@ -0,0 +1 @@
+alter table t1 add column b int;
@@ -0,0 +1,625 @@
+package virtualdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/mysql"
+)
+
+// columnChange is a column definition from ADD, CHANGE, MODIFY or RENAME COLUMN
+// waiting to be placed into the column list of the altered table.
+type columnChange struct {
+	// oldName is the lower-case name of the column being replaced, it is empty for ADD COLUMN.
+	oldName  string
+	col      *ast.ColumnDef
+	position *ast.ColumnPosition
+	matched  bool
+}
+
+// mergeAlterToTable applies the specs of an ALTER TABLE to a copy of oldTable, it
+// also returns the new names of the changed and dropped columns like alterColumns.
+//
+// Like MySQL (mysql_prepare_alter_table), DROP, CHANGE, MODIFY, RENAME and
+// ALTER COLUMN refer to the columns of the original table, while new and
+// positioned columns are placed in written order afterwards. Indexes are
+// handled the same way: DROP and RENAME INDEX refer to the original indexes
+// and new indexes are added in written order. So "ADD COLUMN x, DROP COLUMN x"
+// fails when x does not exist yet, while "DROP INDEX a, ADD INDEX a(...)"
+// redefines the index.
+func (c *VirtualDB) mergeAlterToTable(oldTable *ast.CreateTableStmt,
+	alterTable *ast.AlterTableStmt) (*ast.CreateTableStmt, map[string]*ast.ColumnName, error) {
+
+	tmpTable, err := copyCreateTableStmt(oldTable)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schemaName := c.getSchemaName(tmpTable.Table)
+	tableName := tmpTable.Table.Name.String()
+
+	// convertFrom is the table options before CONVERT TO CHARACTER SET.
+	var convertFrom []*ast.TableOption
+	for _, spec := range alterTable.Specs {
+		switch spec.Tp {
+		case ast.AlterTableRenameTable:
+			tmpTable.Table = spec.NewTable
+		case ast.AlterTableOption:
+			options := spec.Options
+			if isConvertCharset(spec) {
+				convertFrom = tmpTable.Options
+				options = c.convertCharsetOptions(schemaName, spec.Options)
+			}
+			tmpTable.Options = mergeTableOptions(tmpTable.Options, options)
+		}
+	}
+
+	cols, columns, err := alterColumns(tmpTable.Cols, alterTable.Specs, schemaName, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpTable.Cols = cols
+	if convertFrom != nil {
+		c.convertColumnsCharset(schemaName, tmpTable, convertFrom)
+	}
+
+	if err := alterConstraints(tmpTable, alterTable.Specs, columns, schemaName, tableName); err != nil {
+		return nil, nil, err
+	}
+	if err := alterChecks(tmpTable, alterTable.Specs, schemaName, tableName); err != nil {
+		return nil, nil, err
+	}
+	if err := checkGeneratedColumnChanges(oldTable.Cols, tmpTable.Cols, columns, schemaName, tableName); err != nil {
+		return nil, nil, err
+	}
+	if err := checkColumnDependencies(tmpTable, columns, schemaName, tableName); err != nil {
+		return nil, nil, err
+	}
+	if err := alterPartitions(tmpTable, alterTable.Specs, schemaName, tableName); err != nil {
+		return nil, nil, err
+	}
+	normalizeIndexes(tmpTable)
+	normalizeChecks(tmpTable)
+	renameChecks(tmpTable, tableName)
+	return tmpTable, columns, nil
+}
+
+// alterColumns returns the column list of the table after the column specs are applied,
+// and the new names of the changed and dropped columns keyed by their lower-case old
+// names. Dropped columns map to nil.
+func alterColumns(oldCols []*ast.ColumnDef, specs []*ast.AlterTableSpec,
+	schemaName, tableName string) ([]*ast.ColumnDef, map[string]*ast.ColumnName, error) {
+
+	drops := getAlterTableSpecByTp(specs, ast.AlterTableDropColumn)
+	droppedSpecs := map[*ast.AlterTableSpec]struct{}{}
+	alters := getAlterTableSpecByTp(specs, ast.AlterTableAlterColumn)
+	alteredSpecs := map[*ast.AlterTableSpec]struct{}{}
+
+	changes := []*columnChange{}
+	for _, spec := range getAlterTableSpecByTp(specs, ast.AlterTableAddColumns,
+		ast.AlterTableChangeColumn, ast.AlterTableModifyColumn, ast.AlterTableRenameColumn) {
+		switch spec.Tp {
+		case ast.AlterTableAddColumns:
+			for _, col := range spec.NewColumns {
+				changes = append(changes, &columnChange{col: col, position: spec.Position})
+			}
+		case ast.AlterTableChangeColumn:
+			changes = append(changes, &columnChange{
+				oldName:  spec.OldColumnName.Name.L,
+				col:      spec.NewColumns[0],
+				position: spec.Position,
+			})
+		case ast.AlterTableModifyColumn:
+			changes = append(changes, &columnChange{
+				oldName:  spec.NewColumns[0].Name.Name.L,
+				col:      spec.NewColumns[0],
+				position: spec.Position,
+			})
+		case ast.AlterTableRenameColumn:
+			changes = append(changes, &columnChange{
+				oldName: spec.OldColumnName.Name.L,
+				col:     &ast.ColumnDef{Name: spec.NewColumnName},
+			})
+		}
+	}
+
+	newCols := []*ast.ColumnDef{}
+	columns := map[string]*ast.ColumnName{}
+	for _, col := range oldCols {
+		columnName := col.Name.Name.L
+
+		dropped := false
+		for _, spec := range drops {
+			if _, ok := droppedSpecs[spec]; ok {
+				continue
+			}
+			if spec.OldColumnName.Name.L == columnName {
+				droppedSpecs[spec] = struct{}{}
+				dropped = true
+				break
+			}
+		}
+		if dropped {
+			columns[columnName] = nil
+			continue
+		}
+
+		var change *columnChange
+		for _, cc := range changes {
+			if !cc.matched && cc.oldName != "" && cc.oldName == columnName {
+				change = cc
+				break
+			}
+		}
+		if change != nil {
+			change.matched = true
+			columns[columnName] = change.col.Name
+			// RENAME COLUMN keeps the old definition under the new name.
+			if change.col.Tp == nil {
+				change.col.Tp = col.Tp
+				change.col.Options = col.Options
+			}
+			if !hasColumnPosition(change.position) {
+				newCols = append(newCols, change.col)
+			}
+			continue
+		}
+
+		for _, spec := range alters {
+			if spec.NewColumns[0].Name.Name.L == columnName {
+				alteredSpecs[spec] = struct{}{}
+				alterColumnDefault(col, spec.NewColumns[0])
+			}
+		}
+		newCols = append(newCols, col)
+	}
+
+	for _, cc := range changes {
+		if cc.oldName != "" && !cc.matched {
+			return nil, nil, unknownColumnError(schemaName, tableName, cc.oldName)
+		}
+		if cc.oldName != "" && !hasColumnPosition(cc.position) {
+			continue
+		}
+		if cc.oldName == "" && hasColumn(newCols, cc.col.Name.Name.L) {
+			return nil, nil, duplicateColumnError(schemaName, tableName, cc.col.Name.Name.L)
+		}
+
+		var err error
+		newCols, err = insertColumn(newCols, cc.col, cc.position, schemaName, tableName)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, spec := range drops {
+		if _, ok := droppedSpecs[spec]; ok || spec.IfExists {
+			continue
+		}
+		return nil, nil, cantDropColumnError(schemaName, tableName, spec.OldColumnName.Name.L)
+	}
+
+	for _, spec := range alters {
+		if _, ok := alteredSpecs[spec]; ok {
+			continue
+		}
+		return nil, nil, unknownColumnError(schemaName, tableName, spec.NewColumns[0].Name.Name.L)
+	}
+
+	names := map[string]struct{}{}
+	for _, col := range newCols {
+		columnName := col.Name.Name.L
+		if _, ok := names[columnName]; ok {
+			return nil, nil, duplicateColumnError(schemaName, tableName, columnName)
+		}
+		names[columnName] = struct{}{}
+	}
+	return newCols, columns, nil
+}
+
+// alterConstraints drops, renames and adds the indexes of the table according to the specs.
+// Like MySQL, the original indexes follow the column changes: dropped columns are removed
+// from the keys, renamed columns are renamed in the keys, and indexes without any key
+// part left are dropped.
+func alterConstraints(table *ast.CreateTableStmt, specs []*ast.AlterTableSpec,
+	columns map[string]*ast.ColumnName, schemaName, tableName string) error {
+
+	drops := getAlterTableSpecByTp(specs, ast.AlterTableDropIndex,
+		ast.AlterTableDropPrimaryKey, ast.AlterTableDropForeignKey)
+	droppedSpecs := map[*ast.AlterTableSpec]struct{}{}
+	renames := getAlterTableSpecByTp(specs, ast.AlterTableRenameIndex)
+	renamedSpecs := map[*ast.AlterTableSpec]struct{}{}
+
+	newConstraints := []*ast.Constraint{}
+	for _, constraint := range table.Constraints {
+		dropped := false
+		for _, spec := range drops {
+			if _, ok := droppedSpecs[spec]; ok {
+				continue
+			}
+			if isDropConstraintSpec(spec, constraint) {
+				droppedSpecs[spec] = struct{}{}
+				dropped = true
+				break
+			}
+		}
+		if dropped {
+			continue
+		}
+
+		keep, err := cascadeColumnChanges(constraint, columns, schemaName, tableName)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
+
+		for _, spec := range renames {
+			if _, ok := renamedSpecs[spec]; ok {
+				continue
+			}
+			if isIndexConstraint(constraint) && strings.EqualFold(spec.FromKey.O, constraint.Name) {
+				renamedSpecs[spec] = struct{}{}
+				constraint.Name = spec.ToKey.O
+				break
+			}
+		}
+		newConstraints = append(newConstraints, constraint)
+	}
+	table.Constraints = newConstraints
+
+	// the primary key may also be declared in the column definition.
+	for _, spec := range getAlterTableSpecByTp(drops, ast.AlterTableDropPrimaryKey) {
+		if _, ok := droppedSpecs[spec]; ok {
+			continue
+		}
+		for _, col := range table.Cols {
+			if hasOneInOptions(col.Options, ast.ColumnOptionPrimaryKey) {
+				col.Options = removeColumnOptions(col.Options, ast.ColumnOptionPrimaryKey)
+				droppedSpecs[spec] = struct{}{}
+			}
+		}
+	}
+
+	for _, spec := range getAlterTableSpecByTp(specs, ast.AlterTableAddColumns, ast.AlterTableAddConstraint) {
+		constraints := spec.NewConstraints
+		if spec.Tp == ast.AlterTableAddConstraint {
+			constraints = []*ast.Constraint{spec.Constraint}
+		}
+		for _, constraint := range constraints {
+			if constraint.Tp == ast.ConstraintPrimaryKey {
+				if hasPrimaryKey(table) {
+					return existPrimaryKeyError(schemaName, tableName)
+				}
+				table.Constraints = append(table.Constraints, constraint)
+				continue
+			}
+			if constraint.Name != "" && hasConstraint(table.Constraints, constraint) {
+				if constraint.IfNotExists {
+					continue
+				}
+				if constraint.Tp == ast.ConstraintCheck {
+					return duplicateCheckError(schemaName, tableName, constraint.Name)
+				}
+				return duplicateIndexError(schemaName, tableName, constraint.Name)
+			}
+			table.Constraints = append(table.Constraints, constraint)
+		}
+	}
+
+	for _, spec := range drops {
+		if _, ok := droppedSpecs[spec]; ok {
+			continue
+		}
+		if spec.Tp == ast.AlterTableDropPrimaryKey ||
+			spec.Tp == ast.AlterTableDropIndex && strings.EqualFold(spec.Name, "PRIMARY") {
+			return noPrimaryKeyError(schemaName, tableName)
+		}
+		if spec.IfExists {
+			continue
+		}
+		return cantDropIndexError(schemaName, tableName, spec.Name)
+	}
+
+	for _, spec := range renames {
+		if _, ok := renamedSpecs[spec]; ok {
+			continue
+		}
+		return unknownIndexError(schemaName, tableName, spec.FromKey.O)
+	}
+
+	names := map[string]struct{}{}
+	for _, constraint := range table.Constraints {
+		if !isIndexConstraint(constraint) || constraint.Name == "" {
+			continue
+		}
+		indexName := strings.ToLower(constraint.Name)
+		if _, ok := names[indexName]; ok {
+			return duplicateIndexError(schemaName, tableName, constraint.Name)
+		}
+		names[indexName] = struct{}{}
+	}
+
+	if countPrimaryKey(table) > 1 {
+		return existPrimaryKeyError(schemaName, tableName)
+	}
+	return nil
+}
+
+// cascadeColumnChanges renames and removes the key parts of the constraint according to
+// the column changes, it returns false when no key part is left.
+func cascadeColumnChanges(constraint *ast.Constraint, columns map[string]*ast.ColumnName,
+	schemaName, tableName string) (bool, error) {
+
+	if len(constraint.Keys) == 0 {
+		return true, nil
+	}
+
+	keys := []*ast.IndexPartSpecification{}
+	for _, key := range constraint.Keys {
+		if key.Column == nil {
+			keys = append(keys, key)
+			continue
+		}
+		newName, ok := columns[key.Column.Name.L]
+		if !ok {
+			keys = append(keys, key)
+			continue
+		}
+		if newName == nil {
+			if constraint.Tp == ast.ConstraintForeignKey {
+				err := newColumnError(ErrFKColumnCannotDrop, schemaName, tableName, key.Column.Name.O,
+					fmt.Sprintf(DropColumnInForeignKeyErrorPattern, key.Column.Name, constraint.Name, schemaName, tableName))
+				err.Index = constraint.Name
+				return false, err
+			}
+			continue
+		}
+		key.Column.Name = newName.Name
+		keys = append(keys, key)
+	}
+	constraint.Keys = keys
+
+	// a foreign key referring to the table itself follows the renamed columns.
+	if constraint.Tp == ast.ConstraintForeignKey && isReferToTable(constraint.Refer, schemaName, tableName) {
+		for _, key := range constraint.Refer.IndexPartSpecifications {
+			if key.Column == nil {
+				continue
+			}
+			if newName := columns[key.Column.Name.L]; newName != nil {
+				key.Column.Name = newName.Name
+			}
+		}
+	}
+	return len(keys) > 0, nil
+}
+
+// isReferToTable reports whether the foreign key refers to the table.
+func isReferToTable(refer *ast.ReferenceDef, schemaName, tableName string) bool {
+	if refer == nil || refer.Table == nil {
+		return false
+	}
+	refSchema := refer.Table.Schema.O
+	return (refSchema == "" || strings.EqualFold(refSchema, schemaName)) &&
+		strings.EqualFold(refer.Table.Name.O, tableName)
+}
+
+// isDropConstraintSpec reports whether the DROP spec drops the constraint.
+func isDropConstraintSpec(spec *ast.AlterTableSpec, constraint *ast.Constraint) bool {
+	switch spec.Tp {
+	case ast.AlterTableDropPrimaryKey:
+		return constraint.Tp == ast.ConstraintPrimaryKey
+	case ast.AlterTableDropForeignKey:
+		return constraint.Tp == ast.ConstraintForeignKey &&
+			strings.EqualFold(spec.Name, constraint.Name)
+	case ast.AlterTableDropIndex:
+		// "DROP INDEX `PRIMARY`" drops the primary key.
+		if constraint.Tp == ast.ConstraintPrimaryKey {
+			return strings.EqualFold(spec.Name, "PRIMARY")
+		}
+		return isIndexConstraint(constraint) && strings.EqualFold(spec.Name, constraint.Name)
+	}
+	return false
+}
+
+// isIndexConstraint reports whether the constraint is an index.
+func isIndexConstraint(constraint *ast.Constraint) bool {
+	return constraintNamespace(constraint) == ast.ConstraintIndex
+}
+
+// constraintNamespace returns the namespace of the constraint name, the names of
+// indexes, foreign keys and checks do not conflict with each other.
+func constraintNamespace(constraint *ast.Constraint) ast.ConstraintType {
+	switch constraint.Tp {
+	case ast.ConstraintForeignKey, ast.ConstraintCheck:
+		return constraint.Tp
+	}
+	return ast.ConstraintIndex
+}
+
+func hasConstraint(constraints []*ast.Constraint, constraint *ast.Constraint) bool {
+	for _, con := range constraints {
+		if constraintNamespace(con) == constraintNamespace(constraint) &&
+			strings.EqualFold(con.Name, constraint.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasColumn(cols []*ast.ColumnDef, columnName string) bool {
+	for _, col := range cols {
+		if col.Name.Name.L == columnName {
+			return true
+		}
+	}
+	return false
+}
+
+func hasColumnPosition(position *ast.ColumnPosition) bool {
+	return position != nil && position.Tp != ast.ColumnPositionNone
+}
+
+// insertColumn places the column in cols according to FIRST or AFTER.
+func insertColumn(cols []*ast.ColumnDef, col *ast.ColumnDef, position *ast.ColumnPosition,
+	schemaName, tableName string) ([]*ast.ColumnDef, error) {
+
+	if !hasColumnPosition(position) {
+		return append(cols, col), nil
+	}
+	if position.Tp == ast.ColumnPositionFirst {
+		return append([]*ast.ColumnDef{col}, cols...), nil
+	}
+
+	relativeName := position.RelativeColumn.Name.L
+	for i, c := range cols {
+		if c.Name.Name.L == relativeName {
+			newCols := append([]*ast.ColumnDef{}, cols[:i+1]...)
+			newCols = append(newCols, col)
+			return append(newCols, cols[i+1:]...), nil
+		}
+	}
+	return nil, unknownColumnError(schemaName, tableName, relativeName)
+}
+
+// alterColumnDefault applies "ALTER COLUMN SET DEFAULT" or "ALTER COLUMN DROP DEFAULT" to col.
+func alterColumnDefault(col *ast.ColumnDef, newCol *ast.ColumnDef) {
+	// alter table alter column drop default
+	if len(newCol.Options) == 0 {
+		col.Options = removeColumnOptions(col.Options, ast.ColumnOptionDefaultValue)
+		return
+	}
+
+	defaultOption := &ast.ColumnOption{
+		Tp:   ast.ColumnOptionDefaultValue,
+		Expr: newCol.Options[0].Expr,
+	}
+	for i, op := range col.Options {
+		if op.Tp == ast.ColumnOptionDefaultValue {
+			col.Options[i] = defaultOption
+			return
+		}
+	}
+	col.Options = append(col.Options, defaultOption)
+}
+
+func removeColumnOptions(options []*ast.ColumnOption, opTp ...ast.ColumnOptionType) []*ast.ColumnOption {
+	newOptions := []*ast.ColumnOption{}
+	for _, op := range options {
+		if !hasOneInOptions([]*ast.ColumnOption{op}, opTp...) {
+			newOptions = append(newOptions, op)
+		}
+	}
+	return newOptions
+}
+
+// mergeTableOptions replaces the options of the same type and appends the others.
+func mergeTableOptions(options []*ast.TableOption, newOptions []*ast.TableOption) []*ast.TableOption {
+	merged := append([]*ast.TableOption{}, options...)
+	for _, newOp := range newOptions {
+		replaced := false
+		for i, op := range merged {
+			if op.Tp == newOp.Tp {
+				merged[i] = newOp
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, newOp)
+		}
+	}
+	return merged
+}
+
+// isConvertCharset reports whether the spec is CONVERT TO CHARACTER SET.
+func isConvertCharset(spec *ast.AlterTableSpec) bool {
+	return len(spec.Options) != 0 && spec.Options[0].Tp == ast.TableOptionCharset &&
+		spec.Options[0].UintValue == ast.TableOptionCharsetWithConvertTo
+}
+
+// convertCharsetOptions returns the DEFAULT CHARSET and COLLATE table options of
+// CONVERT TO CHARACTER SET, CHARACTER SET DEFAULT is the charset of the schema.
+func (c *VirtualDB) convertCharsetOptions(schemaName string, options []*ast.TableOption) []*ast.TableOption {
+	charset, collation := normalizeCharset(options[0].StrValue), ""
+	if len(options) > 1 {
+		collation = normalizeCollation(options[1].StrValue)
+	}
+	if options[0].Default {
+		var schemaStmt *ast.CreateDatabaseStmt
+		if schema, exist := c.getSchema(schemaName); exist {
+			schemaStmt = schema.Schema
+		}
+		schemaCharset, schemaCollation := SchemaCharset(schemaStmt, c.serverVersion)
+		charset = schemaCharset
+		if collation == "" {
+			collation = schemaCollation
+		}
+	}
+	if collation == "" {
+		collation = DefaultCollation(charset, c.serverVersion)
+	}
+	return []*ast.TableOption{
+		{Tp: ast.TableOptionCharset, StrValue: charset},
+		{Tp: ast.TableOptionCollate, StrValue: collation},
+	}
+}
+
+// textTypes are the TEXT types in ascending order of their max length in bytes.
+var textTypes = []struct {
+	tp     byte
+	maxLen int64
+}{
+	{mysql.TypeTinyBlob, 255},
+	{mysql.TypeBlob, 65535},
+	{mysql.TypeMediumBlob, 16777215},
+	{mysql.TypeLongBlob, 4294967295},
+}
+
+// convertColumnsCharset gives the string columns the charset and collation of the
+// table after CONVERT TO CHARACTER SET, oldOptions are the table options before
+// it. Like MySQL a TEXT column becomes a larger TEXT type when it can't hold as
+// many characters in the new charset, the other columns keep their types.
+func (c *VirtualDB) convertColumnsCharset(schemaName string, table *ast.CreateTableStmt, oldOptions []*ast.TableOption) {
+	var schemaStmt *ast.CreateDatabaseStmt
+	if schema, exist := c.getSchema(schemaName); exist {
+		schemaStmt = schema.Schema
+	}
+	charset, collation := TableCharset(schemaStmt, table, c.serverVersion)
+	oldTable := &ast.CreateTableStmt{Options: oldOptions}
+
+	for i, col := range table.Cols {
+		if !hasCharset(col) {
+			continue
+		}
+		oldCharset, _ := ColumnCharset(schemaStmt, oldTable, col, c.serverVersion)
+		// the column is replaced since it may be a node of the ALTER TABLE.
+		tp := *col.Tp
+		tp.Charset = charset
+		tp.Collate = collation
+		tp.Flag &^= mysql.BinaryFlag
+		for j, textType := range textTypes {
+			if tp.Tp != textType.tp {
+				continue
+			}
+			chars := textType.maxLen / int64(getCharsetMaxLen(oldCharset))
+			for _, newType := range textTypes[j:] {
+				tp.Tp = newType.tp
+				if chars*int64(getCharsetMaxLen(charset)) <= newType.maxLen {
+					break
+				}
+			}
+			break
+		}
+		newCol := *col
+		newCol.Tp = &tp
+		newCol.Options = removeColumnOptions(col.Options, ast.ColumnOptionCollate)
+		if tp.Tp == mysql.TypeEnum || tp.Tp == mysql.TypeSet {
+			// the charset of ENUM and SET is not restored from the type.
+			newCol.Options = append(newCol.Options, &ast.ColumnOption{Tp: ast.ColumnOptionCollate, StrValue: collation})
+		}
+		table.Cols[i] = &newCol
+	}
+}
@@ -0,0 +1,280 @@
+package virtualdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type alterCase struct {
+	name        string
+	input       string
+	expect      string
+	expectError string
+}
+
+// testAlterCases runs each case in the schema db1.
+func testAlterCases(t *testing.T, cases []alterCase) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			testExec(t, "create database db1; use db1;\n"+c.input,
+				"CREATE DATABASE `db1`;\n"+c.expect, c.expectError)
+		})
+	}
+}
+
+func TestMultiSpecAlterTable(t *testing.T) {
+	cases := []alterCase{
+		{
+			name:        "add then drop a new column",
+			input:       "create table t1(a int); alter table t1 add column x int, drop column x;",
+			expectError: "not exist column x in db1.t1",
+		},
+		{
+			name:   "drop then add an existing column",
+			input:  "create table t1(a int, x int); alter table t1 drop column x, add column x varchar(10);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`x` VARCHAR(10));\n",
+		},
+		{
+			name:   "drop then add the same index",
+			input:  "create table t1(a int, b int, index a(a)); alter table t1 drop index a, add index a(b);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,INDEX `a`(`b`));\n",
+		},
+		{
+			name:   "add then drop an existing index",
+			input:  "create table t1(a int, b int, index a(a)); alter table t1 add index a(b), drop index a;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,INDEX `a`(`b`));\n",
+		},
+		{
+			name:        "add then drop a new index",
+			input:       "create table t1(a int); alter table t1 add index a(a), drop index a;",
+			expectError: "not exist index a in db1.t1",
+		},
+		{
+			name:        "add a duplicate index",
+			input:       "create table t1(a int, index a(a)); alter table t1 add index a(a);",
+			expectError: "duplicate index a in db1.t1",
+		},
+		{
+			name:   "swap two columns by change",
+			input:  "create table t1(a int, b varchar(10)); alter table t1 change a b int, change b a varchar(10);",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT,`a` VARCHAR(10));\n",
+		},
+		{
+			name:        "change a dropped column",
+			input:       "create table t1(a int, b int); alter table t1 drop column a, change a c int;",
+			expectError: "not exist column a in db1.t1",
+		},
+		{
+			name:        "drop a column twice",
+			input:       "create table t1(a int, b int); alter table t1 drop column a, drop column a;",
+			expectError: "not exist column a in db1.t1",
+		},
+		{
+			name:   "drop a missing column if exists",
+			input:  "create table t1(a int, b int); alter table t1 drop column if exists c;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT);\n",
+		},
+		{
+			name:   "add columns first and after",
+			input:  "create table t1(a int, b int); alter table t1 add column c int first, add column d int after a;",
+			expect: "CREATE TABLE `db1`.`t1` (`c` INT,`a` INT,`d` INT,`b` INT);\n",
+		},
+		{
+			name:   "modify after a column added earlier",
+			input:  "create table t1(a int, b int); alter table t1 add column c int, modify a bigint after c;",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT,`c` INT,`a` BIGINT);\n",
+		},
+		{
+			name:        "modify after a column added later",
+			input:       "create table t1(a int, b int); alter table t1 modify a bigint after c, add column c int;",
+			expectError: "not exist column c in db1.t1",
+		},
+		{
+			name:   "change first",
+			input:  "create table t1(a int, b int); alter table t1 change b c int first;",
+			expect: "CREATE TABLE `db1`.`t1` (`c` INT,`a` INT);\n",
+		},
+		{
+			name:        "change to an existing column",
+			input:       "create table t1(a int, b int); alter table t1 change a b int;",
+			expectError: "duplicate column b in db1.t1",
+		},
+		{
+			name:   "rename column and reuse the old name",
+			input:  "create table t1(a int not null, b int); alter table t1 rename column a to c, add column a int;",
+			expect: "CREATE TABLE `db1`.`t1` (`c` INT NOT NULL,`b` INT,`a` INT);\n",
+		},
+		{
+			name:        "alter a changed column by its old name",
+			input:       "create table t1(a int, b int); alter table t1 change a c int, alter column a set default 1;",
+			expectError: "not exist column a in db1.t1",
+		},
+		{
+			name:   "set and drop default",
+			input:  "create table t1(a int default 1, b int); alter table t1 alter column a drop default, alter column b set default 2;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT DEFAULT 2);\n",
+		},
+		{
+			name:   "rename an index and add one with the old name",
+			input:  "create table t1(a int, b int, index i1(a)); alter table t1 rename index i1 to i2, add index i1(b);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,INDEX `i2`(`a`),INDEX `i1`(`b`));\n",
+		},
+		{
+			name:        "rename an index to an existing name",
+			input:       "create table t1(a int, b int, index i1(a), index i2(b)); alter table t1 rename index i1 to i2;",
+			expectError: "duplicate index i2 in db1.t1",
+		},
+		{
+			name:        "rename a dropped index",
+			input:       "create table t1(a int, index i1(a)); alter table t1 drop index i1, rename index i1 to i2;",
+			expectError: "not exist index i1 in db1.t1",
+		},
+		{
+			name:   "drop and add primary key",
+			input:  "create table t1(a int, b int, primary key(a)); alter table t1 drop primary key, add primary key(b);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,PRIMARY KEY(`b`));\n",
+		},
+		{
+			name:   "drop a column level primary key",
+			input:  "create table t1(a int primary key, b int); alter table t1 drop primary key, add primary key(a, b);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,PRIMARY KEY(`a`, `b`));\n",
+		},
+		{
+			name:        "add a second primary key",
+			input:       "create table t1(a int primary key, b int); alter table t1 add primary key(b);",
+			expectError: "primary key exists",
+		},
+		{
+			name:        "add a primary key column",
+			input:       "create table t1(a int, primary key(a)); alter table t1 add column b int primary key;",
+			expectError: "primary key exists",
+		},
+		{
+			name:        "drop a missing primary key",
+			input:       "create table t1(a int); alter table t1 drop primary key;",
+			expectError: "no primary key",
+		},
+		{
+			name:   "change table options",
+			input:  "create table t1(a int) engine=innodb comment='old'; alter table t1 comment='new', add column b int;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT) ENGINE = innodb COMMENT = 'new';\n",
+		},
+		{
+			name:   "rename table and add column",
+			input:  "create table t1(a int); alter table t1 add column b int, rename to t2;",
+			expect: "CREATE TABLE `db1`.`t2` (`a` INT,`b` INT);\n",
+		},
+		{
+			name:  "rename table to another schema",
+			input: "create database db2; create table t1(a int); alter table t1 rename to db2.t1;",
+			expect: "CREATE DATABASE `db2`;\n" +
+				"CREATE TABLE `db2`.`t1` (`a` INT);\n",
+		},
+	}
+	testAlterCases(t, cases)
+}
+
+func TestAlterTableCascadeColumns(t *testing.T) {
+	cases := []alterCase{
+		{
+			name:   "drop a column of a composite index",
+			input:  "create table t1(a int, b int, c int, index i1(a, b)); alter table t1 drop column a;",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT,`c` INT,INDEX `i1`(`b`));\n",
+		},
+		{
+			name:   "drop the only column of an index",
+			input:  "create table t1(a int, b int, index i1(a), unique key u1(a)); alter table t1 drop column a;",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT);\n",
+		},
+		{
+			name:   "drop a column of the primary key",
+			input:  "create table t1(a int, b int, c int, primary key(a, b)); alter table t1 drop column a;",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT,`c` INT,PRIMARY KEY(`b`));\n",
+		},
+		{
+			name:   "drop the primary key column",
+			input:  "create table t1(a int, b int, primary key(a)); alter table t1 drop column a, add primary key(b);",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT,PRIMARY KEY(`b`));\n",
+		},
+		{
+			name:   "drop a column and reuse the index name",
+			input:  "create table t1(a int, b int, index i1(a)); alter table t1 drop column a, add index i1(b);",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT,INDEX `i1`(`b`));\n",
+		},
+		{
+			name:   "change a column of an index",
+			input:  "create table t1(a varchar(20), b int, index i1(a(10), b)); alter table t1 change a c varchar(20);",
+			expect: "CREATE TABLE `db1`.`t1` (`c` VARCHAR(20),`b` INT,INDEX `i1`(`c`(10), `b`));\n",
+		},
+		{
+			name:   "rename a column of the primary key",
+			input:  "create table t1(a int, b int, primary key(a)); alter table t1 rename column a to c;",
+			expect: "CREATE TABLE `db1`.`t1` (`c` INT,`b` INT,PRIMARY KEY(`c`));\n",
+		},
+		{
+			name:   "swap two columns of an index",
+			input:  "create table t1(a int, b int, index i1(a)); alter table t1 change a b int, change b a int;",
+			expect: "CREATE TABLE `db1`.`t1` (`b` INT,`a` INT,INDEX `i1`(`b`));\n",
+		},
+		{
+			name:  "rename a column of a foreign key",
+			input: "create table t1(a int, primary key(a)); create table t2(b int, constraint fk foreign key (b) references t1(a)); alter table t2 change b c int;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,PRIMARY KEY(`a`));\n" +
+				"CREATE TABLE `db1`.`t2` (`c` INT,CONSTRAINT `fk` FOREIGN KEY (`c`) REFERENCES `t1`(`a`));\n",
+		},
+		{
+			name: "rename a column referred by a foreign key of the table itself",
+			input: "create table t1(id int, pid int, primary key(id), constraint fk foreign key (pid) references t1(id));" +
+				"alter table t1 rename column id to uid;",
+			expect: "CREATE TABLE `db1`.`t1` (`uid` INT,`pid` INT,PRIMARY KEY(`uid`)," +
+				"CONSTRAINT `fk` FOREIGN KEY (`pid`) REFERENCES `t1`(`uid`));\n",
+		},
+		{
+			name:        "drop a column of a foreign key",
+			input:       "create table t1(a int, primary key(a)); create table t2(b int, constraint fk foreign key (b) references t1(a)); alter table t2 drop column b;",
+			expectError: "can't drop column b needed in foreign key fk in db1.t2",
+		},
+		{
+			name:  "drop a column and its foreign key",
+			input: "create table t1(a int, primary key(a)); create table t2(b int, c int, constraint fk foreign key (b) references t1(a)); alter table t2 drop foreign key fk, drop column b;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,PRIMARY KEY(`a`));\n" +
+				"CREATE TABLE `db1`.`t2` (`c` INT);\n",
+		},
+	}
+	testAlterCases(t, cases)
+}
+
+func TestAlterTableConvertCharset(t *testing.T) {
+	cases := []alterCase{
+		{
+			name: "convert the table and its string columns",
+			input: "create table t1(a varchar(10) character set latin1, b text, c int, d enum('x') collate latin1_bin) " +
+				"default charset latin1; alter table t1 convert to character set utf8mb4 collate utf8mb4_bin;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` VARCHAR(10) CHARACTER SET UTF8MB4 COLLATE utf8mb4_bin," +
+				"`b` MEDIUMTEXT CHARACTER SET UTF8MB4 COLLATE utf8mb4_bin,`c` INT,`d` ENUM('x') COLLATE utf8mb4_bin) " +
+				"DEFAULT CHARACTER SET = UTF8MB4 DEFAULT COLLATE = UTF8MB4_BIN;\n",
+		},
+		{
+			name:  "convert to the default charset of the schema",
+			input: "create table t1(a varchar(10) charset latin1); alter table t1 convert to character set default;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` VARCHAR(10) CHARACTER SET UTF8MB4 COLLATE utf8mb4_0900_ai_ci) " +
+				"DEFAULT CHARACTER SET = UTF8MB4 DEFAULT COLLATE = UTF8MB4_0900_AI_CI;\n",
+		},
+	}
+	testAlterCases(t, cases)
+
+	vb := NewVirtualDBWithOpt("db1", DBOption{ServerVersion: 80040})
+	err := vb.ExecSQL("create table t1(a varchar(10) character set latin1, b tinytext) default charset latin1;" +
+		"alter table t1 convert to character set utf8mb4 collate utf8mb4_bin;")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rs, err := vb.QuerySQL("show create table t1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CREATE TABLE `t1` (\n"+
+			"  `a` varchar(10) COLLATE utf8mb4_bin DEFAULT NULL,\n"+
+			"  `b` text COLLATE utf8mb4_bin\n"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin", rs.Rows[0][1].String)
+	}
+}
@@ -0,0 +1,177 @@
+package virtualdb
+
+import (
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/mysql"
+)
+
+// DefaultCharset is the default character_set_server since MySQL 8.0, before
+// which it is latin1.
+const DefaultCharset = "utf8mb4"
+
+// charsetMaxLen is the max length in bytes of one character of each MySQL charset.
+var charsetMaxLen = map[string]int{
+	"armscii8": 1, "ascii": 1, "big5": 2, "binary": 1, "cp1250": 1, "cp1251": 1,
+	"cp1256": 1, "cp1257": 1, "cp850": 1, "cp852": 1, "cp866": 1, "cp932": 2,
+	"dec8": 1, "eucjpms": 3, "euckr": 2, "gb18030": 4, "gb2312": 2, "gbk": 2,
+	"geostd8": 1, "greek": 1, "hebrew": 1, "hp8": 1, "keybcs2": 1, "koi8r": 1,
+	"koi8u": 1, "latin1": 1, "latin2": 1, "latin5": 1, "latin7": 1, "macce": 1,
+	"macroman": 1, "sjis": 2, "swe7": 1, "tis620": 1, "ucs2": 2, "ujis": 3,
+	"utf16": 4, "utf16le": 4, "utf32": 4, "utf8": 3, "utf8mb3": 3, "utf8mb4": 4,
+}
+
+// defaultCollations is the default collation of each charset, utf8mb4 defaults
+// to utf8mb4_0900_ai_ci since MySQL 8.0.
+var defaultCollations = map[string]string{
+	"armscii8": "armscii8_general_ci", "ascii": "ascii_general_ci", "big5": "big5_chinese_ci",
+	"binary": "binary", "cp1250": "cp1250_general_ci", "cp1251": "cp1251_general_ci",
+	"cp1256": "cp1256_general_ci", "cp1257": "cp1257_general_ci", "cp850": "cp850_general_ci",
+	"cp852": "cp852_general_ci", "cp866": "cp866_general_ci", "cp932": "cp932_japanese_ci",
+	"dec8": "dec8_swedish_ci", "eucjpms": "eucjpms_japanese_ci", "euckr": "euckr_korean_ci",
+	"gb18030": "gb18030_chinese_ci", "gb2312": "gb2312_chinese_ci", "gbk": "gbk_chinese_ci",
+	"geostd8": "geostd8_general_ci", "greek": "greek_general_ci", "hebrew": "hebrew_general_ci",
+	"hp8": "hp8_english_ci", "keybcs2": "keybcs2_general_ci", "koi8r": "koi8r_general_ci",
+	"koi8u": "koi8u_general_ci", "latin1": "latin1_swedish_ci", "latin2": "latin2_general_ci",
+	"latin5": "latin5_turkish_ci", "latin7": "latin7_general_ci", "macce": "macce_general_ci",
+	"macroman": "macroman_general_ci", "sjis": "sjis_japanese_ci", "swe7": "swe7_swedish_ci",
+	"tis620": "tis620_thai_ci", "ucs2": "ucs2_general_ci", "ujis": "ujis_japanese_ci",
+	"utf16": "utf16_general_ci", "utf16le": "utf16le_general_ci", "utf32": "utf32_general_ci",
+	"utf8": "utf8_general_ci", "utf8mb4": "utf8mb4_0900_ai_ci",
+}
+
+// getCharsetMaxLen returns the max bytes of one character, unknown charsets take 4 bytes.
+func getCharsetMaxLen(charset string) int {
+	if maxLen, ok := charsetMaxLen[strings.ToLower(charset)]; ok {
+		return maxLen
+	}
+	return 4
+}
+
+// normalizeCharset returns the charset in lowercase, utf8mb3 is the alias of utf8.
+func normalizeCharset(charset string) string {
+	charset = strings.ToLower(charset)
+	if charset == "utf8mb3" {
+		return "utf8"
+	}
+	return charset
+}
+
+// normalizeCollation returns the collation in lowercase, utf8mb3_* is the alias of utf8_*.
+func normalizeCollation(collation string) string {
+	collation = strings.ToLower(collation)
+	if strings.HasPrefix(collation, "utf8mb3_") {
+		return "utf8_" + collation[len("utf8mb3_"):]
+	}
+	return collation
+}
+
+// ServerDefaultCharset returns the default character_set_server of the MySQL version.
+func ServerDefaultCharset(serverVersion int) string {
+	if serverVersion < 80000 {
+		return "latin1"
+	}
+	return DefaultCharset
+}
+
+// DefaultCollation returns the default collation of the charset in the MySQL version.
+func DefaultCollation(charset string, serverVersion int) string {
+	charset = normalizeCharset(charset)
+	if charset == "utf8mb4" && serverVersion < 80000 {
+		return "utf8mb4_general_ci"
+	}
+	if collation, ok := defaultCollations[charset]; ok {
+		return collation
+	}
+	return charset + "_general_ci"
+}
+
+// CharsetOfCollation returns the charset of the collation, such as utf8mb4 of utf8mb4_bin.
+func CharsetOfCollation(collation string) string {
+	collation = normalizeCollation(collation)
+	if i := strings.Index(collation, "_"); i > 0 {
+		return collation[:i]
+	}
+	return collation
+}
+
+// resolveCharset returns the effective charset and collation of an object, which
+// inherits them from its parent when it specifies neither of them.
+func resolveCharset(charset, collation, parentCharset, parentCollation string, serverVersion int) (string, string) {
+	charset = normalizeCharset(charset)
+	collation = normalizeCollation(collation)
+	switch {
+	case charset == "" && collation == "":
+		return parentCharset, parentCollation
+	case collation == "":
+		return charset, DefaultCollation(charset, serverVersion)
+	case charset == "":
+		return CharsetOfCollation(collation), collation
+	}
+	return charset, collation
+}
+
+// SchemaCharset returns the effective charset and collation of the schema, a nil
+// schema uses the defaults of the server.
+func SchemaCharset(schema *ast.CreateDatabaseStmt, serverVersion int) (string, string) {
+	serverCharset := ServerDefaultCharset(serverVersion)
+	charset, collation := "", ""
+	if schema != nil {
+		for _, op := range schema.Options {
+			switch op.Tp {
+			case ast.DatabaseOptionCharset:
+				charset = op.Value
+			case ast.DatabaseOptionCollate:
+				collation = op.Value
+			}
+		}
+	}
+	return resolveCharset(charset, collation, serverCharset, DefaultCollation(serverCharset, serverVersion), serverVersion)
+}
+
+// TableCharset returns the effective charset and collation of the table.
+func TableCharset(schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt, serverVersion int) (string, string) {
+	charset, collation := "", ""
+	for _, op := range table.Options {
+		switch op.Tp {
+		case ast.TableOptionCharset:
+			charset = op.StrValue
+		case ast.TableOptionCollate:
+			collation = op.StrValue
+		}
+	}
+	schemaCharset, schemaCollation := SchemaCharset(schema, serverVersion)
+	return resolveCharset(charset, collation, schemaCharset, schemaCollation, serverVersion)
+}
+
+// ColumnCharset returns the effective charset and collation of the column, they
+// are empty for the columns of non-string types.
+func ColumnCharset(schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt, col *ast.ColumnDef,
+	serverVersion int) (string, string) {
+
+	if !hasCharset(col) {
+		return "", ""
+	}
+	collation := col.Tp.Collate
+	for _, op := range col.Options {
+		if op.Tp == ast.ColumnOptionCollate {
+			collation = op.StrValue
+		}
+	}
+	tableCharset, tableCollation := TableCharset(schema, table, serverVersion)
+	return resolveCharset(col.Tp.Charset, collation, tableCharset, tableCollation, serverVersion)
+}
+
+// hasCharset reports whether the column is of a string type with a charset.
+func hasCharset(col *ast.ColumnDef) bool {
+	if col.Tp == nil || col.Tp.Charset == "binary" {
+		return false
+	}
+	switch col.Tp.Tp {
+	case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeTinyBlob,
+		mysql.TypeBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeEnum, mysql.TypeSet:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package virtualdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultCollation(t *testing.T) {
+	assert.Equal(t, "utf8mb4_0900_ai_ci", DefaultCollation("utf8mb4", 80023))
+	assert.Equal(t, "utf8mb4_general_ci", DefaultCollation("utf8mb4", 50744))
+	assert.Equal(t, "utf8_general_ci", DefaultCollation("utf8mb3", 80023))
+	assert.Equal(t, "latin1_swedish_ci", DefaultCollation("LATIN1", 80023))
+	assert.Equal(t, "utf8", CharsetOfCollation("utf8mb3_bin"))
+	assert.Equal(t, "utf8mb4", CharsetOfCollation("utf8mb4_unicode_ci"))
+	assert.Equal(t, "latin1", ServerDefaultCharset(50744))
+	assert.Equal(t, "utf8mb4", ServerDefaultCharset(80000))
+}
+
+func TestColumnCharset(t *testing.T) {
+	testCases := []struct {
+		version   int
+		sql       string
+		column    string
+		charset   string
+		collation string
+	}{
+		{80000, "CREATE TABLE t (a VARCHAR(10))", "a", "utf8mb4", "utf8mb4_0900_ai_ci"},
+		{50744, "CREATE TABLE t (a VARCHAR(10))", "a", "latin1", "latin1_swedish_ci"},
+		{80000, "CREATE TABLE t (a VARCHAR(10)) CHARSET=utf8", "a", "utf8", "utf8_general_ci"},
+		{80000, "CREATE TABLE t (a VARCHAR(10)) COLLATE=utf8mb4_bin", "a", "utf8mb4", "utf8mb4_bin"},
+		{80000, "CREATE TABLE t (a VARCHAR(10) CHARACTER SET utf8) CHARSET=latin1", "a", "utf8", "utf8_general_ci"},
+		{80000, "CREATE TABLE t (a TEXT COLLATE latin1_bin) CHARSET=utf8", "a", "latin1", "latin1_bin"},
+		{80000, "CREATE TABLE t (a ENUM('x','y'))", "a", "utf8mb4", "utf8mb4_0900_ai_ci"},
+		{80000, "CREATE TABLE t (a INT)", "a", "", ""},
+		{80000, "CREATE TABLE t (a BLOB)", "a", "", ""},
+		{80000, "CREATE TABLE t (a VARBINARY(10))", "a", "", ""},
+	}
+
+	for _, testCase := range testCases {
+		c := NewVirtualDBWithOpt("db1", DBOption{ServerVersion: testCase.version})
+		assert.NoError(t, c.ExecSQL(testCase.sql))
+
+		schema, _ := c.GetSchema("db1")
+		tables, _ := c.GetTableStmts("db1")
+		col, _, _ := c.GetColumn("db1", "t", testCase.column)
+		charset, collation := ColumnCharset(schema, tables["t"].Table, col, c.ServerVersion())
+		assert.Equal(t, testCase.charset, charset, testCase.sql)
+		assert.Equal(t, testCase.collation, collation, testCase.sql)
+	}
+
+	c := NewVirtualDB("")
+	assert.NoError(t, c.ExecSQL("CREATE DATABASE db1 COLLATE=gbk_bin; CREATE TABLE db1.t (a CHAR(1));"))
+	schema, _ := c.GetSchema("db1")
+	tables, _ := c.GetTableStmts("db1")
+	table := tables["t"].Table
+	charset, collation := ColumnCharset(schema, table, table.Cols[0], c.ServerVersion())
+	assert.Equal(t, "gbk", charset)
+	assert.Equal(t, "gbk_bin", collation)
+}
@@ -0,0 +1,237 @@
+package virtualdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+)
+
+// checkNamePrefix returns the prefix of the names MySQL generates for the
+// unnamed checks of the table, they are named <table>_chk_<n>.
+func checkNamePrefix(tableName string) string {
+	return tableName + "_chk_"
+}
+
+// normalizeChecks moves the checks of the column definitions to the constraints
+// of the table and names the unnamed checks like MySQL, so the checks can be
+// dropped by name whether they are written with the column or not.
+// The column checks are named before the table checks, as the columns are
+// usually defined before the constraints.
+func normalizeChecks(table *ast.CreateTableStmt) {
+	columnChecks := []*ast.Constraint{}
+	for _, col := range table.Cols {
+		options := []*ast.ColumnOption{}
+		for _, op := range col.Options {
+			if op.Tp != ast.ColumnOptionCheck {
+				options = append(options, op)
+				continue
+			}
+			columnChecks = append(columnChecks, &ast.Constraint{
+				Tp:       ast.ConstraintCheck,
+				Expr:     op.Expr,
+				Enforced: op.Enforced,
+			})
+		}
+		col.Options = options
+	}
+
+	prefix := checkNamePrefix(table.Table.Name.O)
+	number := 0
+	for _, constraint := range table.Constraints {
+		if n, ok := getCheckNumber(constraint, prefix); ok && n > number {
+			number = n
+		}
+	}
+	for _, constraint := range append(columnChecks, table.Constraints...) {
+		if constraint.Tp == ast.ConstraintCheck && constraint.Name == "" {
+			number++
+			constraint.Name = prefix + strconv.Itoa(number)
+		}
+	}
+	table.Constraints = append(table.Constraints, columnChecks...)
+}
+
+// getCheckNumber returns the number of a check named by MySQL.
+func getCheckNumber(constraint *ast.Constraint, prefix string) (int, bool) {
+	if constraint.Tp != ast.ConstraintCheck || len(constraint.Name) <= len(prefix) ||
+		!strings.EqualFold(constraint.Name[:len(prefix)], prefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(constraint.Name[len(prefix):])
+	return n, err == nil
+}
+
+// renameChecks renames the checks named by MySQL when the table is renamed.
+func renameChecks(table *ast.CreateTableStmt, oldTableName string) {
+	oldPrefix := checkNamePrefix(oldTableName)
+	for _, constraint := range table.Constraints {
+		if n, ok := getCheckNumber(constraint, oldPrefix); ok {
+			constraint.Name = checkNamePrefix(table.Table.Name.O) + strconv.Itoa(n)
+		}
+	}
+}
+
+// alterChecks applies DROP CHECK and ALTER CHECK to the table.
+func alterChecks(table *ast.CreateTableStmt, specs []*ast.AlterTableSpec, schemaName, tableName string) error {
+	for _, spec := range getAlterTableSpecByTp(specs, ast.AlterTableDropCheck, ast.AlterTableAlterCheck) {
+		index := -1
+		for i, constraint := range table.Constraints {
+			if constraint.Tp == ast.ConstraintCheck && strings.EqualFold(constraint.Name, spec.Constraint.Name) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return newIndexError(ErrCheckConstraintNotFound, schemaName, tableName, spec.Constraint.Name,
+				fmt.Sprintf(NotExistCheckErrorPattern, spec.Constraint.Name, schemaName, tableName))
+		}
+
+		if spec.Tp == ast.AlterTableAlterCheck {
+			table.Constraints[index].Enforced = spec.Constraint.Enforced
+			continue
+		}
+		table.Constraints = append(table.Constraints[:index:index], table.Constraints[index+1:]...)
+	}
+	return nil
+}
+
+// getGeneratedOption returns the GENERATED ALWAYS AS option of the column.
+func getGeneratedOption(col *ast.ColumnDef) *ast.ColumnOption {
+	for _, op := range col.Options {
+		if op.Tp == ast.ColumnOptionGenerated {
+			return op
+		}
+	}
+	return nil
+}
+
+// isVirtualColumn reports whether the column is a virtual generated column.
+func isVirtualColumn(col *ast.ColumnDef) bool {
+	op := getGeneratedOption(col)
+	return op != nil && !op.Stored
+}
+
+// checkGeneratedColumnChanges checks the changed columns like MySQL, a virtual
+// column can't become stored or not generated, and the other way round.
+func checkGeneratedColumnChanges(oldCols, newCols []*ast.ColumnDef, columns map[string]*ast.ColumnName,
+	schemaName, tableName string) error {
+
+	for _, oldCol := range oldCols {
+		newName := columns[oldCol.Name.Name.L]
+		if newName == nil {
+			continue
+		}
+		for _, newCol := range newCols {
+			if newCol.Name.Name.L != newName.Name.L {
+				continue
+			}
+			oldGenerated, newGenerated := getGeneratedOption(oldCol), getGeneratedOption(newCol)
+			if (isVirtualColumn(oldCol) || isVirtualColumn(newCol)) &&
+				(oldGenerated == nil || newGenerated == nil || oldGenerated.Stored != newGenerated.Stored) {
+				err := newColumnError(ErrUnsupportedOnGeneratedColumn, schemaName, tableName, newCol.Name.Name.O,
+					fmt.Sprintf(GeneratedColumnStoredErrorPattern, newCol.Name.Name.O, schemaName, tableName))
+				err.arg = "Changing the STORED status"
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkColumnDependencies checks that the dropped and renamed columns are not
+// used by the generated columns and checks which are kept.
+func checkColumnDependencies(table *ast.CreateTableStmt, columns map[string]*ast.ColumnName,
+	schemaName, tableName string) error {
+
+	isChanged := func(columnName string) bool {
+		columnName = strings.ToLower(columnName)
+		_, changed := columns[columnName]
+		return changed && !hasColumn(table.Cols, columnName)
+	}
+
+	for _, col := range table.Cols {
+		op := getGeneratedOption(col)
+		if op == nil {
+			continue
+		}
+		for _, columnName := range getExprColumns(op.Expr) {
+			if isChanged(columnName) {
+				return newKeyError(ErrDependentByGeneratedColumn, schemaName, tableName, columnName, "",
+					fmt.Sprintf(DependentByGeneratedColumnErrorPattern, columnName, col.Name.Name.O, schemaName, tableName))
+			}
+		}
+	}
+	for _, constraint := range table.Constraints {
+		if constraint.Tp != ast.ConstraintCheck {
+			continue
+		}
+		for _, columnName := range getExprColumns(constraint.Expr) {
+			if isChanged(columnName) {
+				return newKeyError(ErrDependentByCheckConstraint, schemaName, tableName, columnName, constraint.Name,
+					fmt.Sprintf(DependentByCheckErrorPattern, columnName, constraint.Name, schemaName, tableName))
+			}
+		}
+	}
+	return nil
+}
+
+// validateGeneratedColumns checks the columns used by the generated columns
+// exist, and like MySQL a generated column only refers to the generated
+// columns before it, and not to the auto increment column.
+func validateGeneratedColumns(schemaName, tableName string, table *ast.CreateTableStmt) []error {
+	errs := []error{}
+	positions := map[string]int{}
+	for i, col := range table.Cols {
+		positions[col.Name.Name.L] = i
+	}
+
+	for i, col := range table.Cols {
+		op := getGeneratedOption(col)
+		if op == nil {
+			continue
+		}
+		for _, columnName := range getExprColumns(op.Expr) {
+			position, ok := positions[strings.ToLower(columnName)]
+			if !ok {
+				errs = append(errs, newColumnError(ErrUnknownColumn, schemaName, tableName, columnName,
+					fmt.Sprintf(NotExistGeneratedColumnRefErrorPattern, columnName, col.Name.Name.O, schemaName, tableName)))
+				continue
+			}
+			refCol := table.Cols[position]
+			if position >= i && getGeneratedOption(refCol) != nil {
+				errs = append(errs, newColumnError(ErrGeneratedColumnNonPrior, schemaName, tableName, col.Name.Name.O,
+					fmt.Sprintf(GeneratedColumnNonPriorErrorPattern, col.Name.Name.O, columnName, schemaName, tableName)))
+			}
+			if hasOneInOptions(refCol.Options, ast.ColumnOptionAutoIncrement) {
+				errs = append(errs, newColumnError(ErrGeneratedColumnRefAutoInc, schemaName, tableName, col.Name.Name.O,
+					fmt.Sprintf(GeneratedColumnAutoIncErrorPattern, col.Name.Name.O, columnName, schemaName, tableName)))
+			}
+		}
+	}
+	return errs
+}
+
+// validateChecks checks the names of the checks are unique and the columns used
+// by the checks exist.
+func validateChecks(schemaName, tableName string, table *ast.CreateTableStmt) []error {
+	errs := []error{}
+	names := map[string]struct{}{}
+	for _, constraint := range table.Constraints {
+		if constraint.Tp != ast.ConstraintCheck {
+			continue
+		}
+		if _, ok := names[strings.ToLower(constraint.Name)]; ok {
+			errs = append(errs, duplicateCheckError(schemaName, tableName, constraint.Name))
+		}
+		names[strings.ToLower(constraint.Name)] = struct{}{}
+		for _, columnName := range getExprColumns(constraint.Expr) {
+			if !hasColumn(table.Cols, strings.ToLower(columnName)) {
+				errs = append(errs, newKeyError(ErrUnknownColumn, schemaName, tableName, columnName, constraint.Name,
+					fmt.Sprintf(NotExistCheckColumnErrorPattern, columnName, constraint.Name, schemaName, tableName)))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,109 @@
+package virtualdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlterCheck(t *testing.T) {
+	cases := []alterCase{
+		{
+			name:   "name the checks like MySQL",
+			input:  "create table t1(a int check (a > 0), b int, constraint c1 check (b > 0), check (a < b));",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,CONSTRAINT `c1` CHECK(`b`>0) ENFORCED,CONSTRAINT `t1_chk_2` CHECK(`a`<`b`) ENFORCED,CONSTRAINT `t1_chk_1` CHECK(`a`>0) ENFORCED);\n",
+		},
+		{
+			name:   "add a check after the named checks",
+			input:  "create table t1(a int check (a > 0)); alter table t1 add check (a < 10);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,CONSTRAINT `t1_chk_1` CHECK(`a`>0) ENFORCED,CONSTRAINT `t1_chk_2` CHECK(`a`<10) ENFORCED);\n",
+		},
+		{
+			name:   "drop and alter checks",
+			input:  "create table t1(a int check (a > 0), check (a < 10)); alter table t1 drop check t1_chk_1, alter check t1_chk_2 not enforced;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,CONSTRAINT `t1_chk_2` CHECK(`a`<10) NOT ENFORCED);\n",
+		},
+		{
+			name:        "drop a check which doesn't exist",
+			input:       "create table t1(a int); alter table t1 drop check c1;",
+			expectError: "not exist check c1 in db1.t1",
+		},
+		{
+			name:        "add a duplicate check",
+			input:       "create table t1(a int, constraint c1 check (a > 0)); alter table t1 add constraint c1 check (a < 10);",
+			expectError: "duplicate check c1 in db1.t1",
+		},
+		{
+			name:   "rename the checks with the table",
+			input:  "create table t1(a int check (a > 0)); alter table t1 rename to t2;",
+			expect: "CREATE TABLE `db1`.`t2` (`a` INT,CONSTRAINT `t2_chk_1` CHECK(`a`>0) ENFORCED);\n",
+		},
+		{
+			name:        "drop a column used by a check",
+			input:       "create table t1(a int, b int, check (a > b)); alter table t1 drop column b;",
+			expectError: "can't drop or rename column b used by check t1_chk_1 in db1.t1",
+		},
+		{
+			name:        "rename a column used by a check",
+			input:       "create table t1(a int, b int, check (a > b)); alter table t1 change b c int;",
+			expectError: "can't drop or rename column b used by check t1_chk_1 in db1.t1",
+		},
+	}
+	testAlterCases(t, cases)
+}
+
+func TestAlterGeneratedColumn(t *testing.T) {
+	cases := []alterCase{
+		{
+			name:   "change the expression of a generated column",
+			input:  "create table t1(a int, b int as (a + 1)); alter table t1 modify b int as (a * 2);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT GENERATED ALWAYS AS(`a`*2) VIRTUAL);\n",
+		},
+		{
+			name:   "change a stored column to a normal column",
+			input:  "create table t1(a int, b int as (a + 1) stored); alter table t1 modify b int;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT);\n",
+		},
+		{
+			name:        "change a virtual column to a stored column",
+			input:       "create table t1(a int, b int as (a + 1)); alter table t1 modify b int as (a + 1) stored;",
+			expectError: "can't change the STORED status of generated column b in db1.t1",
+		},
+		{
+			name:        "drop a column used by a generated column",
+			input:       "create table t1(a int, b int as (a + 1)); alter table t1 drop column a;",
+			expectError: "can't drop or rename column a used by generated column b in db1.t1",
+		},
+		{
+			name:   "drop a column with the generated column",
+			input:  "create table t1(a int, b int as (a + 1), c int); alter table t1 drop column a, drop column b;",
+			expect: "CREATE TABLE `db1`.`t1` (`c` INT);\n",
+		},
+	}
+	testAlterCases(t, cases)
+}
+
+func TestCheckError(t *testing.T) {
+	testSchemaError(t, "create table t1(a int); alter table t1 drop check c1;", ErrCheckConstraintNotFound,
+		"ERROR 3821 (HY000): Check constraint 'c1' is not found in the table.")
+	testSchemaError(t, "create table t1(a int, b int, check (a > b)); alter table t1 drop column b;", ErrDependentByCheckConstraint,
+		"ERROR 3959 (HY000): Check constraint 't1_chk_1' uses column 'b', hence column cannot be dropped or renamed.")
+	testSchemaError(t, "create table t1(a int, b int as (a + 1)); alter table t1 drop column a;", ErrDependentByGeneratedColumn,
+		"ERROR 3108 (HY000): Column 'a' has a generated column dependency.")
+	testSchemaError(t, "create table t1(a int, b int as (a + 1)); alter table t1 modify b int;", ErrUnsupportedOnGeneratedColumn,
+		"ERROR 3106 (HY000): 'Changing the STORED status' is not supported for generated columns.")
+}
+
+func TestValidateGeneratedColumn(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int auto_increment primary key, b int as (c + 1), c int as (a + 1), d int as (x + 1));"+
+		"create table t2(a int, check (x > 0));"))
+
+	errs := vb.Validate()
+	assert.Equal(t, 4, len(errs))
+	assert.True(t, errors.Is(errs[0], &SchemaError{Kind: ErrGeneratedColumnNonPrior, Table: "t1", Column: "b"}))
+	assert.True(t, errors.Is(errs[1], &SchemaError{Kind: ErrGeneratedColumnRefAutoInc, Table: "t1", Column: "c"}))
+	assert.True(t, errors.Is(errs[2], &SchemaError{Kind: ErrUnknownColumn, Table: "t1", Column: "x"}))
+	assert.True(t, errors.Is(errs[3], &SchemaError{Kind: ErrUnknownColumn, Table: "t2", Column: "x"}))
+}
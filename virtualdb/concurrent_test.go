@@ -0,0 +1,85 @@
+package virtualdb
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConcurrentExecAndGet runs statements and getters in parallel, it is
+// meant to be run with the race detector: go test -race.
+func TestConcurrentExecAndGet(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int, index i1(a));"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				sql := fmt.Sprintf("create table t_%d_%d(a int); alter table t1 add column c_%d_%d int;", i, j, i, j)
+				assert.NoError(t, vb.ExecSQL(sql))
+			}
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_, exist, err := vb.GetColumn("db1", "t1", "a")
+				assert.NoError(t, err)
+				assert.True(t, exist)
+				_, exist, err = vb.GetConstraints("db1", "t1", "i1")
+				assert.NoError(t, err)
+				assert.True(t, exist)
+
+				tables, exist := vb.GetTableStmts("db1")
+				assert.True(t, exist)
+				for _, table := range tables {
+					_, err := restoreToSql(table.Table)
+					assert.NoError(t, err)
+				}
+				_, err = vb.Text()
+				assert.NoError(t, err)
+				vb.Validate()
+			}
+		}()
+	}
+	wg.Wait()
+
+	tables, _ := vb.GetTableStmts("db1")
+	assert.Equal(t, 81, len(tables))
+	assert.Equal(t, 81, len(tables["t1"].Table.Cols))
+}
+
+func TestConcurrentSnapshot(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int);"))
+	snapshot, err := vb.Snapshot()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, vb.ExecSQLAtomic(fmt.Sprintf("create table t_%d_%d(a int);", i, j)))
+				_, err := vb.Clone()
+				assert.NoError(t, err)
+				if j%5 == 0 {
+					assert.NoError(t, vb.Restore(snapshot))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_, exist, err := vb.GetColumn("db1", "t1", "a")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
@@ -0,0 +1,352 @@
+package virtualdb
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/tidb/types"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+)
+
+// resolveCreateTable returns the full definition of CREATE TABLE ... LIKE and
+// CREATE TABLE ... SELECT, the other tables are returned as they are.
+func (c *VirtualDB) resolveCreateTable(stmt *ast.CreateTableStmt) (*ast.CreateTableStmt, error) {
+	switch {
+	case stmt.ReferTable != nil:
+		return c.createTableLike(stmt)
+	case stmt.Select != nil:
+		return c.createTableSelect(stmt)
+	}
+	return stmt, nil
+}
+
+// createTableLike copies the definition of the referenced table like MySQL, the
+// foreign keys and the AUTO_INCREMENT option are not copied, and the checks
+// named by MySQL are named after the new table.
+func (c *VirtualDB) createTableLike(stmt *ast.CreateTableStmt) (*ast.CreateTableStmt, error) {
+	schemaName := c.getSchemaName(stmt.ReferTable)
+	tableName := stmt.ReferTable.Name.String()
+	info, exist, err := c.getTable(schemaName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		if c.hasView(schemaName, tableName) {
+			return nil, notBaseTableError(schemaName, tableName)
+		}
+		return nil, noSuchTableError(schemaName, tableName)
+	}
+
+	table, err := copyCreateTableStmt(info.Table)
+	if err != nil {
+		return nil, err
+	}
+	table.Table = stmt.Table
+	table.IfNotExists = stmt.IfNotExists
+	table.IsTemporary = stmt.IsTemporary
+
+	constraints := []*ast.Constraint{}
+	for _, constraint := range table.Constraints {
+		if constraint.Tp != ast.ConstraintForeignKey {
+			constraints = append(constraints, constraint)
+		}
+	}
+	table.Constraints = constraints
+
+	options := []*ast.TableOption{}
+	for _, op := range table.Options {
+		if op.Tp != ast.TableOptionAutoIncrement {
+			options = append(options, op)
+		}
+	}
+	table.Options = options
+
+	renameChecks(table, tableName)
+	return table, nil
+}
+
+// createTableSelect infers the columns of CREATE TABLE ... SELECT from the select
+// list. Like MySQL the columns defined in the statement come first, and a select
+// column with the same name uses the defined column. The types of the table
+// columns are kept, the types of the literals and casts are inferred, and the
+// other expressions are LONGTEXT.
+func (c *VirtualDB) createTableSelect(stmt *ast.CreateTableStmt) (*ast.CreateTableStmt, error) {
+	resolver := &columnResolver{c, c.getSchemaName(stmt.Table), stmt.Table.Name.String()}
+	selectCols, err := resolver.resultColumns(stmt.Select)
+	if err != nil {
+		return nil, err
+	}
+
+	table := *stmt
+	table.Select = nil
+	table.OnDuplicate = ast.OnDuplicateKeyHandlingError
+	table.Cols = append([]*ast.ColumnDef{}, stmt.Cols...)
+	for _, col := range selectCols {
+		if !hasColumn(table.Cols, col.Name.Name.L) {
+			table.Cols = append(table.Cols, col)
+		}
+	}
+	return &table, nil
+}
+
+// columnResolver resolves the columns of the select of a table, the errors
+// refer to the table.
+type columnResolver struct {
+	c          *VirtualDB
+	schemaName string
+	tableName  string
+}
+
+// selectSource is a table source of a select with its columns.
+type selectSource struct {
+	name string
+	cols []*ast.ColumnDef
+}
+
+// resultColumns returns the columns of the result of the select, the columns of
+// a union are those of its first select.
+func (r *columnResolver) resultColumns(node ast.ResultSetNode) ([]*ast.ColumnDef, error) {
+	var selectStmt *ast.SelectStmt
+	switch stmt := node.(type) {
+	case *ast.SelectStmt:
+		selectStmt = stmt
+	case *ast.UnionStmt:
+		if stmt.SelectList != nil && len(stmt.SelectList.Selects) != 0 {
+			selectStmt = stmt.SelectList.Selects[0]
+		}
+	}
+	if selectStmt == nil || selectStmt.Fields == nil {
+		return nil, fmt.Errorf("stmt not support")
+	}
+
+	sources := []*selectSource{}
+	if selectStmt.From != nil {
+		var err error
+		if sources, err = r.joinSources(selectStmt.From.TableRefs); err != nil {
+			return nil, err
+		}
+	}
+
+	cols := []*ast.ColumnDef{}
+	for _, field := range selectStmt.Fields.Fields {
+		if field.WildCard != nil {
+			wildCols, err := wildCardColumns(field.WildCard, sources)
+			if err != nil {
+				return nil, err
+			}
+			cols = append(cols, wildCols...)
+			continue
+		}
+
+		col, err := r.exprColumn(field.Expr, sources)
+		if err != nil {
+			return nil, err
+		}
+		name := field.AsName.O
+		if name == "" {
+			if colExpr, ok := field.Expr.(*ast.ColumnNameExpr); ok {
+				name = colExpr.Name.Name.O
+			} else {
+				// like MySQL the column is named by the text of the expression.
+				name = strings.TrimSpace(field.Text())
+				if name == "" {
+					name, _ = restoreToSql(field.Expr)
+				}
+			}
+		}
+		col.Name = &ast.ColumnName{Name: model.NewCIStr(name)}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// joinSources returns the table sources of the FROM clause in order.
+func (r *columnResolver) joinSources(node ast.ResultSetNode) ([]*selectSource, error) {
+	switch n := node.(type) {
+	case *ast.Join:
+		sources, err := r.joinSources(n.Left)
+		if err != nil || n.Right == nil {
+			return sources, err
+		}
+		rightSources, err := r.joinSources(n.Right)
+		return append(sources, rightSources...), err
+	case *ast.TableSource:
+		source, err := r.tableSource(n)
+		if err != nil {
+			return nil, err
+		}
+		return []*selectSource{source}, nil
+	}
+	return nil, nil
+}
+
+// tableSource returns the columns of a table, a view or a derived table.
+func (r *columnResolver) tableSource(source *ast.TableSource) (*selectSource, error) {
+	switch n := source.Source.(type) {
+	case *ast.TableName:
+		name := n.Name.L
+		if source.AsName.L != "" {
+			name = source.AsName.L
+		}
+		cols, err := r.tableColumns(n)
+		return &selectSource{name: name, cols: cols}, err
+	case *ast.SelectStmt, *ast.UnionStmt:
+		cols, err := r.resultColumns(n)
+		return &selectSource{name: source.AsName.L, cols: cols}, err
+	case *ast.Join:
+		sources, err := r.joinSources(n)
+		if err != nil {
+			return nil, err
+		}
+		merged := &selectSource{name: source.AsName.L}
+		for _, s := range sources {
+			merged.cols = append(merged.cols, s.cols...)
+		}
+		return merged, nil
+	}
+	return nil, fmt.Errorf("stmt not support")
+}
+
+// tableColumns returns the columns of a table or a view. Like MySQL the columns
+// keep their types, NOT NULL and DEFAULT, but not the keys and AUTO_INCREMENT.
+func (r *columnResolver) tableColumns(tableName *ast.TableName) ([]*ast.ColumnDef, error) {
+	schemaName := r.c.getSchemaName(tableName)
+	info, exist, err := r.c.getTable(schemaName, tableName.Name.String())
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		view, exist := r.c.getView(schemaName, tableName.Name.String())
+		if !exist {
+			return nil, noSuchTableError(schemaName, tableName.Name.String())
+		}
+		cols, err := r.resultColumns(view.View.Select)
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range view.View.Cols {
+			if i < len(cols) {
+				cols[i].Name = &ast.ColumnName{Name: name}
+			}
+		}
+		return cols, nil
+	}
+
+	pkColumns, _ := getPrimaryKey(info.Table)
+	cols := []*ast.ColumnDef{}
+	for _, col := range info.Table.Cols {
+		tp := *col.Tp
+		newCol := &ast.ColumnDef{Name: &ast.ColumnName{Name: col.Name.Name}, Tp: &tp}
+		if _, ok := pkColumns[col.Name.Name.L]; ok && !hasOneInOptions(col.Options, ast.ColumnOptionNotNull) {
+			newCol.Options = append(newCol.Options, &ast.ColumnOption{Tp: ast.ColumnOptionNotNull})
+		}
+		for _, op := range col.Options {
+			switch op.Tp {
+			case ast.ColumnOptionNotNull, ast.ColumnOptionNull, ast.ColumnOptionDefaultValue:
+				newCol.Options = append(newCol.Options, op)
+			}
+		}
+		cols = append(cols, newCol)
+	}
+	return cols, nil
+}
+
+// wildCardColumns returns the columns of * or t.*.
+func wildCardColumns(wildCard *ast.WildCardField, sources []*selectSource) ([]*ast.ColumnDef, error) {
+	cols := []*ast.ColumnDef{}
+	for _, source := range sources {
+		if wildCard.Table.L == "" || wildCard.Table.L == source.name {
+			for _, col := range source.cols {
+				newCol := *col
+				cols = append(cols, &newCol)
+			}
+		}
+	}
+	if wildCard.Table.L != "" && len(cols) == 0 {
+		return nil, noSuchTableError(wildCard.Schema.O, wildCard.Table.O)
+	}
+	return cols, nil
+}
+
+// exprColumn returns the column of a select field without its name.
+func (r *columnResolver) exprColumn(expr ast.ExprNode, sources []*selectSource) (*ast.ColumnDef, error) {
+	switch e := expr.(type) {
+	case *ast.ColumnNameExpr:
+		for _, source := range sources {
+			if e.Name.Table.L != "" && e.Name.Table.L != source.name {
+				continue
+			}
+			for _, col := range source.cols {
+				if col.Name.Name.L == e.Name.Name.L {
+					newCol := *col
+					return &newCol, nil
+				}
+			}
+		}
+		return nil, newColumnError(ErrUnknownColumn, r.schemaName, r.tableName, e.Name.Name.O,
+			fmt.Sprintf(NotExistSelectColumnErrorPattern, e.Name.Name.O, r.schemaName, r.tableName))
+	case *ast.ParenthesesExpr:
+		return r.exprColumn(e.Expr, sources)
+	case *driver.ValueExpr:
+		return valueColumn(e), nil
+	case *ast.FuncCastExpr:
+		tp := *e.Tp
+		switch tp.Tp {
+		case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeDuration:
+			tp.Flen = types.UnspecifiedLength
+		}
+		return &ast.ColumnDef{Tp: &tp}, nil
+	case *ast.AggregateFuncExpr:
+		return r.aggregateColumn(e, sources)
+	case *ast.BinaryOperationExpr:
+		return r.binaryOperationColumn(e, sources)
+	case *ast.UnaryOperationExpr:
+		return r.unaryOperationColumn(e, sources)
+	case *ast.FuncCallExpr:
+		return r.funcCallColumn(e, sources)
+	case *ast.IsNullExpr, *ast.IsTruthExpr:
+		return intResultColumn(1, false, true), nil
+	case *ast.PatternInExpr, *ast.PatternLikeExpr, *ast.PatternRegexpExpr, *ast.BetweenExpr:
+		return intResultColumn(1, false, false), nil
+	}
+	return longTextColumn(), nil
+}
+
+// valueColumn returns the column of a literal like MySQL, the literals other
+// than NULL are NOT NULL.
+func valueColumn(value *driver.ValueExpr) *ast.ColumnDef {
+	var tp *types.FieldType
+	switch value.Kind() {
+	case types.KindNull:
+		tp = newFieldType(mysql.TypeString, 0, types.UnspecifiedLength)
+		tp.Charset, tp.Collate = "binary", "binary"
+		tp.Flag |= mysql.BinaryFlag
+		return &ast.ColumnDef{Tp: tp}
+	case types.KindInt64, types.KindUint64:
+		tp = newFieldType(mysql.TypeLong, types.UnspecifiedLength, types.UnspecifiedLength)
+		if value.Kind() == types.KindUint64 || value.GetInt64() > 1<<31-1 || value.GetInt64() < -1<<31 {
+			tp.Tp = mysql.TypeLonglong
+		}
+	case types.KindMysqlDecimal:
+		precision, frac := value.GetMysqlDecimal().PrecisionAndFrac()
+		tp = newFieldType(mysql.TypeNewDecimal, precision, frac)
+	case types.KindFloat32, types.KindFloat64:
+		tp = newFieldType(mysql.TypeDouble, types.UnspecifiedLength, types.UnspecifiedLength)
+	case types.KindString, types.KindBytes:
+		tp = newFieldType(mysql.TypeVarchar, utf8.RuneCountInString(value.GetString()), types.UnspecifiedLength)
+	default:
+		tp = newFieldType(mysql.TypeLongBlob, types.UnspecifiedLength, types.UnspecifiedLength)
+	}
+	return &ast.ColumnDef{Tp: tp, Options: []*ast.ColumnOption{{Tp: ast.ColumnOptionNotNull}}}
+}
+
+func newFieldType(tp byte, flen, decimal int) *types.FieldType {
+	ft := types.NewFieldType(tp)
+	ft.Flen, ft.Decimal = flen, decimal
+	ft.Charset, ft.Collate = "", ""
+	return ft
+}
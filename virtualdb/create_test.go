@@ -0,0 +1,93 @@
+package virtualdb
+
+import "testing"
+
+func TestCreateTableLike(t *testing.T) {
+	cases := []alterCase{
+		{
+			name: "copy the definition of the table",
+			input: "create table t1(id int auto_increment primary key, a int check (a > 0), b int, index(a), " +
+				"foreign key (b) references t1 (id)) engine=InnoDB auto_increment=10 comment='t1';" +
+				"create table t2 like t1; drop table t1;",
+			expect: "CREATE TABLE `db1`.`t2` (`id` INT AUTO_INCREMENT PRIMARY KEY,`a` INT,`b` INT,INDEX `a`(`a`)," +
+				"CONSTRAINT `t2_chk_1` CHECK(`a`>0) ENFORCED) ENGINE = InnoDB COMMENT = 't1';\n",
+		},
+		{
+			name:        "copy a table which doesn't exist",
+			input:       "create table t2 like t1;",
+			expectError: "not exist table: db1.t1",
+		},
+		{
+			name:        "copy a view",
+			input:       "create table t1(a int); create view v1 as select a from t1; create table t2 like v1;",
+			expectError: "db1.v1 is not BASE TABLE",
+		},
+		{
+			name:   "copy a table if not exists",
+			input:  "create table t1(a int); create table t2(b int); create table if not exists t2 like t1; drop table t1;",
+			expect: "CREATE TABLE `db1`.`t2` (`b` INT);\n",
+		},
+	}
+	testAlterCases(t, cases)
+}
+
+func TestCreateTableSelect(t *testing.T) {
+	cases := []alterCase{
+		{
+			name: "infer the columns of the select",
+			input: "create table t1(id int auto_increment primary key, a varchar(10) not null default 'x', b decimal(10,2));" +
+				"create table t2 (c int, primary key (id)) as select t.id, a as name, b + 1, 1 as one, 'abc' s, 1.5 d, null n, " +
+				"cast(a as date) dt, count(*) cnt from t1 t; drop table t1;",
+			expect: "CREATE TABLE `db1`.`t2` (`c` INT,`id` INT NOT NULL,`name` VARCHAR(10) NOT NULL DEFAULT 'x',`b + 1` DECIMAL(11,2)," +
+				"`one` INT NOT NULL,`s` VARCHAR(3) NOT NULL,`d` DECIMAL(2,1) NOT NULL,`n` BINARY(0),`dt` DATE," +
+				"`cnt` BIGINT NOT NULL,PRIMARY KEY(`id`));\n",
+		},
+		{
+			name: "infer the types of the operators",
+			input: "create table t1(a int not null, b int unsigned, c decimal(10,2), f double, g tinyint);" +
+				"create table t2 select a + 1 x1, a + b x2, a * c x3, a / a x4, c / 2 x5, a + f x6, a div 2 x7, -a x8, " +
+				"g + g x9, a = 1 x10, a is null x11 from t1; drop table t1;",
+			expect: "CREATE TABLE `db1`.`t2` (`x1` BIGINT NOT NULL,`x2` BIGINT UNSIGNED,`x3` DECIMAL(20,2),`x4` DECIMAL(14,4)," +
+				"`x5` DECIMAL(14,6),`x6` DOUBLE,`x7` BIGINT,`x8` BIGINT NOT NULL,`x9` INT,`x10` INT NOT NULL,`x11` INT NOT NULL);\n",
+		},
+		{
+			name: "infer the types of the functions",
+			input: "create table t1(d varchar(10) not null, e text);" +
+				"create table t2 select concat(d, 'ab') s1, upper(d) s2, left(d, 3) s3, lpad(d, 600, 'x') s4, concat(e, d) s5, " +
+				"replace(d, 'a', 'bc') s6, length(d) l1, sum(length(d)) g1, avg(length(d)) g2, max(d) g3, group_concat(d) g4, " +
+				"now() n1, ifnull(e, d) o1 from t1; drop table t1;",
+			expect: "CREATE TABLE `db1`.`t2` (`s1` VARCHAR(12) NOT NULL,`s2` VARCHAR(10) NOT NULL,`s3` VARCHAR(3) NOT NULL," +
+				"`s4` TEXT NOT NULL,`s5` MEDIUMTEXT,`s6` VARCHAR(20) NOT NULL,`l1` BIGINT NOT NULL,`g1` DECIMAL(41,0)," +
+				"`g2` DECIMAL(23,4),`g3` VARCHAR(10),`g4` TEXT,`n1` DATETIME NOT NULL,`o1` LONGTEXT);\n",
+		},
+		{
+			name: "select all the columns of a join and a view",
+			input: "create table t1(a int, b int); create table t2(a int, c char(2)); create view v1 (x, y) as select a, c from t2;" +
+				"create table t3 select t1.*, v.y from t1 join v1 v on t1.a = v.x; drop view v1; drop table t1, t2;",
+			expect: "CREATE TABLE `db1`.`t3` (`a` INT,`b` INT,`y` CHAR(2));\n",
+		},
+		{
+			name:   "use the defined column",
+			input:  "create table t1(a int, b int); create table t2 (b bigint not null) select a, b from t1; drop table t1;",
+			expect: "CREATE TABLE `db1`.`t2` (`b` BIGINT NOT NULL,`a` INT);\n",
+		},
+		{
+			name:        "select a column which doesn't exist",
+			input:       "create table t1(a int); create table t2 select x from t1;",
+			expectError: "not exist column x used in select of db1.t2",
+		},
+		{
+			name:        "select from a table which doesn't exist",
+			input:       "create table t2 select a from t1;",
+			expectError: "not exist table: db1.t1",
+		},
+	}
+	testAlterCases(t, cases)
+}
+
+func TestCreateTableLikeError(t *testing.T) {
+	testSchemaError(t, "create table t1(a int); create view v1 as select a from t1; create table t2 like v1;", ErrWrongObject,
+		"ERROR 1347 (HY000): 'db1.v1' is not BASE TABLE")
+	testSchemaError(t, "create table t1(a int); create table t2 like t1; create or replace view t2 as select 1;", ErrWrongObject,
+		"ERROR 1347 (HY000): 'db1.t2' is not VIEW")
+}
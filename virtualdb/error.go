@@ -1,19 +1,464 @@
 package virtualdb
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/pingcap/parser/mysql"
+)
+
+const (
+	NotExistSchemaErrorPattern   = "not exist schema: %s"
+	NoSchemaSelectedErrorPattern = "no schema selected"
+	DuplicateSchemaErrorPattern  = "duplicate schema: %s"
+	NotExistTableErrorPattern    = "not exist table: %s.%s"
+	DuplicateTableErrorPattern   = "duplicate table: %s.%s"
+	NotExistColumnErrorPattern   = "not exist column %s in %s.%s"
+	DuplicateColumnErrorPattern  = "duplicate column %s in %s.%s"
+	NotExistIndexErrorPattern    = "not exist index %s in %s.%s"
+	DuplicateIndexErrorPattern   = "duplicate index %s in %s.%s"
+
+	DropColumnInForeignKeyErrorPattern = "can't drop column %s needed in foreign key %s in %s.%s"
+
+	NotExistKeyColumnErrorPattern     = "not exist column %s used in index %s in %s.%s"
+	BlobKeyWithoutLengthErrorPattern  = "blob/text column %s used in index %s without a key length in %s.%s"
+	TooLongKeyErrorPattern            = "index %s is too long in %s.%s, max key length is %d bytes"
+	MultipleAutoIncrementErrorPattern = "more than one auto increment column in %s.%s"
+	AutoIncrementNotKeyErrorPattern   = "auto increment column %s is not defined as a key in %s.%s"
+	NullablePrimaryKeyErrorPattern    = "primary key column %s can't be null in %s.%s"
+	InvalidDefaultErrorPattern        = "invalid default value for column %s in %s.%s"
+
+	NotExistObjectErrorPattern       = "not exist %s %s in %s"
+	DuplicateObjectErrorPattern      = "duplicate %s %s in %s"
+	WrongObjectErrorPattern          = "%s.%s is not %s"
+	NotExistViewColumnErrorPattern   = "not exist column %s used in view %s.%s"
+	NotExistSelectColumnErrorPattern = "not exist column %s used in select of %s.%s"
+	ViewColumnCountErrorPattern      = "view %s.%s has %d columns but its select has %d"
+	TriggerOnViewErrorPattern        = "trigger %s can't be created on view %s.%s"
+	TriggerWrongSchemaErrorPattern   = "can't move table %s.%s with triggers to schema %s"
+	TriggerTableSchemaErrorPattern   = "trigger %s.%s can't be created on table %s.%s"
+
+	NotPartitionedErrorPattern          = "%s.%s is not partitioned"
+	NotExistPartitionErrorPattern       = "not exist partition %s in %s.%s"
+	DuplicatePartitionErrorPattern      = "duplicate partition %s in %s.%s"
+	DropLastPartitionErrorPattern       = "can't remove all partitions of %s.%s"
+	PartitionTypeErrorPattern           = "%s PARTITION can't be used on %s partitions of %s.%s"
+	PartitionsNotDefinedErrorPattern    = "%s partitions of %s.%s must be defined"
+	RangeNotIncreasingErrorPattern      = "range of partition %s in %s.%s is not greater than the previous partition"
+	ListDuplicateValueErrorPattern      = "value %s is in more than one partition of %s.%s"
+	ReorgOutsideRangeErrorPattern       = "reorganized partitions of %s.%s change the range"
+	NotExistPartitionColumnErrorPattern = "not exist column %s used in partition function of %s.%s"
+	UniqueKeyPartitionErrorPattern      = "index %s in %s.%s doesn't include all columns of the partition function"
+
+	NotExistCheckErrorPattern              = "not exist check %s in %s.%s"
+	DuplicateCheckErrorPattern             = "duplicate check %s in %s.%s"
+	NotExistCheckColumnErrorPattern        = "not exist column %s used by check %s in %s.%s"
+	DependentByCheckErrorPattern           = "can't drop or rename column %s used by check %s in %s.%s"
+	NotExistGeneratedColumnRefErrorPattern = "not exist column %s used by generated column %s in %s.%s"
+	DependentByGeneratedColumnErrorPattern = "can't drop or rename column %s used by generated column %s in %s.%s"
+	GeneratedColumnStoredErrorPattern      = "can't change the STORED status of generated column %s in %s.%s"
+	GeneratedColumnNonPriorErrorPattern    = "generated column %s refers to column %s which is not a generated column before it in %s.%s"
+	GeneratedColumnAutoIncErrorPattern     = "generated column %s refers to auto increment column %s in %s.%s"
+)
+
+// ErrorKind is the kind of a SchemaError, its value is the MySQL error number.
+type ErrorKind uint16
+
+// ErrorKind values.
 const (
-	NotExistSchemaErrorPattern  = "not exist schema: %s"
-	DuplicateSchemaErrorPattern = "duplicate schema: %s"
-	NotExistTableErrorPattern   = "not exist table: %s.%s"
-	DuplicateTableErrorPattern  = "duplicate table: %s.%s"
-	NotExistColumnErrorPattern  = "not exist column %s in %s.%s"
-	DuplicateColumnErrorPattern = "duplicate column %s in %s.%s"
-	NotExistIndexErrorPattern   = "not exist index %s in %s.%s"
-	DuplicateIndexErrorPattern  = "duplicate index %s in %s.%s"
+	ErrDatabaseExists        ErrorKind = mysql.ErrDBCreateExists
+	ErrCantDropDatabase      ErrorKind = mysql.ErrDBDropExists
+	ErrUnknownDatabase       ErrorKind = mysql.ErrBadDB
+	ErrNoDatabaseSelected    ErrorKind = mysql.ErrNoDB
+	ErrTableExists           ErrorKind = mysql.ErrTableExists
+	ErrUnknownTable          ErrorKind = mysql.ErrBadTable
+	ErrUnknownColumn         ErrorKind = mysql.ErrBadField
+	ErrDuplicateColumn       ErrorKind = mysql.ErrDupFieldName
+	ErrDuplicateKeyName      ErrorKind = mysql.ErrDupKeyName
+	ErrInvalidDefault        ErrorKind = mysql.ErrInvalidDefault
+	ErrMultiplePrimaryKey    ErrorKind = mysql.ErrMultiplePriKey
+	ErrTooLongKey            ErrorKind = mysql.ErrTooLongKey
+	ErrKeyColumnDoesNotExist ErrorKind = mysql.ErrKeyColumnDoesNotExits
+	ErrWrongAutoKey          ErrorKind = mysql.ErrWrongAutoKey
+	ErrCantDrop              ErrorKind = mysql.ErrCantDropFieldOrKey
+	ErrNoSuchTable           ErrorKind = mysql.ErrNoSuchTable
+	ErrBlobKeyWithoutLength  ErrorKind = mysql.ErrBlobKeyWithoutLength
+	ErrPrimaryCantHaveNull   ErrorKind = mysql.ErrPrimaryCantHaveNull
+	ErrKeyDoesNotExist       ErrorKind = mysql.ErrKeyDoesNotExist
+	ErrFKColumnCannotDrop    ErrorKind = mysql.ErrFkColumnCannotDrop
+	ErrWrongObject           ErrorKind = mysql.ErrWrongObject
+	ErrViewWrongList         ErrorKind = mysql.ErrViewWrongList
+	ErrTriggerExists         ErrorKind = mysql.ErrTrgAlreadyExists
+	ErrTriggerDoesNotExist   ErrorKind = mysql.ErrTrgDoesNotExist
+	ErrTriggerOnView         ErrorKind = mysql.ErrTrgOnViewOrTempTable
+	ErrTriggerInWrongSchema  ErrorKind = mysql.ErrTrgInWrongSchema
+	ErrRoutineExists         ErrorKind = mysql.ErrSpAlreadyExists
+	ErrRoutineDoesNotExist   ErrorKind = mysql.ErrSpDoesNotExist
+	ErrEventExists           ErrorKind = mysql.ErrEventAlreadyExists
+	ErrEventDoesNotExist     ErrorKind = mysql.ErrEventDoesNotExist
+
+	ErrNotPartitioned                   ErrorKind = mysql.ErrPartitionMgmtOnNonpartitioned
+	ErrUnknownPartition                 ErrorKind = mysql.ErrUnknownPartition
+	ErrDuplicatePartition               ErrorKind = mysql.ErrSameNamePartition
+	ErrWrongPartitionList               ErrorKind = mysql.ErrDropPartitionNonExistent
+	ErrDropLastPartition                ErrorKind = mysql.ErrDropLastPartition
+	ErrOnlyOnRangeList                  ErrorKind = mysql.ErrOnlyOnRangeListPartition
+	ErrCoalesceOnlyOnHash               ErrorKind = mysql.ErrCoalesceOnlyOnHashPartition
+	ErrPartitionsMustBeDefined          ErrorKind = mysql.ErrPartitionsMustBeDefined
+	ErrRangeNotIncreasing               ErrorKind = mysql.ErrRangeNotIncreasing
+	ErrDuplicateListValue               ErrorKind = mysql.ErrMultipleDefConstInListPart
+	ErrReorgOutsideRange                ErrorKind = mysql.ErrReorgOutsideRange
+	ErrPartitionColumnNotFound          ErrorKind = mysql.ErrFieldNotFoundPart
+	ErrUniqueKeyNeedAllPartitionColumns ErrorKind = mysql.ErrUniqueKeyNeedAllFieldsInPf
+
+	ErrUnsupportedOnGeneratedColumn ErrorKind = mysql.ErrUnsupportedOnGeneratedColumn
+	ErrGeneratedColumnNonPrior      ErrorKind = mysql.ErrGeneratedColumnNonPrior
+	ErrDependentByGeneratedColumn   ErrorKind = mysql.ErrDependentByGeneratedColumn
+	ErrGeneratedColumnRefAutoInc    ErrorKind = mysql.ErrGeneratedColumnRefAutoInc
+	// the errors of the checks are added in MySQL 8.0.16, the parser doesn't have them.
+	ErrCheckConstraintNotFound    ErrorKind = 3821
+	ErrCheckConstraintDupName     ErrorKind = 3822
+	ErrDependentByCheckConstraint ErrorKind = 3959
 )
 
+// errNames are the messages of the ErrorKinds the parser doesn't have.
+var errNames = map[ErrorKind]string{
+	ErrCheckConstraintNotFound:    "Check constraint '%-.192s' is not found in the table.",
+	ErrCheckConstraintDupName:     "Duplicate check constraint name '%-.192s'.",
+	ErrDependentByCheckConstraint: "Check constraint '%-.64s' uses column '%-.64s', hence column cannot be dropped or renamed.",
+}
+
+// SchemaError is the error returned by VirtualDB when a statement can't be applied
+// to the schema, callers can use errors.As to get the kind and the object it refers to.
+type SchemaError struct {
+	Kind   ErrorKind
+	Schema string
+	Table  string
+	Column string
+	Index  string
+	// Object is the name of the trigger, stored procedure, function or event.
+	Object     string
+	ObjectType ObjectType
+	Partition  string
+
+	msg string
+	// arg is the argument of the MySQL message of some partition and generated
+	// column errors, such as the operation or the partition type.
+	arg string
+	// sentinel is the exported error wrapped by the error, such as NoPrimaryKeyError.
+	sentinel error
+}
+
+func (e *SchemaError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the exported error the error wraps, so errors.Is matches
+// NoPrimaryKeyError and ExistPrimaryKeyError by identity.
+func (e *SchemaError) Unwrap() error {
+	return e.sentinel
+}
+
+// Is reports whether target is a SchemaError of the same kind whose non-empty
+// fields are equal to the fields of e.
+func (e *SchemaError) Is(target error) bool {
+	t, ok := target.(*SchemaError)
+	if !ok {
+		return false
+	}
+	// an error wrapping a sentinel only matches the errors wrapping the same one.
+	if t.sentinel != nil && t.sentinel != e.sentinel {
+		return false
+	}
+	return e.Kind == t.Kind &&
+		(t.Schema == "" || t.Schema == e.Schema) &&
+		(t.Table == "" || t.Table == e.Table) &&
+		(t.Column == "" || t.Column == e.Column) &&
+		(t.Index == "" || t.Index == e.Index) &&
+		(t.Object == "" || t.Object == e.Object) &&
+		(t.Partition == "" || t.Partition == e.Partition)
+}
+
+// Code returns the MySQL error number.
+func (e *SchemaError) Code() int {
+	return int(e.Kind)
+}
+
+// SQLState returns the MySQL SQLSTATE value.
+func (e *SchemaError) SQLState() string {
+	if state, ok := mysql.MySQLState[uint16(e.Kind)]; ok {
+		return state
+	}
+	return mysql.DefaultMySQLState
+}
+
+// MySQLMessage returns the message MySQL reports for the error, such as
+// "ERROR 1054 (42S22): Unknown column 'a' in 't1'".
+func (e *SchemaError) MySQLMessage() string {
+	var args []interface{}
+	switch e.Kind {
+	case ErrDatabaseExists, ErrCantDropDatabase, ErrUnknownDatabase:
+		args = []interface{}{e.Schema}
+	case ErrTableExists:
+		args = []interface{}{e.Table}
+	case ErrUnknownTable:
+		if e.Schema == "" {
+			args = []interface{}{e.Table}
+		} else {
+			args = []interface{}{e.Schema + "." + e.Table}
+		}
+	case ErrNoSuchTable:
+		args = []interface{}{e.Schema, e.Table}
+	case ErrUnknownColumn:
+		args = []interface{}{e.Column, e.Table}
+	case ErrDuplicateColumn, ErrInvalidDefault, ErrKeyColumnDoesNotExist, ErrBlobKeyWithoutLength:
+		args = []interface{}{e.Column}
+	case ErrDuplicateKeyName:
+		args = []interface{}{e.Index}
+	case ErrTooLongKey:
+		args = []interface{}{MaxKeyLength}
+	case ErrCantDrop:
+		if e.Column != "" {
+			args = []interface{}{e.Column}
+		} else {
+			args = []interface{}{e.Index}
+		}
+	case ErrKeyDoesNotExist:
+		args = []interface{}{e.Index, e.Table}
+	case ErrFKColumnCannotDrop:
+		args = []interface{}{e.Column, e.Index}
+	case ErrWrongObject:
+		args = []interface{}{e.Schema, e.Table, e.arg}
+	case ErrTriggerOnView:
+		args = []interface{}{e.Table}
+	case ErrRoutineExists, ErrRoutineDoesNotExist:
+		args = []interface{}{e.ObjectType.String(), e.Object}
+	case ErrEventExists, ErrEventDoesNotExist:
+		args = []interface{}{e.Object}
+	case ErrDuplicatePartition:
+		args = []interface{}{e.Partition}
+	case ErrUnknownPartition:
+		args = []interface{}{e.Partition, e.Table}
+	case ErrWrongPartitionList, ErrOnlyOnRangeList, ErrPartitionsMustBeDefined, ErrUniqueKeyNeedAllPartitionColumns,
+		ErrUnsupportedOnGeneratedColumn:
+		args = []interface{}{e.arg}
+	case ErrDependentByGeneratedColumn, ErrGeneratedColumnRefAutoInc:
+		args = []interface{}{e.Column}
+	case ErrCheckConstraintNotFound, ErrCheckConstraintDupName:
+		args = []interface{}{e.Index}
+	case ErrDependentByCheckConstraint:
+		args = []interface{}{e.Index, e.Column}
+	}
+	name, ok := mysql.MySQLErrName[uint16(e.Kind)]
+	if !ok {
+		name = errNames[e.Kind]
+	}
+	return fmt.Sprintf("ERROR %d (%s): %s", e.Code(), e.SQLState(), fmt.Sprintf(name, args...))
+}
+
 var (
 	NoPrimaryKeyError    = errors.New("no primary key")
 	ExistPrimaryKeyError = errors.New("primary key exists")
 )
+
+func newSchemaError(kind ErrorKind, schemaName, tableName string, msg string) *SchemaError {
+	return &SchemaError{Kind: kind, Schema: schemaName, Table: tableName, msg: msg}
+}
+
+func newColumnError(kind ErrorKind, schemaName, tableName, columnName string, msg string) *SchemaError {
+	err := newSchemaError(kind, schemaName, tableName, msg)
+	err.Column = columnName
+	return err
+}
+
+func newIndexError(kind ErrorKind, schemaName, tableName, indexName string, msg string) *SchemaError {
+	err := newSchemaError(kind, schemaName, tableName, msg)
+	err.Index = indexName
+	return err
+}
+
+func unknownDatabaseError(schemaName string) error {
+	return newSchemaError(ErrUnknownDatabase, schemaName, "",
+		fmt.Sprintf(NotExistSchemaErrorPattern, schemaName))
+}
+
+func noDatabaseSelectedError() error {
+	return newSchemaError(ErrNoDatabaseSelected, "", "", NoSchemaSelectedErrorPattern)
+}
+
+func noSuchTableError(schemaName, tableName string) error {
+	return newSchemaError(ErrNoSuchTable, schemaName, tableName,
+		fmt.Sprintf(NotExistTableErrorPattern, schemaName, tableName))
+}
+
+func unknownColumnError(schemaName, tableName, columnName string) error {
+	return newColumnError(ErrUnknownColumn, schemaName, tableName, columnName,
+		fmt.Sprintf(NotExistColumnErrorPattern, columnName, schemaName, tableName))
+}
+
+func duplicateColumnError(schemaName, tableName, columnName string) error {
+	return newColumnError(ErrDuplicateColumn, schemaName, tableName, columnName,
+		fmt.Sprintf(DuplicateColumnErrorPattern, columnName, schemaName, tableName))
+}
+
+func duplicateIndexError(schemaName, tableName, indexName string) error {
+	return newIndexError(ErrDuplicateKeyName, schemaName, tableName, indexName,
+		fmt.Sprintf(DuplicateIndexErrorPattern, indexName, schemaName, tableName))
+}
+
+func noPrimaryKeyError(schemaName, tableName string) error {
+	err := newIndexError(ErrCantDrop, schemaName, tableName, "PRIMARY", NoPrimaryKeyError.Error())
+	err.sentinel = NoPrimaryKeyError
+	return err
+}
+
+func existPrimaryKeyError(schemaName, tableName string) error {
+	err := newSchemaError(ErrMultiplePrimaryKey, schemaName, tableName, ExistPrimaryKeyError.Error())
+	err.sentinel = ExistPrimaryKeyError
+	return err
+}
+
+func cantDropColumnError(schemaName, tableName, columnName string) error {
+	return newColumnError(ErrCantDrop, schemaName, tableName, columnName,
+		fmt.Sprintf(NotExistColumnErrorPattern, columnName, schemaName, tableName))
+}
+
+func cantDropIndexError(schemaName, tableName, indexName string) error {
+	return newIndexError(ErrCantDrop, schemaName, tableName, indexName,
+		fmt.Sprintf(NotExistIndexErrorPattern, indexName, schemaName, tableName))
+}
+
+func unknownIndexError(schemaName, tableName, indexName string) error {
+	return newIndexError(ErrKeyDoesNotExist, schemaName, tableName, indexName,
+		fmt.Sprintf(NotExistIndexErrorPattern, indexName, schemaName, tableName))
+}
+
+func newKeyError(kind ErrorKind, schemaName, tableName, columnName, indexName string, msg string) *SchemaError {
+	err := newColumnError(kind, schemaName, tableName, columnName, msg)
+	err.Index = indexName
+	return err
+}
+
+func newObjectError(kind ErrorKind, schemaName string, objectType ObjectType, objectName string, msg string) *SchemaError {
+	err := newSchemaError(kind, schemaName, "", msg)
+	err.Object = objectName
+	err.ObjectType = objectType
+	return err
+}
+
+func duplicateObjectError(schemaName string, objectType ObjectType, objectName string) error {
+	kind := map[ObjectType]ErrorKind{
+		ObjectTrigger: ErrTriggerExists, ObjectProcedure: ErrRoutineExists,
+		ObjectFunction: ErrRoutineExists, ObjectEvent: ErrEventExists,
+	}[objectType]
+	return newObjectError(kind, schemaName, objectType, objectName,
+		fmt.Sprintf(DuplicateObjectErrorPattern, strings.ToLower(objectType.String()), objectName, schemaName))
+}
+
+func unknownObjectError(schemaName string, objectType ObjectType, objectName string) error {
+	kind := map[ObjectType]ErrorKind{
+		ObjectTrigger: ErrTriggerDoesNotExist, ObjectProcedure: ErrRoutineDoesNotExist,
+		ObjectFunction: ErrRoutineDoesNotExist, ObjectEvent: ErrEventDoesNotExist,
+	}[objectType]
+	return newObjectError(kind, schemaName, objectType, objectName,
+		fmt.Sprintf(NotExistObjectErrorPattern, strings.ToLower(objectType.String()), objectName, schemaName))
+}
+
+func wrongObjectError(schemaName, tableName string) error {
+	err := newSchemaError(ErrWrongObject, schemaName, tableName,
+		fmt.Sprintf(WrongObjectErrorPattern, schemaName, tableName, ObjectView.String()))
+	err.arg = ObjectView.String()
+	return err
+}
+
+// notBaseTableError is returned when a view is used where a table is needed.
+func notBaseTableError(schemaName, tableName string) error {
+	err := newSchemaError(ErrWrongObject, schemaName, tableName,
+		fmt.Sprintf(WrongObjectErrorPattern, schemaName, tableName, "BASE TABLE"))
+	err.arg = "BASE TABLE"
+	return err
+}
+
+func newPartitionError(kind ErrorKind, schemaName, tableName, partitionName, arg string, msg string) *SchemaError {
+	err := newSchemaError(kind, schemaName, tableName, msg)
+	err.Partition = partitionName
+	err.arg = arg
+	return err
+}
+
+func notPartitionedError(schemaName, tableName string) error {
+	return newSchemaError(ErrNotPartitioned, schemaName, tableName,
+		fmt.Sprintf(NotPartitionedErrorPattern, schemaName, tableName))
+}
+
+func duplicateCheckError(schemaName, tableName, checkName string) error {
+	return newIndexError(ErrCheckConstraintDupName, schemaName, tableName, checkName,
+		fmt.Sprintf(DuplicateCheckErrorPattern, checkName, schemaName, tableName))
+}
+
+func reorgOutsideRangeError(schemaName, tableName string) error {
+	return newSchemaError(ErrReorgOutsideRange, schemaName, tableName,
+		fmt.Sprintf(ReorgOutsideRangeErrorPattern, schemaName, tableName))
+}
+
+func partitionColumnError(schemaName, tableName, columnName string) error {
+	return newColumnError(ErrPartitionColumnNotFound, schemaName, tableName, columnName,
+		fmt.Sprintf(NotExistPartitionColumnErrorPattern, columnName, schemaName, tableName))
+}
+
+// maxSnippetLength is the max length in characters of the statement snippet in a StmtError.
+const maxSnippetLength = 64
+
+// StmtError is the error of a statement executed by ExecSQL, it records where
+// the statement is in the input.
+type StmtError struct {
+	// Index is the ordinal of the statement in the input, starting from 1.
+	Index int
+	// Line and Column are the start position of the statement, starting from 1.
+	Line    int
+	Column  int
+	Snippet string
+	Err     error
+}
+
+func (e *StmtError) Error() string {
+	return fmt.Sprintf("statement #%d at line %d, column %d (%s): %s",
+		e.Index, e.Line, e.Column, e.Snippet, e.Err)
+}
+
+func (e *StmtError) Unwrap() error {
+	return e.Err
+}
+
+// ExecErrors is the failures of ExecSQLWithOpt with ContinueOnError, in the
+// order of the statements.
+type ExecErrors []*StmtError
+
+func (e ExecErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is reports whether any of the failures matches target.
+func (e ExecErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first failure that matches target.
+func (e ExecErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
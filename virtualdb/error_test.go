@@ -0,0 +1,160 @@
+package virtualdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSchemaError(t *testing.T, input string, kind ErrorKind, mysqlMessage string) {
+	vb := NewVirtualDB("db1")
+	err := vb.ExecSQL(input)
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Errorf("expect SchemaError, got %v", err)
+		return
+	}
+	assert.Equal(t, kind, schemaErr.Kind)
+	assert.Equal(t, mysqlMessage, schemaErr.MySQLMessage())
+}
+
+func TestSchemaError(t *testing.T) {
+	testSchemaError(t, "use db2;", ErrUnknownDatabase,
+		"ERROR 1049 (42000): Unknown database 'db2'")
+	testSchemaError(t, "alter database db2 character set latin1;", ErrUnknownDatabase,
+		"ERROR 1049 (42000): Unknown database 'db2'")
+	testSchemaError(t, "create database db1;", ErrDatabaseExists,
+		"ERROR 1007 (HY000): Can't create database 'db1'; database exists")
+	testSchemaError(t, "drop database db2;", ErrCantDropDatabase,
+		"ERROR 1008 (HY000): Can't drop database 'db2'; database doesn't exist")
+	testSchemaError(t, "create table t1(a int); create table t1(a int);", ErrTableExists,
+		"ERROR 1050 (42S01): Table 't1' already exists")
+	testSchemaError(t, "drop table t1;", ErrUnknownTable,
+		"ERROR 1051 (42S02): Unknown table 'db1.t1'")
+	testSchemaError(t, "drop table t1, t2;", ErrUnknownTable,
+		"ERROR 1051 (42S02): Unknown table 'db1.t1,db1.t2'")
+	testSchemaError(t, "alter table t1 add column a int;", ErrNoSuchTable,
+		"ERROR 1146 (42S02): Table 'db1.t1' doesn't exist")
+	testSchemaError(t, "create table t1(a int); alter table t1 add column b int after c;", ErrUnknownColumn,
+		"ERROR 1054 (42S22): Unknown column 'c' in 't1'")
+	testSchemaError(t, "create table t1(a int); alter table t1 add column a int;", ErrDuplicateColumn,
+		"ERROR 1060 (42S21): Duplicate column name 'a'")
+	testSchemaError(t, "create table t1(a int, index i1(a)); alter table t1 add index i1(a);", ErrDuplicateKeyName,
+		"ERROR 1061 (42000): Duplicate key name 'i1'")
+	testSchemaError(t, "create table t1(a int); alter table t1 drop column b;", ErrCantDrop,
+		"ERROR 1091 (42000): Can't DROP 'b'; check that column/key exists")
+	testSchemaError(t, "create table t1(a int); alter table t1 drop index i1;", ErrCantDrop,
+		"ERROR 1091 (42000): Can't DROP 'i1'; check that column/key exists")
+	testSchemaError(t, "create table t1(a int); alter table t1 rename index i1 to i2;", ErrKeyDoesNotExist,
+		"ERROR 1176 (42000): Key 'i1' doesn't exist in table 't1'")
+	testSchemaError(t, "create table t1(a int primary key); alter table t1 add primary key(a);", ErrMultiplePrimaryKey,
+		"ERROR 1068 (42000): Multiple primary key defined")
+}
+
+func TestSchemaErrorIs(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	err := vb.ExecSQL("create table t1(a int); alter table t1 drop primary key;")
+	assert.True(t, errors.Is(err, NoPrimaryKeyError))
+	assert.False(t, errors.Is(err, ExistPrimaryKeyError))
+	assert.EqualError(t, errors.Unwrap(err), "no primary key")
+	var schemaErr *SchemaError
+	if assert.True(t, errors.As(err, &schemaErr)) {
+		assert.Equal(t, NoPrimaryKeyError, errors.Unwrap(schemaErr))
+		assert.Equal(t, "PRIMARY", schemaErr.Index)
+	}
+
+	err = vb.ExecSQL("alter table t1 drop index `PRIMARY`;")
+	assert.True(t, errors.Is(err, NoPrimaryKeyError))
+	assert.False(t, errors.Is(cantDropIndexError("db1", "t1", "PRIMARY"), NoPrimaryKeyError))
+	assert.False(t, errors.Is(cantDropIndexError("db1", "t1", "PRIMARY"), noPrimaryKeyError("db1", "t1")))
+
+	err = vb.ExecSQL("alter table t1 drop column b;")
+	assert.True(t, errors.Is(err, &SchemaError{Kind: ErrCantDrop, Table: "t1", Column: "b"}))
+	assert.False(t, errors.Is(err, &SchemaError{Kind: ErrCantDrop, Column: "a"}))
+}
+
+func TestStmtError(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	err := vb.ExecSQL(`create table t1(a int);
+create table t2(a int);  alter table t3
+  add column b int;
+create table t4(a int);`)
+	var stmtErr *StmtError
+	if !errors.As(err, &stmtErr) {
+		t.Errorf("expect StmtError, got %v", err)
+		return
+	}
+	assert.Equal(t, 3, stmtErr.Index)
+	assert.Equal(t, 2, stmtErr.Line)
+	assert.Equal(t, 26, stmtErr.Column)
+	assert.Equal(t, "alter table t3 add column b int;", stmtErr.Snippet)
+	assert.True(t, errors.Is(err, &SchemaError{Kind: ErrNoSuchTable, Table: "t3"}))
+	assert.EqualError(t, err, "statement #3 at line 2, column 26 (alter table t3 add column b int;): not exist table: db1.t3")
+
+	err = vb.ExecSQL("create table t5(a int); create table t1(`" + strings.Repeat("a", 70) + "` int);")
+	if !errors.As(err, &stmtErr) {
+		t.Errorf("expect StmtError, got %v", err)
+		return
+	}
+	assert.Equal(t, 2, stmtErr.Index)
+	assert.Equal(t, 1, stmtErr.Line)
+	assert.Equal(t, 25, stmtErr.Column)
+	assert.Equal(t, "create table t1(`"+strings.Repeat("a", 47)+"...", stmtErr.Snippet)
+}
+
+func TestExecSQLContinueOnError(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	err := vb.ExecSQLWithOpt(`create table t1(a int);
+alter table t2 add column b int;
+create table t1(a int);
+alter table t1 add column b int;
+alter table t1 drop column c;`, ExecOption{ContinueOnError: true})
+
+	var errs ExecErrors
+	if !errors.As(err, &errs) {
+		t.Errorf("expect ExecErrors, got %v", err)
+		return
+	}
+	assert.Equal(t, 3, len(errs))
+	assert.Equal(t, []int{2, 3, 5}, []int{errs[0].Line, errs[1].Line, errs[2].Line})
+	assert.EqualError(t, err, "statement #2 at line 2, column 1 (alter table t2 add column b int): not exist table: db1.t2\n"+
+		"statement #3 at line 3, column 1 (create table t1(a int)): duplicate table: db1.t1\n"+
+		"statement #5 at line 5, column 1 (alter table t1 drop column c): not exist column c in db1.t1")
+	assert.True(t, errors.Is(err, &SchemaError{Kind: ErrTableExists, Table: "t1"}))
+	assert.False(t, errors.Is(err, &SchemaError{Kind: ErrUnknownDatabase}))
+
+	var schemaErr *SchemaError
+	assert.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, ErrNoSuchTable, schemaErr.Kind)
+
+	actual, err := vb.Text()
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE DATABASE `db1`;\nCREATE TABLE `db1`.`t1` (`a` INT,`b` INT);\n", actual)
+
+	assert.NoError(t, vb.ExecSQLWithOpt("create table t3(a int);", ExecOption{ContinueOnError: true}))
+}
+
+func TestExecSQLContinueOnSyntaxError(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	err := vb.ExecSQLWithOpt(`create table t1(a int);
+create tabel t2(a int);
+create table t3(a int);`, ExecOption{ContinueOnError: true})
+
+	var errs ExecErrors
+	if !errors.As(err, &errs) {
+		t.Errorf("expect ExecErrors, got %v", err)
+		return
+	}
+	if assert.Equal(t, 1, len(errs)) {
+		assert.Equal(t, 2, errs[0].Index)
+		assert.Equal(t, 2, errs[0].Line)
+		assert.Equal(t, 1, errs[0].Column)
+		assert.Equal(t, "create tabel t2(a int)", errs[0].Snippet)
+	}
+
+	actual, err := vb.Text()
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE DATABASE `db1`;\nCREATE TABLE `db1`.`t1` (`a` INT);\nCREATE TABLE `db1`.`t3` (`a` INT);\n", actual)
+}
@@ -0,0 +1,355 @@
+package virtualdb
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pingcap/parser/ast"
+)
+
+// Event is a change of the schema, it carries the statement which caused it.
+type Event interface {
+	Statement() ast.StmtNode
+}
+
+// Observer receives the events of a VirtualDB. The events are delivered after
+// the statement is applied and the lock is released, so observers can call the
+// getters of the VirtualDB. The events are delivered one statement at a time in
+// the order the statements are applied, so observers must not execute
+// statements on the same VirtualDB.
+type Observer interface {
+	OnEvent(event Event)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(event Event)
+
+func (f ObserverFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// BaseEvent is embedded by all the events.
+type BaseEvent struct {
+	Stmt ast.StmtNode
+}
+
+func (e BaseEvent) Statement() ast.StmtNode {
+	return e.Stmt
+}
+
+type SchemaCreated struct {
+	BaseEvent
+	Schema *ast.CreateDatabaseStmt
+}
+
+type SchemaDropped struct {
+	BaseEvent
+	Schema *ast.CreateDatabaseStmt
+}
+
+// SchemaAltered is sent by ALTER DATABASE with the schema before and after it.
+type SchemaAltered struct {
+	BaseEvent
+	OldSchema *ast.CreateDatabaseStmt
+	NewSchema *ast.CreateDatabaseStmt
+}
+
+type TableCreated struct {
+	BaseEvent
+	Schema string
+	Table  *ast.CreateTableStmt
+}
+
+type TableDropped struct {
+	BaseEvent
+	Schema string
+	Table  *ast.CreateTableStmt
+}
+
+// TableRenamed is sent after the other events of the same ALTER TABLE, which
+// refer to the table by its old name.
+type TableRenamed struct {
+	BaseEvent
+	OldSchema string
+	OldTable  string
+	NewSchema string
+	NewTable  string
+}
+
+type TableOptionsChanged struct {
+	BaseEvent
+	Schema string
+	Table  string
+	Before []*ast.TableOption
+	After  []*ast.TableOption
+}
+
+// ColumnEvent is the change of a column, Before is nil for new columns and After
+// is nil for dropped columns.
+type ColumnEvent struct {
+	BaseEvent
+	Schema string
+	Table  string
+	Before *ast.ColumnDef
+	After  *ast.ColumnDef
+}
+
+type ColumnAdded struct{ ColumnEvent }
+
+type ColumnDropped struct{ ColumnEvent }
+
+// ColumnModified is sent when the definition or the name of a column is changed.
+type ColumnModified struct{ ColumnEvent }
+
+// IndexEvent is the change of an index, foreign key or check constraint, Before
+// is nil for new ones and After is nil for dropped ones.
+type IndexEvent struct {
+	BaseEvent
+	Schema string
+	Table  string
+	Before *ast.Constraint
+	After  *ast.Constraint
+}
+
+type IndexAdded struct{ IndexEvent }
+
+type IndexDropped struct{ IndexEvent }
+
+type IndexRenamed struct{ IndexEvent }
+
+// IndexModified is sent when the key parts of an index are changed by the
+// columns they refer to.
+type IndexModified struct{ IndexEvent }
+
+// ViewEvent is the change of a view, Before is nil for new views and After is
+// nil for dropped views.
+type ViewEvent struct {
+	BaseEvent
+	Schema string
+	Before *ast.CreateViewStmt
+	After  *ast.CreateViewStmt
+}
+
+type ViewCreated struct{ ViewEvent }
+
+type ViewDropped struct{ ViewEvent }
+
+// ViewReplaced is sent by CREATE OR REPLACE VIEW and ALTER VIEW, the statement
+// of ALTER VIEW is an ObjectStmt.
+type ViewReplaced struct{ ViewEvent }
+
+// ObjectChange is the change of a trigger, stored procedure, function or event,
+// Before is nil for new objects and After is nil for dropped objects. The
+// statement is an ObjectStmt, or the DROP TABLE and ALTER TABLE which drop or
+// rename the table of a trigger.
+type ObjectChange struct {
+	BaseEvent
+	Schema string
+	Before *ObjectInfo
+	After  *ObjectInfo
+}
+
+type ObjectCreated struct{ ObjectChange }
+
+type ObjectDropped struct{ ObjectChange }
+
+// ObjectAltered is sent by ALTER, CREATE OR REPLACE and the rename of the table
+// of a trigger, NewSchema differs from Schema when ALTER EVENT ... RENAME TO
+// moves the event to another schema.
+type ObjectAltered struct {
+	ObjectChange
+	NewSchema string
+}
+
+// AddObserver registers an observer for the events of the following statements.
+func (c *VirtualDB) AddObserver(observer Observer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observers = append(c.observers, observer)
+}
+
+// emit queues the event until the statement is done, the caller must hold the lock.
+func (c *VirtualDB) emit(event Event) {
+	if len(c.observers) != 0 {
+		c.events = append(c.events, event)
+	}
+}
+
+// takeEvents returns and clears the queued events, the caller must hold the lock.
+func (c *VirtualDB) takeEvents() ([]Observer, []Event) {
+	events := c.events
+	c.events = nil
+	return c.observers, events
+}
+
+// unlockAndNotify releases the lock and sends the events to the observers. The
+// events are numbered before the lock is released and delivered in that order,
+// so concurrent statements can't deliver their events out of order.
+func (c *VirtualDB) unlockAndNotify(observers []Observer, events []Event) {
+	if len(observers) == 0 || len(events) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	seq := c.notifySeq
+	c.notifySeq++
+	c.mu.Unlock()
+
+	c.notifyMu.Lock()
+	defer c.notifyMu.Unlock()
+	if c.notifyCond == nil {
+		c.notifyCond = sync.NewCond(&c.notifyMu)
+	}
+	for c.notifiedSeq != seq {
+		c.notifyCond.Wait()
+	}
+	defer func() {
+		c.notifiedSeq++
+		c.notifyCond.Broadcast()
+	}()
+	notify(observers, events)
+}
+
+func notify(observers []Observer, events []Event) {
+	for _, event := range events {
+		for _, observer := range observers {
+			observer.OnEvent(event)
+		}
+	}
+}
+
+// emitAlterEvents compares the table before and after the ALTER TABLE, columns
+// are the new names of the changed and dropped columns returned by alterColumns.
+func (c *VirtualDB) emitAlterEvents(alter *ast.AlterTableStmt, oldTable, newTable *ast.CreateTableStmt,
+	columns map[string]*ast.ColumnName) {
+
+	if len(c.observers) == 0 {
+		return
+	}
+	schemaName := c.getSchemaName(oldTable.Table)
+	tableName := oldTable.Table.Name.String()
+	base := BaseEvent{Stmt: alter}
+
+	if !equalNodes(tableOptionNodes(oldTable.Options), tableOptionNodes(newTable.Options)) {
+		c.emit(&TableOptionsChanged{base, schemaName, tableName, oldTable.Options, newTable.Options})
+	}
+
+	matchedCols := map[*ast.ColumnDef]struct{}{}
+	for _, col := range oldTable.Cols {
+		newName := col.Name
+		if name, ok := columns[col.Name.Name.L]; ok {
+			newName = name
+		}
+		var newCol *ast.ColumnDef
+		if newName != nil {
+			newCol = findColumn(newTable.Cols, newName.Name.L)
+		}
+		if newCol == nil {
+			c.emit(&ColumnDropped{ColumnEvent{base, schemaName, tableName, col, nil}})
+			continue
+		}
+		matchedCols[newCol] = struct{}{}
+		if col.Name.Name.O != newCol.Name.Name.O || !equalNodes([]restorer{col}, []restorer{newCol}) {
+			c.emit(&ColumnModified{ColumnEvent{base, schemaName, tableName, col, newCol}})
+		}
+	}
+	for _, col := range newTable.Cols {
+		if _, ok := matchedCols[col]; !ok {
+			c.emit(&ColumnAdded{ColumnEvent{base, schemaName, tableName, nil, col}})
+		}
+	}
+
+	renames := map[string]string{}
+	for _, spec := range getAlterTableSpecByTp(alter.Specs, ast.AlterTableRenameIndex) {
+		renames[strings.ToLower(spec.FromKey.O)] = strings.ToLower(spec.ToKey.O)
+	}
+	drops := getAlterTableSpecByTp(alter.Specs, ast.AlterTableDropIndex,
+		ast.AlterTableDropPrimaryKey, ast.AlterTableDropForeignKey)
+
+	matchedConstraints := map[*ast.Constraint]struct{}{}
+	for _, constraint := range oldTable.Constraints {
+		dropped := false
+		for _, spec := range drops {
+			if isDropConstraintSpec(spec, constraint) {
+				dropped = true
+			}
+		}
+		var newConstraint *ast.Constraint
+		newName, renamed := renames[strings.ToLower(constraint.Name)]
+		renamed = renamed && isIndexConstraint(constraint)
+		if !dropped {
+			name := constraint.Name
+			if renamed {
+				name = newName
+			}
+			newConstraint = findConstraint(newTable.Constraints, constraintNamespace(constraint), name)
+		}
+		switch {
+		case newConstraint == nil:
+			c.emit(&IndexDropped{IndexEvent{base, schemaName, tableName, constraint, nil}})
+			continue
+		case renamed:
+			c.emit(&IndexRenamed{IndexEvent{base, schemaName, tableName, constraint, newConstraint}})
+		case !equalNodes([]restorer{constraint}, []restorer{newConstraint}):
+			c.emit(&IndexModified{IndexEvent{base, schemaName, tableName, constraint, newConstraint}})
+		}
+		matchedConstraints[newConstraint] = struct{}{}
+	}
+	for _, constraint := range newTable.Constraints {
+		if _, ok := matchedConstraints[constraint]; !ok {
+			c.emit(&IndexAdded{IndexEvent{base, schemaName, tableName, nil, constraint}})
+		}
+	}
+
+	newSchemaName := c.getSchemaName(newTable.Table)
+	newTableName := newTable.Table.Name.String()
+	if newSchemaName != schemaName || newTableName != tableName {
+		c.emit(&TableRenamed{base, schemaName, tableName, newSchemaName, newTableName})
+	}
+}
+
+func findColumn(cols []*ast.ColumnDef, columnName string) *ast.ColumnDef {
+	for _, col := range cols {
+		if col.Name.Name.L == columnName {
+			return col
+		}
+	}
+	return nil
+}
+
+// findConstraint finds the constraint by its name in the namespace, the unnamed
+// primary key is found by the name "PRIMARY".
+func findConstraint(constraints []*ast.Constraint, namespace ast.ConstraintType, name string) *ast.Constraint {
+	for _, con := range constraints {
+		if constraintNamespace(con) != namespace {
+			continue
+		}
+		if strings.EqualFold(con.Name, name) ||
+			(con.Tp == ast.ConstraintPrimaryKey && (name == "" || strings.EqualFold(name, "PRIMARY"))) {
+			return con
+		}
+	}
+	return nil
+}
+
+func tableOptionNodes(options []*ast.TableOption) []restorer {
+	nodes := make([]restorer, 0, len(options))
+	for _, op := range options {
+		nodes = append(nodes, op)
+	}
+	return nodes
+}
+
+// equalNodes reports whether the nodes are restored to the same SQL.
+func equalNodes(a, b []restorer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		sqlA, errA := restoreToSql(a[i])
+		sqlB, errB := restoreToSql(b[i])
+		if errA != nil || errB != nil || sqlA != sqlB {
+			return false
+		}
+	}
+	return true
+}
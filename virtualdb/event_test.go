@@ -0,0 +1,228 @@
+package virtualdb
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+// describeEvent formats the event as "<type> <object> [before -> after]".
+func describeEvent(t *testing.T, event Event) string {
+	sql := func(node restorer) string {
+		if node == nil {
+			return "nil"
+		}
+		s, err := restoreToSql(node)
+		assert.NoError(t, err)
+		return s
+	}
+	switch e := event.(type) {
+	case *SchemaCreated:
+		return "SchemaCreated " + e.Schema.Name
+	case *SchemaDropped:
+		return "SchemaDropped " + e.Schema.Name
+	case *SchemaAltered:
+		return fmt.Sprintf("SchemaAltered %s -> %s", sql(e.OldSchema), sql(e.NewSchema))
+	case *TableCreated:
+		return "TableCreated " + e.Schema + "." + e.Table.Table.Name.O
+	case *TableDropped:
+		return "TableDropped " + e.Schema + "." + e.Table.Table.Name.O
+	case *TableRenamed:
+		return fmt.Sprintf("TableRenamed %s.%s -> %s.%s", e.OldSchema, e.OldTable, e.NewSchema, e.NewTable)
+	case *TableOptionsChanged:
+		return fmt.Sprintf("TableOptionsChanged %s.%s", e.Schema, e.Table)
+	case *ColumnAdded:
+		return fmt.Sprintf("ColumnAdded %s.%s %s", e.Schema, e.Table, sql(e.After))
+	case *ColumnDropped:
+		return fmt.Sprintf("ColumnDropped %s.%s %s", e.Schema, e.Table, sql(e.Before))
+	case *ColumnModified:
+		return fmt.Sprintf("ColumnModified %s.%s %s -> %s", e.Schema, e.Table, sql(e.Before), sql(e.After))
+	case *IndexAdded:
+		return fmt.Sprintf("IndexAdded %s.%s %s", e.Schema, e.Table, sql(e.After))
+	case *IndexDropped:
+		return fmt.Sprintf("IndexDropped %s.%s %s", e.Schema, e.Table, sql(e.Before))
+	case *IndexRenamed:
+		return fmt.Sprintf("IndexRenamed %s.%s %s -> %s", e.Schema, e.Table, sql(e.Before), sql(e.After))
+	case *IndexModified:
+		return fmt.Sprintf("IndexModified %s.%s %s -> %s", e.Schema, e.Table, sql(e.Before), sql(e.After))
+	case *ViewCreated:
+		return fmt.Sprintf("ViewCreated %s %s", e.Schema, sql(e.After))
+	case *ViewDropped:
+		return fmt.Sprintf("ViewDropped %s %s", e.Schema, sql(e.Before))
+	case *ViewReplaced:
+		return fmt.Sprintf("ViewReplaced %s %s -> %s", e.Schema, sql(e.Before), sql(e.After))
+	case *ObjectCreated:
+		return fmt.Sprintf("ObjectCreated %s %s", e.Schema, e.After.CreateSQL(""))
+	case *ObjectDropped:
+		return fmt.Sprintf("ObjectDropped %s %s", e.Schema, e.Before.CreateSQL(""))
+	case *ObjectAltered:
+		return fmt.Sprintf("ObjectAltered %s %s -> %s %s", e.Schema, e.Before.CreateSQL(""),
+			e.NewSchema, e.After.CreateSQL(""))
+	}
+	return fmt.Sprintf("%T", event)
+}
+
+func testEvents(t *testing.T, init, input string, expect ...string) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL(init))
+
+	actual := []string{}
+	vb.AddObserver(ObserverFunc(func(event Event) {
+		assert.NotNil(t, event.Statement())
+		actual = append(actual, describeEvent(t, event))
+	}))
+	vb.ExecSQLWithOpt(input, ExecOption{ContinueOnError: true})
+	if len(expect) == 0 {
+		expect = []string{}
+	}
+	assert.Equal(t, expect, actual)
+}
+
+func TestEvents(t *testing.T) {
+	testEvents(t, "", "create database db2; create database if not exists db2; drop database db2;",
+		"SchemaCreated db2",
+		"SchemaDropped db2")
+
+	testEvents(t, "", "create database db2 charset latin1; alter database db2 collate utf8mb4_bin;",
+		"SchemaCreated db2",
+		"SchemaAltered CREATE DATABASE `db2` CHARACTER SET = latin1 -> CREATE DATABASE `db2` COLLATE = utf8mb4_bin")
+
+	testEvents(t, "create table t1(a int);",
+		"create table t2(a int); create table if not exists t1(b int); drop table t1, t2;",
+		"TableCreated db1.t2",
+		"TableDropped db1.t1",
+		"TableDropped db1.t2")
+
+	testEvents(t, "create table t1(a int, b int, c int);",
+		"alter table t1 add column d int, drop column b, change c e bigint, alter column a set default 1;",
+		"ColumnModified db1.t1 `a` INT -> `a` INT DEFAULT 1",
+		"ColumnDropped db1.t1 `b` INT",
+		"ColumnModified db1.t1 `c` INT -> `e` BIGINT",
+		"ColumnAdded db1.t1 `d` INT")
+
+	testEvents(t, "create table t1(a int, b int, index i1(a), index i2(a, b), primary key(a));",
+		"alter table t1 drop index i1, rename index i2 to i3, drop primary key, add unique key u1(b);",
+		"IndexDropped db1.t1 INDEX `i1`(`a`)",
+		"IndexRenamed db1.t1 INDEX `i2`(`a`, `b`) -> INDEX `i3`(`a`, `b`)",
+		"IndexDropped db1.t1 PRIMARY KEY(`a`)",
+		"IndexAdded db1.t1 UNIQUE `u1`(`b`)")
+
+	testEvents(t, "create table t1(a int, b int, index i1(a, b), index i2(b));",
+		"alter table t1 drop column b;",
+		"ColumnDropped db1.t1 `b` INT",
+		"IndexModified db1.t1 INDEX `i1`(`a`, `b`) -> INDEX `i1`(`a`)",
+		"IndexDropped db1.t1 INDEX `i2`(`b`)")
+
+	testEvents(t, "create database db2; create table t1(a int) comment 'x';",
+		"alter table t1 comment 'y', rename to db2.t2;",
+		"TableOptionsChanged db1.t1",
+		"TableRenamed db1.t1 -> db2.t2")
+}
+
+func TestViewAndObjectEvents(t *testing.T) {
+	testEvents(t, "create table t1(a int);",
+		"create view v1 as select a from t1; create or replace view v1 as select a + 1 as b from t1; "+
+			"alter view v1 as select a as c from t1; drop view v1;",
+		"ViewCreated db1 CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`v1` AS SELECT `a` FROM `db1`.`t1`",
+		"ViewReplaced db1 CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`v1` AS SELECT `a` FROM `db1`.`t1` -> "+
+			"CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`v1` AS SELECT `a`+1 AS `b` FROM `db1`.`t1`",
+		"ViewReplaced db1 CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`v1` AS SELECT `a`+1 AS `b` FROM `db1`.`t1` -> "+
+			"CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`v1` AS SELECT `a` AS `c` FROM `db1`.`t1`",
+		"ViewDropped db1 CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`v1` AS SELECT `a` AS `c` FROM `db1`.`t1`")
+
+	testEvents(t, "create database db2; create table t1(a int);",
+		"create procedure p1() select 1; alter procedure p1 comment 'x'; drop procedure p1; "+
+			"create event e1 on schedule every 1 day do select 1; alter event e1 rename to db2.e2; "+
+			"create trigger tr1 before insert on t1 for each row set new.a = 1; "+
+			"alter table t1 rename to t2; drop table t2;",
+		"ObjectCreated db1 CREATE PROCEDURE `p1` () select 1",
+		"ObjectAltered db1 CREATE PROCEDURE `p1` () select 1 -> db1 CREATE PROCEDURE `p1` () select 1",
+		"ObjectDropped db1 CREATE PROCEDURE `p1` () select 1",
+		"ObjectCreated db1 CREATE EVENT `e1` on schedule every 1 day do select 1",
+		"ObjectAltered db1 CREATE EVENT `e1` on schedule every 1 day do select 1 -> db2 CREATE EVENT `e2` on schedule every 1 day do select 1",
+		"ObjectCreated db1 CREATE TRIGGER `tr1` BEFORE INSERT ON `t1` for each row set new.a = 1",
+		"ObjectAltered db1 CREATE TRIGGER `tr1` BEFORE INSERT ON `t1` for each row set new.a = 1 -> "+
+			"db1 CREATE TRIGGER `tr1` BEFORE INSERT ON `t2` for each row set new.a = 1",
+		"TableRenamed db1.t1 -> db1.t2",
+		"ObjectDropped db1 CREATE TRIGGER `tr1` BEFORE INSERT ON `t2` for each row set new.a = 1",
+		"TableDropped db1.t2")
+}
+
+func TestCreateViewKeepsStatement(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int);"))
+	node, err := parseOneSql("create or replace view v1 as select a from t1")
+	assert.NoError(t, err)
+	assert.NoError(t, vb.Exec(node))
+
+	// the caller's statement is not qualified by the schema.
+	sql, err := restoreToSql(node)
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE OR REPLACE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `v1` AS SELECT `a` FROM `t1`", sql)
+	view, exist := vb.getView("db1", "v1")
+	assert.True(t, exist)
+	assert.NotEqual(t, node, view.View)
+}
+
+func TestEventsOfFailedStatements(t *testing.T) {
+	// the failed statements don't send events.
+	testEvents(t, "create table t1(a int);",
+		"alter table t1 add column b int, drop column c; create table t1(a int); create table t2(a int);",
+		"TableCreated db1.t2")
+
+	vb := NewVirtualDB("db1")
+	events := []Event{}
+	vb.AddObserver(ObserverFunc(func(event Event) {
+		events = append(events, event)
+	}))
+	assert.Error(t, vb.ExecSQLAtomic("create table t1(a int); create table t1(a int);"))
+	assert.Equal(t, 0, len(events))
+
+	assert.NoError(t, vb.ExecSQLAtomic("create table t1(a int); alter table t1 add column b int;"))
+	assert.Equal(t, 2, len(events))
+	_, ok := events[1].Statement().(*ast.AlterTableStmt)
+	assert.True(t, ok)
+}
+
+func TestObserverCallsGetters(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	vb.AddObserver(ObserverFunc(func(event Event) {
+		_, exist, err := vb.GetColumn("db1", "t1", "a")
+		assert.NoError(t, err)
+		assert.True(t, exist)
+	}))
+	assert.NoError(t, vb.ExecSQL("create table t1(a int);"))
+}
+
+func TestEventsInStatementOrder(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int);"))
+	columns := []string{}
+	vb.AddObserver(ObserverFunc(func(event Event) {
+		if e, ok := event.(*ColumnAdded); ok {
+			columns = append(columns, e.After.Name.Name.O)
+		}
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, vb.ExecSQL(fmt.Sprintf("alter table t1 add column c%d int;", i)))
+		}(i)
+	}
+	wg.Wait()
+
+	// the observer sees the columns in the order they are added to the table.
+	tables, ok := vb.GetTableStmts("db1")
+	assert.True(t, ok)
+	expect := []string{}
+	for _, col := range tables["t1"].Table.Cols[1:] {
+		expect = append(expect, col.Name.Name.O)
+	}
+	assert.Equal(t, expect, columns)
+}
@@ -0,0 +1,521 @@
+package virtualdb
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/opcode"
+	"github.com/pingcap/tidb/types"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+)
+
+// The types of the expressions of CREATE TABLE ... SELECT are inferred like
+// MySQL: the integer results with a display length of 10 or more are BIGINT,
+// the decimal results follow the precision rules of the arithmetic operators,
+// and the string results longer than 512 characters are TEXT types.
+
+const (
+	// divPrecisionIncrement is the default div_precision_increment.
+	divPrecisionIncrement = 4
+	// longlongDigits is the precision added to SUM of integers and decimals.
+	longlongDigits = 22
+	// maxVarcharLength is the max length of a VARCHAR result, longer results are TEXT.
+	maxVarcharLength    = 512
+	maxDecimalPrecision = 65
+	maxDecimalScale     = 30
+)
+
+type numericClass int
+
+const (
+	numericInt numericClass = iota
+	numericDecimal
+	numericReal
+)
+
+// numericType is the type of an operand of the arithmetic operators, length is
+// the display length of integers.
+type numericType struct {
+	class     numericClass
+	length    int
+	precision int
+	frac      int
+	unsigned  bool
+}
+
+// intDisplayLengths is the display length of each integer type, signed and unsigned.
+var intDisplayLengths = map[byte][2]int{
+	mysql.TypeTiny:     {4, 3},
+	mysql.TypeShort:    {6, 5},
+	mysql.TypeInt24:    {9, 8},
+	mysql.TypeLong:     {11, 10},
+	mysql.TypeLonglong: {20, 20},
+	mysql.TypeYear:     {4, 4},
+}
+
+// numericOf returns the numeric type of the operand, the strings and the other
+// types are converted to DOUBLE like MySQL.
+func numericOf(expr ast.ExprNode, col *ast.ColumnDef) numericType {
+	if literal, ok := intLiteral(expr); ok {
+		text := fmt.Sprint(literal)
+		precision := len(strings.TrimPrefix(text, "-"))
+		return numericType{class: numericInt, length: len(text), precision: precision}
+	}
+	tp := col.Tp
+	if lengths, ok := intDisplayLengths[tp.Tp]; ok {
+		if mysql.HasUnsignedFlag(tp.Flag) {
+			return numericType{class: numericInt, length: lengths[1], precision: lengths[1], unsigned: true}
+		}
+		return numericType{class: numericInt, length: lengths[0], precision: lengths[0] - 1}
+	}
+	if tp.Tp == mysql.TypeNewDecimal {
+		precision, frac := tp.Flen, tp.Decimal
+		if precision == types.UnspecifiedLength {
+			precision = 10
+		}
+		if frac == types.UnspecifiedLength {
+			frac = 0
+		}
+		return numericType{class: numericDecimal, precision: precision, frac: frac,
+			unsigned: mysql.HasUnsignedFlag(tp.Flag)}
+	}
+	return numericType{class: numericReal}
+}
+
+// intLiteral returns the value of an integer literal.
+func intLiteral(expr ast.ExprNode) (interface{}, bool) {
+	value, ok := expr.(*driver.ValueExpr)
+	if !ok {
+		return nil, false
+	}
+	switch value.Kind() {
+	case types.KindInt64:
+		return value.GetInt64(), true
+	case types.KindUint64:
+		return value.GetUint64(), true
+	}
+	return nil, false
+}
+
+// isNotNull reports whether all the columns are NOT NULL.
+func isNotNull(cols ...*ast.ColumnDef) bool {
+	for _, col := range cols {
+		if !hasOneInOptions(col.Options, ast.ColumnOptionNotNull) {
+			return false
+		}
+	}
+	return true
+}
+
+// newResultColumn returns the column of a result, which is NOT NULL if notNull.
+func newResultColumn(tp *types.FieldType, notNull bool) *ast.ColumnDef {
+	col := &ast.ColumnDef{Tp: tp}
+	if notNull {
+		col.Options = []*ast.ColumnOption{{Tp: ast.ColumnOptionNotNull}}
+	}
+	return col
+}
+
+// intResultColumn returns INT for the integers shorter than 10 and BIGINT for the others.
+func intResultColumn(length int, unsigned, notNull bool) *ast.ColumnDef {
+	tp := newFieldType(mysql.TypeLong, types.UnspecifiedLength, types.UnspecifiedLength)
+	if length >= 10 {
+		tp.Tp = mysql.TypeLonglong
+	}
+	if unsigned {
+		tp.Flag |= mysql.UnsignedFlag
+	}
+	return newResultColumn(tp, notNull)
+}
+
+func decimalResultColumn(precision, frac int, notNull bool) *ast.ColumnDef {
+	if frac > maxDecimalScale {
+		frac = maxDecimalScale
+	}
+	if precision > maxDecimalPrecision {
+		precision = maxDecimalPrecision
+	}
+	if precision < frac || precision == 0 {
+		precision = frac + 1
+	}
+	return newResultColumn(newFieldType(mysql.TypeNewDecimal, precision, frac), notNull)
+}
+
+// longTextColumn returns the column of the expressions whose types are unknown.
+func longTextColumn() *ast.ColumnDef {
+	return &ast.ColumnDef{Tp: newFieldType(mysql.TypeLongBlob, types.UnspecifiedLength, types.UnspecifiedLength)}
+}
+
+func doubleResultColumn(notNull bool) *ast.ColumnDef {
+	return newResultColumn(newFieldType(mysql.TypeDouble, types.UnspecifiedLength, types.UnspecifiedLength), notNull)
+}
+
+// stringResultColumn returns VARCHAR for the strings up to 512 characters and
+// the smallest TEXT type which holds the utf8mb4 string for the others.
+func stringResultColumn(length int64, notNull bool) *ast.ColumnDef {
+	if length <= maxVarcharLength {
+		return newResultColumn(newFieldType(mysql.TypeVarchar, int(length), types.UnspecifiedLength), notNull)
+	}
+	tp := mysql.TypeLongBlob
+	for _, textType := range textTypes {
+		if length*int64(getCharsetMaxLen(DefaultCharset)) <= textType.maxLen {
+			tp = textType.tp
+			break
+		}
+	}
+	return newResultColumn(newFieldType(tp, types.UnspecifiedLength, types.UnspecifiedLength), notNull)
+}
+
+// charLength returns the max length in characters of the column converted to a string.
+func charLength(col *ast.ColumnDef) int64 {
+	tp := col.Tp
+	if lengths, ok := intDisplayLengths[tp.Tp]; ok {
+		if mysql.HasUnsignedFlag(tp.Flag) {
+			return int64(lengths[1])
+		}
+		return int64(lengths[0])
+	}
+	fsp := int64(0)
+	if tp.Decimal > 0 {
+		fsp = int64(tp.Decimal) + 1
+	}
+	switch tp.Tp {
+	case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString:
+		if tp.Flen == types.UnspecifiedLength {
+			return 1
+		}
+		return int64(tp.Flen)
+	case mysql.TypeEnum, mysql.TypeSet:
+		length := int64(0)
+		for _, elem := range tp.Elems {
+			elemLength := int64(utf8.RuneCountInString(elem))
+			if tp.Tp == mysql.TypeEnum && elemLength > length {
+				length = elemLength
+			} else if tp.Tp == mysql.TypeSet {
+				length += elemLength + 1
+			}
+		}
+		return length
+	case mysql.TypeNewDecimal:
+		number := numericOf(nil, col)
+		length := int64(number.precision) + 1
+		if number.frac > 0 {
+			length++
+		}
+		return length
+	case mysql.TypeFloat:
+		return 12
+	case mysql.TypeDouble:
+		return 22
+	case mysql.TypeDate:
+		return 10
+	case mysql.TypeDatetime, mysql.TypeTimestamp:
+		return 19 + fsp
+	case mysql.TypeDuration:
+		return 10 + fsp
+	}
+	for _, textType := range textTypes {
+		if tp.Tp == textType.tp {
+			if tp.Charset == "binary" {
+				return textType.maxLen
+			}
+			return textType.maxLen / int64(getCharsetMaxLen(tp.Charset))
+		}
+	}
+	return textTypes[len(textTypes)-1].maxLen
+}
+
+// argColumns returns the columns of the arguments.
+func (r *columnResolver) argColumns(args []ast.ExprNode, sources []*selectSource) ([]*ast.ColumnDef, error) {
+	cols := make([]*ast.ColumnDef, 0, len(args))
+	for _, arg := range args {
+		col, err := r.exprColumn(arg, sources)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// binaryOperationColumn returns the column of an arithmetic, comparison, logical
+// or bit operation.
+func (r *columnResolver) binaryOperationColumn(e *ast.BinaryOperationExpr,
+	sources []*selectSource) (*ast.ColumnDef, error) {
+
+	cols, err := r.argColumns([]ast.ExprNode{e.L, e.R}, sources)
+	if err != nil {
+		return nil, err
+	}
+	notNull := isNotNull(cols...)
+	switch e.Op {
+	case opcode.Plus, opcode.Minus, opcode.Mul, opcode.Div, opcode.IntDiv, opcode.Mod:
+		return arithmeticColumn(e.Op, numericOf(e.L, cols[0]), numericOf(e.R, cols[1]), notNull), nil
+	case opcode.And, opcode.Or, opcode.Xor, opcode.LeftShift, opcode.RightShift:
+		return intResultColumn(21, true, notNull), nil
+	case opcode.NullEQ:
+		return intResultColumn(1, false, true), nil
+	}
+	return intResultColumn(1, false, notNull), nil
+}
+
+// arithmeticColumn returns the column of an arithmetic operation like MySQL. The
+// division, the integer division and the modulo may be NULL when dividing by
+// zero.
+func arithmeticColumn(op opcode.Op, l, r numericType, notNull bool) *ast.ColumnDef {
+	if op == opcode.Div || op == opcode.IntDiv || op == opcode.Mod {
+		notNull = false
+	}
+	if op == opcode.IntDiv {
+		return intResultColumn(l.length, l.unsigned || r.unsigned, notNull)
+	}
+	if l.class == numericReal || r.class == numericReal {
+		return doubleResultColumn(notNull)
+	}
+	if op == opcode.Div {
+		return decimalResultColumn(l.precision+divPrecisionIncrement+r.frac, l.frac+divPrecisionIncrement, notNull)
+	}
+
+	if l.class == numericInt && r.class == numericInt {
+		var length int
+		unsigned := l.unsigned || r.unsigned
+		switch op {
+		case opcode.Mul:
+			length = l.length + r.length
+		case opcode.Mod:
+			length, unsigned = maxInt(l.length, r.length), l.unsigned
+		default:
+			length = maxInt(l.length, r.length) + 1
+		}
+		return intResultColumn(length, unsigned, notNull)
+	}
+
+	frac := maxInt(l.frac, r.frac)
+	intDigits := maxInt(l.precision-l.frac, r.precision-r.frac)
+	switch op {
+	case opcode.Mul:
+		return decimalResultColumn(l.precision+r.precision, l.frac+r.frac, notNull)
+	case opcode.Mod:
+		return decimalResultColumn(intDigits+frac, frac, notNull)
+	}
+	return decimalResultColumn(intDigits+1+frac, frac, notNull)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// unaryOperationColumn returns the column of -x, +x, NOT x or ~x.
+func (r *columnResolver) unaryOperationColumn(e *ast.UnaryOperationExpr,
+	sources []*selectSource) (*ast.ColumnDef, error) {
+
+	col, err := r.exprColumn(e.V, sources)
+	if err != nil {
+		return nil, err
+	}
+	notNull := isNotNull(col)
+	switch e.Op {
+	case opcode.Plus:
+		return col, nil
+	case opcode.Minus:
+		number := numericOf(e.V, col)
+		switch number.class {
+		case numericInt:
+			return intResultColumn(number.length+1, false, notNull), nil
+		case numericDecimal:
+			return decimalResultColumn(number.precision, number.frac, notNull), nil
+		}
+		return doubleResultColumn(notNull), nil
+	case opcode.BitNeg:
+		return intResultColumn(21, true, notNull), nil
+	}
+	return intResultColumn(1, false, notNull), nil
+}
+
+// aggregateColumn returns the column of an aggregate function, only COUNT is NOT
+// NULL since the others are NULL without rows.
+func (r *columnResolver) aggregateColumn(e *ast.AggregateFuncExpr, sources []*selectSource) (*ast.ColumnDef, error) {
+	fn := strings.ToLower(e.F)
+	if fn == ast.AggFuncCount {
+		return intResultColumn(21, false, true), nil
+	}
+	if fn == ast.AggFuncGroupConcat {
+		// the result is up to group_concat_max_len, which is 1024 by default.
+		return stringResultColumn(1024, false), nil
+	}
+	if len(e.Args) == 0 {
+		return longTextColumn(), nil
+	}
+	col, err := r.exprColumn(e.Args[0], sources)
+	if err != nil {
+		return nil, err
+	}
+	switch fn {
+	case ast.AggFuncMax, ast.AggFuncMin:
+		tp := *col.Tp
+		return newResultColumn(&tp, false), nil
+	case ast.AggFuncSum, ast.AggFuncAvg:
+		number := numericOf(e.Args[0], col)
+		if number.class == numericReal {
+			return doubleResultColumn(false), nil
+		}
+		if fn == ast.AggFuncSum {
+			return decimalResultColumn(number.precision+longlongDigits, number.frac, false), nil
+		}
+		return decimalResultColumn(number.precision+divPrecisionIncrement, number.frac+divPrecisionIncrement, false), nil
+	case ast.AggFuncBitAnd, ast.AggFuncBitOr, ast.AggFuncBitXor:
+		return intResultColumn(21, true, true), nil
+	}
+	return doubleResultColumn(false), nil
+}
+
+// funcCallColumn returns the column of the string, string length and current
+// time functions, the other functions are LONGTEXT.
+func (r *columnResolver) funcCallColumn(e *ast.FuncCallExpr, sources []*selectSource) (*ast.ColumnDef, error) {
+	switch e.FnName.L {
+	case ast.Now, ast.CurrentTimestamp, ast.LocalTime, ast.LocalTimestamp, ast.Sysdate, ast.UTCTimestamp:
+		return newResultColumn(newFieldType(mysql.TypeDatetime, types.UnspecifiedLength, types.UnspecifiedLength), true), nil
+	case ast.Curdate, ast.CurrentDate, ast.UTCDate:
+		return newResultColumn(newFieldType(mysql.TypeDate, types.UnspecifiedLength, types.UnspecifiedLength), true), nil
+	case ast.Curtime, ast.CurrentTime, ast.UTCTime:
+		return newResultColumn(newFieldType(mysql.TypeDuration, types.UnspecifiedLength, types.UnspecifiedLength), true), nil
+	case ast.UUID:
+		return stringResultColumn(36, true), nil
+	}
+
+	length, ok := stringFuncLengths[e.FnName.L]
+	intLength, isInt := intFuncLengths[e.FnName.L]
+	if (!ok && !isInt) || len(e.Args) == 0 {
+		return longTextColumn(), nil
+	}
+	// the other arguments of TRIM are the direction and the string to remove.
+	args := e.Args
+	if e.FnName.L == ast.Trim {
+		args = args[:1]
+	}
+	cols, err := r.argColumns(args, sources)
+	if err != nil {
+		return nil, err
+	}
+	notNull := isNotNull(cols...)
+	if isInt {
+		return intResultColumn(intLength, false, notNull), nil
+	}
+	return stringResultColumn(length(e.Args, cols), notNull), nil
+}
+
+// intFuncLengths is the display length of the string functions returning integers.
+var intFuncLengths = map[string]int{
+	ast.Length: 10, ast.OctetLength: 10, ast.BitLength: 10, ast.CharLength: 10, ast.CharacterLength: 10,
+	ast.Locate: 11, ast.Instr: 11, ast.Position: 11, ast.Ord: 21,
+	ast.ASCII: 3, ast.Strcmp: 2, ast.FindInSet: 3,
+}
+
+// stringFuncLengths returns the max length in characters of the result of each
+// string function, the lengths are those of MySQL when the length arguments are
+// integer literals, otherwise the results may be as long as LONGTEXT.
+var stringFuncLengths = map[string]func(args []ast.ExprNode, cols []*ast.ColumnDef) int64{
+	ast.Concat: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		length := int64(0)
+		for _, col := range cols {
+			length += charLength(col)
+		}
+		return length
+	},
+	ast.ConcatWS: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		length := int64(0)
+		for _, col := range cols[1:] {
+			length += charLength(col)
+		}
+		if len(cols) > 2 {
+			length += charLength(cols[0]) * int64(len(cols)-2)
+		}
+		return length
+	},
+	ast.Upper: firstArgLength, ast.Lower: firstArgLength, ast.Ucase: firstArgLength, ast.Lcase: firstArgLength,
+	ast.Trim: firstArgLength, ast.LTrim: firstArgLength, ast.RTrim: firstArgLength, ast.Reverse: firstArgLength,
+	ast.Substring: limitedArgLength(2), ast.Substr: limitedArgLength(2), ast.Mid: limitedArgLength(2),
+	ast.Left: limitedArgLength(1), ast.Right: limitedArgLength(1),
+	ast.Lpad: paddedLength, ast.Rpad: paddedLength,
+	ast.Repeat: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		if count, ok := intArg(args, 1); ok {
+			return charLength(cols[0]) * count
+		}
+		return textTypes[len(textTypes)-1].maxLen
+	},
+	ast.Replace: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		length := charLength(cols[0])
+		if len(cols) == 3 {
+			from, to := charLength(cols[1]), charLength(cols[2])
+			if to > from && from > 0 {
+				length += length / from * (to - from)
+			}
+		}
+		return length
+	},
+	ast.Quote: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		return charLength(cols[0])*2 + 2
+	},
+	ast.Hex: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		return charLength(cols[0]) * 2
+	},
+	ast.MD5: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		return 32
+	},
+	ast.SHA1: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		return 40
+	},
+	ast.SHA: func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		return 40
+	},
+}
+
+func firstArgLength(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+	return charLength(cols[0])
+}
+
+// limitedArgLength returns the length of the first argument limited by the length
+// argument at i, such as LEFT(s, 3) and SUBSTRING(s, 2, 3).
+func limitedArgLength(i int) func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+	return func(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+		length := charLength(cols[0])
+		if limit, ok := intArg(args, i); ok && limit < length {
+			return limit
+		}
+		return length
+	}
+}
+
+// paddedLength returns the length of LPAD and RPAD.
+func paddedLength(args []ast.ExprNode, cols []*ast.ColumnDef) int64 {
+	if length, ok := intArg(args, 1); ok {
+		return length
+	}
+	return textTypes[len(textTypes)-1].maxLen
+}
+
+// intArg returns the argument at i if it is a non-negative integer literal.
+func intArg(args []ast.ExprNode, i int) (int64, bool) {
+	if i >= len(args) {
+		return 0, false
+	}
+	literal, ok := intLiteral(args[i])
+	if !ok {
+		return 0, false
+	}
+	switch v := literal.(type) {
+	case int64:
+		if v >= 0 {
+			return v, true
+		}
+	case uint64:
+		return int64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,69 @@
+package virtualdb
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/tidb/types"
+)
+
+// functionalIndexName is the name MySQL gives to an unnamed index whose first
+// key part is an expression.
+const functionalIndexName = "functional_index"
+
+// normalizeIndexes moves the UNIQUE of the column definitions to the constraints
+// of the table and names the unnamed indexes like MySQL: the name of the first
+// column, with the suffix _2, _3... if the name is used by another index or is
+// PRIMARY. So the indexes can be diffed and dropped by the names SHOW CREATE
+// TABLE returns.
+func normalizeIndexes(table *ast.CreateTableStmt) {
+	columnKeys := []*ast.Constraint{}
+	for _, col := range table.Cols {
+		if !hasOneInOptions(col.Options, ast.ColumnOptionUniqKey) {
+			continue
+		}
+		col.Options = removeColumnOptions(col.Options, ast.ColumnOptionUniqKey)
+		columnKeys = append(columnKeys, &ast.Constraint{
+			Tp:   ast.ConstraintUniq,
+			Keys: []*ast.IndexPartSpecification{{Column: col.Name, Length: types.UnspecifiedLength}},
+		})
+	}
+
+	names := map[string]struct{}{"primary": {}}
+	for _, constraint := range table.Constraints {
+		if isIndexConstraint(constraint) && constraint.Tp != ast.ConstraintPrimaryKey && constraint.Name != "" {
+			names[strings.ToLower(constraint.Name)] = struct{}{}
+		}
+	}
+	for _, constraint := range append(columnKeys, table.Constraints...) {
+		if !isIndexConstraint(constraint) || constraint.Tp == ast.ConstraintPrimaryKey || constraint.Name != "" {
+			continue
+		}
+		constraint.Name = uniqueIndexName(names, indexBaseName(constraint))
+		names[strings.ToLower(constraint.Name)] = struct{}{}
+	}
+	table.Constraints = append(table.Constraints, columnKeys...)
+}
+
+// indexBaseName returns the name of the first column of the index.
+func indexBaseName(constraint *ast.Constraint) string {
+	if len(constraint.Keys) == 0 || constraint.Keys[0].Column == nil {
+		return functionalIndexName
+	}
+	return constraint.Keys[0].Column.Name.O
+}
+
+// uniqueIndexName returns the name, or the name with the first suffix from _2
+// which is not in the used names.
+func uniqueIndexName(names map[string]struct{}, name string) string {
+	if _, ok := names[strings.ToLower(name)]; !ok {
+		return name
+	}
+	for i := 2; ; i++ {
+		newName := name + "_" + strconv.Itoa(i)
+		if _, ok := names[strings.ToLower(newName)]; !ok {
+			return newName
+		}
+	}
+}
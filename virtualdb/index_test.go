@@ -0,0 +1,34 @@
+package virtualdb
+
+import "testing"
+
+func TestIndexName(t *testing.T) {
+	cases := []alterCase{
+		{
+			name:   "name the unnamed indexes like MySQL",
+			input:  "create table t1(a int unique, b int, index(a), unique(b), index(a, b), index primary_idx(b), index((a + b)));",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,INDEX `a_2`(`a`),UNIQUE `b`(`b`),INDEX `a_3`(`a`, `b`),INDEX `primary_idx`(`b`),INDEX `functional_index`((`a`+`b`)),UNIQUE `a`(`a`));\n",
+		},
+		{
+			name:   "name the index of a column named primary",
+			input:  "create table t1(`primary` int, index(`primary`));",
+			expect: "CREATE TABLE `db1`.`t1` (`primary` INT,INDEX `primary_2`(`primary`));\n",
+		},
+		{
+			name:   "add an unnamed index",
+			input:  "create table t1(a int, index(a)); alter table t1 add index(a), add unique key(a);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,INDEX `a`(`a`),INDEX `a_2`(`a`),UNIQUE `a_3`(`a`));\n",
+		},
+		{
+			name:   "drop an unnamed index by its name",
+			input:  "create table t1(a int, b int, index(a), index(a, b)); alter table t1 drop index a_2;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,INDEX `a`(`a`));\n",
+		},
+		{
+			name:   "add a unique column",
+			input:  "create table t1(a int); alter table t1 add column b int unique;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`b` INT,UNIQUE `b`(`b`));\n",
+		},
+	}
+	testAlterCases(t, cases)
+}
@@ -0,0 +1,774 @@
+package virtualdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/tidb/types"
+)
+
+// infoSchemaCatalog is the catalog of all the schemas in information_schema.
+const infoSchemaCatalog = "def"
+
+// columnPrivileges are the privileges shown for every column, as if the user
+// has all the privileges.
+const columnPrivileges = "select,insert,update,references"
+
+// showExprFlags restores the expressions like MySQL shows them, with spaces
+// around the operators and lowercase keywords.
+const showExprFlags = format.RestoreStringSingleQuotes | format.RestoreKeyWordLowercase |
+	format.RestoreNameBackQuotes | format.RestoreSpacesAroundBinaryOperation
+
+// SchemataRow is a row of information_schema.SCHEMATA.
+type SchemataRow struct {
+	CatalogName             string
+	SchemaName              string
+	DefaultCharacterSetName string
+	DefaultCollationName    string
+	DefaultEncryption       string
+}
+
+// TablesRow is a row of information_schema.TABLES, the nil fields are NULL. The
+// statistics, such as TABLE_ROWS and DATA_LENGTH, are not known and omitted.
+type TablesRow struct {
+	TableCatalog   string
+	TableSchema    string
+	TableName      string
+	TableType      string
+	Engine         *string
+	Version        *int64
+	RowFormat      *string
+	AutoIncrement  *uint64
+	TableCollation *string
+	CreateOptions  *string
+	TableComment   string
+}
+
+// ColumnsRow is a row of information_schema.COLUMNS, the nil fields are NULL.
+type ColumnsRow struct {
+	TableCatalog           string
+	TableSchema            string
+	TableName              string
+	ColumnName             string
+	OrdinalPosition        int64
+	ColumnDefault          *string
+	IsNullable             string
+	DataType               string
+	CharacterMaximumLength *int64
+	CharacterOctetLength   *int64
+	NumericPrecision       *int64
+	NumericScale           *int64
+	DatetimePrecision      *int64
+	CharacterSetName       *string
+	CollationName          *string
+	ColumnType             string
+	ColumnKey              string
+	Extra                  string
+	Privileges             string
+	ColumnComment          string
+	GenerationExpression   string
+}
+
+// StatisticsRow is a row of information_schema.STATISTICS, one row for each key
+// part of the indexes. The nil fields are NULL, CARDINALITY is omitted.
+type StatisticsRow struct {
+	TableCatalog string
+	TableSchema  string
+	TableName    string
+	NonUnique    int64
+	IndexSchema  string
+	IndexName    string
+	SeqInIndex   int64
+	ColumnName   *string
+	Collation    *string
+	SubPart      *int64
+	Nullable     string
+	IndexType    string
+	Comment      string
+	IndexComment string
+	IsVisible    string
+	Expression   *string
+}
+
+// KeyColumnUsageRow is a row of information_schema.KEY_COLUMN_USAGE, for the
+// columns of the primary keys, unique keys and foreign keys. The nil fields are NULL.
+type KeyColumnUsageRow struct {
+	ConstraintCatalog          string
+	ConstraintSchema           string
+	ConstraintName             string
+	TableCatalog               string
+	TableSchema                string
+	TableName                  string
+	ColumnName                 string
+	OrdinalPosition            int64
+	PositionInUniqueConstraint *int64
+	ReferencedTableSchema      *string
+	ReferencedTableName        *string
+	ReferencedColumnName       *string
+}
+
+// InformationSchema is a read-only copy of the information_schema tables of a
+// VirtualDB. The rows are ordered by schema and table name, and the columns
+// and indexes of a table are in the order of the definition.
+type InformationSchema struct {
+	Schemata       []SchemataRow
+	Tables         []TablesRow
+	Columns        []ColumnsRow
+	Statistics     []StatisticsRow
+	KeyColumnUsage []KeyColumnUsageRow
+}
+
+// InformationSchema returns the information_schema rows generated from the
+// current schemas, so the tools written against information_schema can run
+// against a SQL file.
+func (c *VirtualDB) InformationSchema() *InformationSchema {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	is := &InformationSchema{}
+	for _, schemaKey := range sortedSchemaNames(c.schemas) {
+		schema := c.schemas[schemaKey]
+		// the unnamed default schema is not a real database.
+		if schemaKey != "" {
+			is.Schemata = append(is.Schemata, c.schemataRow(schema.Schema))
+		}
+
+		for _, name := range sortedTableAndViewNames(schema) {
+			if info, ok := schema.Tables[name]; ok {
+				c.addTableRows(is, schema.Schema, info.Table)
+				continue
+			}
+			c.addViewRows(is, schema.Schema, schema.Views[name].View)
+		}
+	}
+	return is
+}
+
+// sortedTableAndViewNames returns the sorted keys of the tables and views of the schema.
+func sortedTableAndViewNames(schema *SchemaInfo) []string {
+	names := make([]string, 0, len(schema.Tables)+len(schema.Views))
+	for name := range schema.Tables {
+		names = append(names, name)
+	}
+	for name := range schema.Views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *VirtualDB) schemataRow(schema *ast.CreateDatabaseStmt) SchemataRow {
+	charset, collation := SchemaCharset(schema, c.serverVersion)
+	encryption := "NO"
+	for _, op := range schema.Options {
+		if op.Tp == ast.DatabaseOptionEncryption && strings.EqualFold(op.Value, "Y") {
+			encryption = "YES"
+		}
+	}
+	return SchemataRow{
+		CatalogName:             infoSchemaCatalog,
+		SchemaName:              schema.Name,
+		DefaultCharacterSetName: charset,
+		DefaultCollationName:    collation,
+		DefaultEncryption:       encryption,
+	}
+}
+
+// addTableRows adds the rows of a base table.
+func (c *VirtualDB) addTableRows(is *InformationSchema, schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt) {
+	schemaName, tableName := schema.Name, table.Table.Name.O
+	table = NormalizePrimaryKey(table)
+
+	engine, rowFormat, comment := "InnoDB", "Dynamic", ""
+	var autoIncrement *uint64
+	createOptions := []string{}
+	for _, op := range table.Options {
+		switch op.Tp {
+		case ast.TableOptionEngine:
+			engine = engineName(op.StrValue)
+		case ast.TableOptionRowFormat:
+			if name, ok := rowFormatNames[op.UintValue]; ok {
+				rowFormat = name
+				createOptions = append(createOptions, "row_format="+strings.ToUpper(name))
+			}
+		case ast.TableOptionAutoIncrement:
+			value := op.UintValue
+			autoIncrement = &value
+		case ast.TableOptionComment:
+			comment = op.StrValue
+		}
+	}
+	if autoIncrement == nil && hasAutoIncrementColumn(table) {
+		value := uint64(1)
+		autoIncrement = &value
+	}
+	if table.Partition != nil {
+		createOptions = append(createOptions, "partitioned")
+	}
+	_, collation := TableCharset(schema, table, c.serverVersion)
+	version := int64(10)
+	is.Tables = append(is.Tables, TablesRow{
+		TableCatalog:   infoSchemaCatalog,
+		TableSchema:    schemaName,
+		TableName:      tableName,
+		TableType:      "BASE TABLE",
+		Engine:         &engine,
+		Version:        &version,
+		RowFormat:      &rowFormat,
+		AutoIncrement:  autoIncrement,
+		TableCollation: &collation,
+		CreateOptions:  stringPtr(strings.Join(createOptions, " ")),
+		TableComment:   comment,
+	})
+
+	for i, col := range table.Cols {
+		is.Columns = append(is.Columns, c.columnsRow(schema, table, col, i+1, columnKey(table, col)))
+	}
+
+	for _, index := range tableIndexes(table) {
+		c.addIndexRows(is, schemaName, table, index)
+	}
+	for _, constraint := range table.Constraints {
+		if constraint.Tp == ast.ConstraintForeignKey {
+			c.addForeignKeyRows(is, schemaName, table, constraint)
+		}
+	}
+}
+
+// addViewRows adds the rows of a view, the types of the columns are resolved
+// like CREATE TABLE ... SELECT.
+func (c *VirtualDB) addViewRows(is *InformationSchema, schema *ast.CreateDatabaseStmt, view *ast.CreateViewStmt) {
+	schemaName, viewName := schema.Name, view.ViewName.Name.O
+	is.Tables = append(is.Tables, TablesRow{
+		TableCatalog: infoSchemaCatalog,
+		TableSchema:  schemaName,
+		TableName:    viewName,
+		TableType:    "VIEW",
+		TableComment: "VIEW",
+	})
+
+	resolver := &columnResolver{c, schemaName, viewName}
+	cols, err := resolver.resultColumns(view.Select)
+	if err != nil {
+		return
+	}
+	table := &ast.CreateTableStmt{Table: view.ViewName}
+	for i, col := range cols {
+		if i < len(view.Cols) {
+			col.Name = &ast.ColumnName{Name: view.Cols[i]}
+		}
+		is.Columns = append(is.Columns, c.columnsRow(schema, table, col, i+1, ""))
+	}
+}
+
+func (c *VirtualDB) columnsRow(schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt, col *ast.ColumnDef,
+	position int, key string) ColumnsRow {
+
+	tp := NormalizeColumn(col, FlavorMySQL, c.serverVersion).Tp
+	row := ColumnsRow{
+		TableCatalog:    infoSchemaCatalog,
+		TableSchema:     schema.Name,
+		TableName:       table.Table.Name.O,
+		ColumnName:      col.Name.Name.O,
+		OrdinalPosition: int64(position),
+		IsNullable:      "YES",
+		ColumnKey:       key,
+		Privileges:      columnPrivileges,
+	}
+
+	charset := ""
+	if isStringType(tp) && tp.Charset != "binary" && !mysql.HasBinaryFlag(tp.Flag) {
+		var collation string
+		charset, collation = ColumnCharset(schema, table, col, c.serverVersion)
+		row.CharacterSetName, row.CollationName = &charset, &collation
+	} else if isStringType(tp) {
+		charset = "binary"
+	}
+	row.DataType = types.TypeToStr(tp.Tp, charset)
+	row.ColumnType = columnTypeString(tp, charset)
+	setColumnLengths(&row, tp, charset)
+
+	extra := []string{}
+	for _, op := range col.Options {
+		switch op.Tp {
+		case ast.ColumnOptionNotNull, ast.ColumnOptionPrimaryKey:
+			row.IsNullable = "NO"
+		case ast.ColumnOptionAutoIncrement:
+			extra = append(extra, "auto_increment")
+		case ast.ColumnOptionDefaultValue:
+			value, isNull, ok := defaultLiteral(op.Expr)
+			switch {
+			case isNull:
+			case ok:
+				row.ColumnDefault = &value
+			default:
+				value = defaultExprText(op.Expr)
+				row.ColumnDefault = &value
+				extra = append(extra, "DEFAULT_GENERATED")
+			}
+		case ast.ColumnOptionOnUpdate:
+			extra = append(extra, "on update "+defaultExprText(op.Expr))
+		case ast.ColumnOptionComment:
+			row.ColumnComment, _ = defaultLiteralString(op.Expr)
+		case ast.ColumnOptionGenerated:
+			row.GenerationExpression = showExpr(op.Expr)
+			if op.Stored {
+				extra = append(extra, "STORED GENERATED")
+			} else {
+				extra = append(extra, "VIRTUAL GENERATED")
+			}
+		}
+	}
+	row.Extra = strings.Join(extra, " ")
+	return row
+}
+
+// addForeignKeyRows adds the rows of KEY_COLUMN_USAGE of a foreign key.
+func (c *VirtualDB) addForeignKeyRows(is *InformationSchema, schemaName string, table *ast.CreateTableStmt,
+	constraint *ast.Constraint) {
+
+	if constraint.Refer == nil {
+		return
+	}
+	tableName := table.Table.Name.O
+	refSchema := constraint.Refer.Table.Schema.O
+	if refSchema == "" {
+		refSchema = schemaName
+	}
+	for i, key := range constraint.Keys {
+		if key.Column == nil || i >= len(constraint.Refer.IndexPartSpecifications) {
+			continue
+		}
+		position := int64(i + 1)
+		refTable := constraint.Refer.Table.Name.O
+		refColumn := constraint.Refer.IndexPartSpecifications[i].Column.Name.O
+		is.KeyColumnUsage = append(is.KeyColumnUsage, KeyColumnUsageRow{
+			ConstraintCatalog:          infoSchemaCatalog,
+			ConstraintSchema:           schemaName,
+			ConstraintName:             foreignKeyName(table, constraint),
+			TableCatalog:               infoSchemaCatalog,
+			TableSchema:                schemaName,
+			TableName:                  tableName,
+			ColumnName:                 key.Column.Name.O,
+			OrdinalPosition:            position,
+			PositionInUniqueConstraint: &position,
+			ReferencedTableSchema:      &refSchema,
+			ReferencedTableName:        &refTable,
+			ReferencedColumnName:       &refColumn,
+		})
+	}
+}
+
+// addIndexRows adds the rows of STATISTICS of an index, and the rows of
+// KEY_COLUMN_USAGE if it is unique.
+func (c *VirtualDB) addIndexRows(is *InformationSchema, schemaName string, table *ast.CreateTableStmt,
+	constraint *ast.Constraint) {
+
+	tableName := table.Table.Name.O
+	indexName := getIndexName(constraint)
+	unique := isUniqueConstraint(constraint)
+	indexType := "BTREE"
+	indexComment, visible := "", "YES"
+	if constraint.Tp == ast.ConstraintFulltext {
+		indexType = "FULLTEXT"
+	}
+	if op := constraint.Option; op != nil {
+		if op.Tp != model.IndexTypeInvalid {
+			indexType = strings.ToUpper(op.Tp.String())
+		}
+		indexComment = op.Comment
+		if op.Visibility == ast.IndexVisibilityInvisible {
+			visible = "NO"
+		}
+	}
+
+	for i, key := range constraint.Keys {
+		row := StatisticsRow{
+			TableCatalog: infoSchemaCatalog,
+			TableSchema:  schemaName,
+			TableName:    tableName,
+			NonUnique:    1,
+			IndexSchema:  schemaName,
+			IndexName:    indexName,
+			SeqInIndex:   int64(i + 1),
+			IndexType:    indexType,
+			IndexComment: indexComment,
+			IsVisible:    visible,
+		}
+		if unique {
+			row.NonUnique = 0
+		}
+		if constraint.Tp != ast.ConstraintFulltext {
+			row.Collation = stringPtr("A")
+		}
+		if key.Length > 0 {
+			length := int64(key.Length)
+			row.SubPart = &length
+		}
+		if key.Column == nil {
+			expr := showExpr(key.Expr)
+			row.Expression = &expr
+			row.Nullable = "YES"
+		} else {
+			name := key.Column.Name.O
+			row.ColumnName = &name
+			if col := findColumn(table.Cols, key.Column.Name.L); col != nil &&
+				!hasOneInOptions(col.Options, ast.ColumnOptionNotNull, ast.ColumnOptionPrimaryKey) {
+				row.Nullable = "YES"
+			}
+		}
+		is.Statistics = append(is.Statistics, row)
+
+		if unique && key.Column != nil {
+			is.KeyColumnUsage = append(is.KeyColumnUsage, KeyColumnUsageRow{
+				ConstraintCatalog: infoSchemaCatalog,
+				ConstraintSchema:  schemaName,
+				ConstraintName:    indexName,
+				TableCatalog:      infoSchemaCatalog,
+				TableSchema:       schemaName,
+				TableName:         tableName,
+				ColumnName:        key.Column.Name.O,
+				OrdinalPosition:   int64(i + 1),
+			})
+		}
+	}
+}
+
+// rowFormatNames are the names of the row formats shown by MySQL.
+var rowFormatNames = map[uint64]string{
+	ast.RowFormatDynamic:    "Dynamic",
+	ast.RowFormatFixed:      "Fixed",
+	ast.RowFormatCompressed: "Compressed",
+	ast.RowFormatRedundant:  "Redundant",
+	ast.RowFormatCompact:    "Compact",
+}
+
+// engineNames are the names of the storage engines shown by MySQL, keyed by
+// the lowercase names and aliases.
+var engineNames = map[string]string{
+	"innodb":             "InnoDB",
+	"myisam":             "MyISAM",
+	"memory":             "MEMORY",
+	"heap":               "MEMORY",
+	"csv":                "CSV",
+	"archive":            "ARCHIVE",
+	"blackhole":          "BLACKHOLE",
+	"merge":              "MRG_MYISAM",
+	"mrg_myisam":         "MRG_MYISAM",
+	"federated":          "FEDERATED",
+	"example":            "EXAMPLE",
+	"ndb":                "ndbcluster",
+	"ndbcluster":         "ndbcluster",
+	"performance_schema": "PERFORMANCE_SCHEMA",
+}
+
+// engineName returns the name of the storage engine as MySQL shows it, the
+// unknown engines are kept as written.
+func engineName(name string) string {
+	if engine, ok := engineNames[strings.ToLower(name)]; ok {
+		return engine
+	}
+	return name
+}
+
+// textLengths are the max lengths of the text and blob types.
+var textLengths = map[byte]int64{
+	mysql.TypeTinyBlob:   255,
+	mysql.TypeBlob:       65535,
+	mysql.TypeMediumBlob: 16777215,
+	mysql.TypeLongBlob:   4294967295,
+}
+
+// integerPrecisions are the numeric precisions of the integer types, signed and unsigned.
+var integerPrecisions = map[byte][2]int64{
+	mysql.TypeTiny:     {3, 3},
+	mysql.TypeShort:    {5, 5},
+	mysql.TypeInt24:    {7, 8},
+	mysql.TypeLong:     {10, 10},
+	mysql.TypeLonglong: {19, 20},
+}
+
+func isUniqueConstraint(constraint *ast.Constraint) bool {
+	switch constraint.Tp {
+	case ast.ConstraintPrimaryKey, ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+		return true
+	}
+	return false
+}
+
+func isStringType(tp *types.FieldType) bool {
+	return types.IsString(tp.Tp) || tp.Tp == mysql.TypeEnum || tp.Tp == mysql.TypeSet
+}
+
+func hasAutoIncrementColumn(table *ast.CreateTableStmt) bool {
+	for _, col := range table.Cols {
+		if hasOneInOptions(col.Options, ast.ColumnOptionAutoIncrement) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnKey returns COLUMN_KEY of the column: PRI for the primary key, UNI for
+// the first column of a unique key, MUL for the first column of the other indexes.
+func columnKey(table *ast.CreateTableStmt, col *ast.ColumnDef) string {
+	key := ""
+	for _, constraint := range table.Constraints {
+		if !isIndexConstraint(constraint) && constraint.Tp != ast.ConstraintForeignKey {
+			continue
+		}
+		if len(constraint.Keys) == 0 || constraint.Keys[0].Column == nil ||
+			constraint.Keys[0].Column.Name.L != col.Name.Name.L {
+			if constraint.Tp == ast.ConstraintPrimaryKey && hasKeyPart(constraint, col.Name.Name.L) {
+				return "PRI"
+			}
+			continue
+		}
+		switch {
+		case constraint.Tp == ast.ConstraintPrimaryKey:
+			return "PRI"
+		case isUniqueConstraint(constraint) && len(constraint.Keys) == 1:
+			key = "UNI"
+		case key == "":
+			key = "MUL"
+		}
+	}
+	return key
+}
+
+// tableIndexes returns the indexes of the table in the order of MySQL: the
+// primary key, the unique keys without and with nullable columns, the other
+// keys and the fulltext keys, with the indexes MySQL creates for the foreign
+// keys which no index starts with the columns of.
+func tableIndexes(table *ast.CreateTableStmt) []*ast.Constraint {
+	indexes := []*ast.Constraint{}
+	for _, constraint := range table.Constraints {
+		if isIndexConstraint(constraint) {
+			indexes = append(indexes, constraint)
+		}
+	}
+	for _, constraint := range table.Constraints {
+		if constraint.Tp == ast.ConstraintForeignKey && !hasIndexPrefix(indexes, constraint.Keys) {
+			indexes = append(indexes, &ast.Constraint{
+				Tp:   ast.ConstraintIndex,
+				Name: getIndexName(constraint),
+				Keys: constraint.Keys,
+			})
+		}
+	}
+
+	rank := func(index *ast.Constraint) int {
+		switch {
+		case index.Tp == ast.ConstraintPrimaryKey:
+			return 0
+		case isUniqueConstraint(index) && !hasNullableKeyPart(table, index):
+			return 1
+		case isUniqueConstraint(index):
+			return 2
+		case index.Tp == ast.ConstraintFulltext:
+			return 4
+		}
+		return 3
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return rank(indexes[i]) < rank(indexes[j])
+	})
+	return indexes
+}
+
+func hasNullableKeyPart(table *ast.CreateTableStmt, index *ast.Constraint) bool {
+	for _, key := range index.Keys {
+		if key.Column == nil {
+			return true
+		}
+		col := findColumn(table.Cols, key.Column.Name.L)
+		if col == nil || !hasOneInOptions(col.Options, ast.ColumnOptionNotNull, ast.ColumnOptionPrimaryKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignKeyName returns the name of the foreign key, the unnamed foreign keys
+// are named <table>_ibfk_<n> like MySQL.
+func foreignKeyName(table *ast.CreateTableStmt, constraint *ast.Constraint) string {
+	if constraint.Name != "" {
+		return constraint.Name
+	}
+	n := 0
+	for _, fk := range table.Constraints {
+		if fk.Tp == ast.ConstraintForeignKey && fk.Name == "" {
+			n++
+		}
+		if fk == constraint {
+			break
+		}
+	}
+	return fmt.Sprintf("%s_ibfk_%d", table.Table.Name.O, n)
+}
+
+// hasIndexPrefix returns whether one of the indexes starts with the keys.
+func hasIndexPrefix(indexes []*ast.Constraint, keys []*ast.IndexPartSpecification) bool {
+	for _, constraint := range indexes {
+		if constraint.Tp == ast.ConstraintFulltext ||
+			len(constraint.Keys) < len(keys) {
+			continue
+		}
+		prefix := true
+		for i, key := range keys {
+			indexKey := constraint.Keys[i]
+			if key.Column == nil || indexKey.Column == nil || indexKey.Column.Name.L != key.Column.Name.L {
+				prefix = false
+				break
+			}
+		}
+		if prefix {
+			return true
+		}
+	}
+	return false
+}
+
+func hasKeyPart(constraint *ast.Constraint, columnName string) bool {
+	for _, key := range constraint.Keys {
+		if key.Column != nil && key.Column.Name.L == columnName {
+			return true
+		}
+	}
+	return false
+}
+
+// columnTypeString returns COLUMN_TYPE, the integers without display width are
+// shown without it like MySQL 8.0.19.
+func columnTypeString(tp *types.FieldType, charset string) string {
+	ft := *tp
+	ft.Charset = charset
+	s := ft.InfoSchemaStr()
+	switch {
+	case mysql.IsIntegerType(ft.Tp) && ft.Flen == types.UnspecifiedLength,
+		ft.Tp == mysql.TypeYear:
+		s = types.TypeToStr(ft.Tp, charset)
+		if mysql.HasUnsignedFlag(ft.Flag) {
+			s += " unsigned"
+		}
+	}
+	if mysql.HasZerofillFlag(ft.Flag) {
+		s += " zerofill"
+	}
+	return s
+}
+
+// setColumnLengths sets the character lengths, numeric precision and scale and
+// datetime precision of the column.
+func setColumnLengths(row *ColumnsRow, tp *types.FieldType, charset string) {
+	int64Ptr := func(v int64) *int64 { return &v }
+	switch {
+	case tp.Tp == mysql.TypeEnum || tp.Tp == mysql.TypeSet:
+		length := 0
+		for _, e := range tp.Elems {
+			if tp.Tp == mysql.TypeEnum && len(e) > length {
+				length = len(e)
+			}
+			if tp.Tp == mysql.TypeSet {
+				length += len(e) + 1
+			}
+		}
+		if tp.Tp == mysql.TypeSet && length > 0 {
+			length--
+		}
+		row.CharacterMaximumLength = int64Ptr(int64(length))
+		row.CharacterOctetLength = int64Ptr(int64(length * getCharsetMaxLen(charset)))
+	case types.IsTypeBlob(tp.Tp):
+		row.CharacterMaximumLength = int64Ptr(textLengths[tp.Tp])
+		row.CharacterOctetLength = int64Ptr(textLengths[tp.Tp])
+	case types.IsString(tp.Tp):
+		length := tp.Flen
+		if length == types.UnspecifiedLength {
+			length = 1
+		}
+		row.CharacterMaximumLength = int64Ptr(int64(length))
+		row.CharacterOctetLength = int64Ptr(int64(length * getCharsetMaxLen(charset)))
+	case mysql.IsIntegerType(tp.Tp):
+		precisions := integerPrecisions[tp.Tp]
+		precision := precisions[0]
+		if mysql.HasUnsignedFlag(tp.Flag) {
+			precision = precisions[1]
+		}
+		row.NumericPrecision, row.NumericScale = int64Ptr(precision), int64Ptr(0)
+	case tp.Tp == mysql.TypeNewDecimal:
+		row.NumericPrecision, row.NumericScale = int64Ptr(int64(tp.Flen)), int64Ptr(int64(tp.Decimal))
+	case tp.Tp == mysql.TypeFloat || tp.Tp == mysql.TypeDouble:
+		precision := int64(12)
+		if tp.Tp == mysql.TypeDouble {
+			precision = 22
+		}
+		if tp.Flen > 0 {
+			precision = int64(tp.Flen)
+		}
+		row.NumericPrecision = int64Ptr(precision)
+		if tp.Decimal > 0 {
+			row.NumericScale = int64Ptr(int64(tp.Decimal))
+		}
+	case tp.Tp == mysql.TypeBit:
+		row.NumericPrecision = int64Ptr(int64(tp.Flen))
+	case tp.Tp == mysql.TypeDatetime || tp.Tp == mysql.TypeTimestamp || tp.Tp == mysql.TypeDuration:
+		precision := int64(0)
+		if tp.Decimal > 0 {
+			precision = int64(tp.Decimal)
+		}
+		row.DatetimePrecision = int64Ptr(precision)
+	}
+}
+
+// defaultExprText returns the text of a default value which is an expression,
+// NOW() and its synonyms are CURRENT_TIMESTAMP.
+func defaultExprText(expr ast.ExprNode) string {
+	if fn, ok := expr.(*ast.FuncCallExpr); ok {
+		switch fn.FnName.L {
+		case ast.CurrentTimestamp, ast.Now, ast.LocalTime, ast.LocalTimestamp:
+			text := "CURRENT_TIMESTAMP"
+			if len(fn.Args) != 0 {
+				precision, _ := restoreToSql(fn.Args[0])
+				text += "(" + precision + ")"
+			}
+			return text
+		}
+	}
+	text, _ := restoreToSql(expr)
+	return text
+}
+
+// defaultLiteralString returns the string of a literal.
+func defaultLiteralString(expr ast.ExprNode) (string, bool) {
+	value, isNull, ok := defaultLiteral(expr)
+	return value, ok && !isNull
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// showExpr returns the expression like MySQL shows it, the operations are in
+// parentheses, such as (`a` + 1).
+func showExpr(expr ast.ExprNode) string {
+	switch e := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return showExpr(e.Expr)
+	case *ast.BinaryOperationExpr:
+		var sb strings.Builder
+		if err := e.Op.Restore(format.NewRestoreCtx(showExprFlags, &sb)); err != nil {
+			return ""
+		}
+		return fmt.Sprintf("(%s %s %s)", showExpr(e.L), sb.String(), showExpr(e.R))
+	}
+	var sb strings.Builder
+	if err := expr.Restore(format.NewRestoreCtx(showExprFlags, &sb)); err != nil {
+		return ""
+	}
+	return sb.String()
+}
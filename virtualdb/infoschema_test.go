@@ -0,0 +1,166 @@
+package virtualdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newInformationSchema(t *testing.T, sql string) *InformationSchema {
+	vb := NewVirtualDBWithOpt("", DBOption{ServerVersion: 80040})
+	if err := vb.ExecSQL(sql); err != nil {
+		t.Fatal(err)
+	}
+	return vb.InformationSchema()
+}
+
+func TestInformationSchemaTables(t *testing.T) {
+	is := newInformationSchema(t, "create database db1 default charset latin1; use db1;"+
+		"create table t1(id int auto_increment primary key) row_format=compact comment 'c1';"+
+		"create table t0(a int) engine=myisam default charset utf8 partition by hash(a) partitions 2;"+
+		"create view v1 as select id from t1;")
+
+	assert.Equal(t, []SchemataRow{{
+		CatalogName:             "def",
+		SchemaName:              "db1",
+		DefaultCharacterSetName: "latin1",
+		DefaultCollationName:    "latin1_swedish_ci",
+		DefaultEncryption:       "NO",
+	}}, is.Schemata)
+
+	if assert.Len(t, is.Tables, 3) {
+		t0, t1, v1 := is.Tables[0], is.Tables[1], is.Tables[2]
+		assert.Equal(t, "t0", t0.TableName)
+		assert.Equal(t, "MyISAM", *t0.Engine)
+		assert.Equal(t, "utf8_general_ci", *t0.TableCollation)
+		assert.Equal(t, "partitioned", *t0.CreateOptions)
+		assert.Nil(t, t0.AutoIncrement)
+
+		assert.Equal(t, "BASE TABLE", t1.TableType)
+		assert.Equal(t, "InnoDB", *t1.Engine)
+		assert.Equal(t, "Compact", *t1.RowFormat)
+		assert.Equal(t, "row_format=COMPACT", *t1.CreateOptions)
+		assert.Equal(t, uint64(1), *t1.AutoIncrement)
+		assert.Equal(t, "latin1_swedish_ci", *t1.TableCollation)
+		assert.Equal(t, "c1", t1.TableComment)
+
+		assert.Equal(t, "VIEW", v1.TableType)
+		assert.Nil(t, v1.Engine)
+		assert.Equal(t, "VIEW", v1.TableComment)
+	}
+}
+
+func TestInformationSchemaColumns(t *testing.T) {
+	is := newInformationSchema(t, "create database db1; use db1;"+
+		"create table t1(id bigint unsigned auto_increment, name varchar(20) not null default 'x' comment 'n', "+
+		"price decimal(10,2), b blob, e enum('a','bc'), ts timestamp(3) default current_timestamp(3) on update current_timestamp(3), "+
+		"n int as (id + 1), primary key(id), unique key(name), key(price));")
+
+	if !assert.Len(t, is.Columns, 7) {
+		return
+	}
+	id, name, price, b, e, ts, n := is.Columns[0], is.Columns[1], is.Columns[2], is.Columns[3],
+		is.Columns[4], is.Columns[5], is.Columns[6]
+
+	assert.Equal(t, "bigint", id.DataType)
+	assert.Equal(t, "bigint unsigned", id.ColumnType)
+	assert.Equal(t, "NO", id.IsNullable)
+	assert.Equal(t, "PRI", id.ColumnKey)
+	assert.Equal(t, "auto_increment", id.Extra)
+	assert.Equal(t, int64(20), *id.NumericPrecision)
+	assert.Nil(t, id.CharacterSetName)
+
+	assert.Equal(t, "varchar(20)", name.ColumnType)
+	assert.Equal(t, "x", *name.ColumnDefault)
+	assert.Equal(t, int64(20), *name.CharacterMaximumLength)
+	assert.Equal(t, int64(80), *name.CharacterOctetLength)
+	assert.Equal(t, "utf8mb4", *name.CharacterSetName)
+	assert.Equal(t, "utf8mb4_0900_ai_ci", *name.CollationName)
+	assert.Equal(t, "UNI", name.ColumnKey)
+	assert.Equal(t, "n", name.ColumnComment)
+
+	assert.Equal(t, "decimal(10,2)", price.ColumnType)
+	assert.Equal(t, int64(10), *price.NumericPrecision)
+	assert.Equal(t, int64(2), *price.NumericScale)
+	assert.Equal(t, "YES", price.IsNullable)
+	assert.Equal(t, "MUL", price.ColumnKey)
+	assert.Nil(t, price.ColumnDefault)
+
+	assert.Equal(t, "blob", b.DataType)
+	assert.Equal(t, int64(65535), *b.CharacterMaximumLength)
+
+	assert.Equal(t, "enum('a','bc')", e.ColumnType)
+	assert.Equal(t, int64(2), *e.CharacterMaximumLength)
+
+	assert.Equal(t, "timestamp(3)", ts.ColumnType)
+	assert.Equal(t, int64(3), *ts.DatetimePrecision)
+	assert.Equal(t, "CURRENT_TIMESTAMP(3)", *ts.ColumnDefault)
+	assert.Equal(t, "DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)", ts.Extra)
+
+	assert.Equal(t, "int", n.ColumnType)
+	assert.Equal(t, "VIRTUAL GENERATED", n.Extra)
+	assert.Equal(t, "(`id` + 1)", n.GenerationExpression)
+}
+
+func TestInformationSchemaIndexes(t *testing.T) {
+	is := newInformationSchema(t, "create database db1; use db1;"+
+		"create table t1(a int, b varchar(20), c int, primary key(a), unique key uk(b(10), c), "+
+		"fulltext key ft(b), key idx((c + 1)) invisible);"+
+		"create table t2(id int, a int, constraint fk foreign key (a) references t1 (a));")
+
+	if assert.Len(t, is.Statistics, 6) {
+		pk, uk1, uk2, idx, ft, fk := is.Statistics[0], is.Statistics[1], is.Statistics[2],
+			is.Statistics[3], is.Statistics[4], is.Statistics[5]
+		assert.Equal(t, "PRIMARY", pk.IndexName)
+		assert.Equal(t, int64(0), pk.NonUnique)
+		assert.Equal(t, "", pk.Nullable)
+
+		assert.Equal(t, "uk", uk1.IndexName)
+		assert.Equal(t, int64(10), *uk1.SubPart)
+		assert.Equal(t, "YES", uk1.Nullable)
+		assert.Equal(t, int64(2), uk2.SeqInIndex)
+		assert.Equal(t, "c", *uk2.ColumnName)
+
+		assert.Equal(t, "FULLTEXT", ft.IndexType)
+		assert.Nil(t, ft.Collation)
+
+		assert.Equal(t, "idx", idx.IndexName)
+		assert.Nil(t, idx.ColumnName)
+		assert.Equal(t, "(`c` + 1)", *idx.Expression)
+		assert.Equal(t, "NO", idx.IsVisible)
+
+		assert.Equal(t, "t2", fk.TableName)
+		assert.Equal(t, "fk", fk.IndexName)
+		assert.Equal(t, int64(1), fk.NonUnique)
+	}
+
+	if assert.Len(t, is.KeyColumnUsage, 4) {
+		assert.Equal(t, "PRIMARY", is.KeyColumnUsage[0].ConstraintName)
+		assert.Equal(t, "uk", is.KeyColumnUsage[2].ConstraintName)
+		assert.Equal(t, int64(2), is.KeyColumnUsage[2].OrdinalPosition)
+
+		fk := is.KeyColumnUsage[3]
+		assert.Equal(t, "fk", fk.ConstraintName)
+		assert.Equal(t, "a", fk.ColumnName)
+		assert.Equal(t, int64(1), *fk.PositionInUniqueConstraint)
+		assert.Equal(t, "db1", *fk.ReferencedTableSchema)
+		assert.Equal(t, "t1", *fk.ReferencedTableName)
+		assert.Equal(t, "a", *fk.ReferencedColumnName)
+	}
+}
+
+func TestInformationSchemaViewColumns(t *testing.T) {
+	is := newInformationSchema(t, "create database db1; use db1;"+
+		"create table t1(id int not null, a varchar(10));"+
+		"create view v1 (x, y) as select id, a from t1;")
+
+	if assert.Len(t, is.Columns, 4) {
+		x, y := is.Columns[2], is.Columns[3]
+		assert.Equal(t, "v1", x.TableName)
+		assert.Equal(t, "x", x.ColumnName)
+		assert.Equal(t, "NO", x.IsNullable)
+		assert.Equal(t, "y", y.ColumnName)
+		assert.Equal(t, "varchar(10)", y.ColumnType)
+		assert.Equal(t, int64(2), y.OrdinalPosition)
+	}
+}
@@ -0,0 +1,77 @@
+package virtualdb
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDump = "-- MySQL dump 10.13  Distrib 5.7.44, for Linux (x86_64)\n" +
+	"/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n" +
+	"/*!40101 SET NAMES utf8mb4 */;\n" +
+	"/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n" +
+	"SET FOREIGN_KEY_CHECKS=0;\n" +
+	"\n" +
+	"CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop` /*!40100 DEFAULT CHARACTER SET utf8mb4 */ /*!80016 DEFAULT ENCRYPTION='N' */;\n" +
+	"USE `shop`;\n" +
+	"DROP TABLE IF EXISTS `t1`;\n" +
+	"/*!40101 SET @saved_cs_client     = @@character_set_client */;\n" +
+	"CREATE TABLE `t1` (\n" +
+	"  `id` int(11) NOT NULL AUTO_INCREMENT,\n" +
+	"  `name` varchar(20) DEFAULT NULL,\n" +
+	"  PRIMARY KEY (`id`)\n" +
+	") ENGINE=InnoDB AUTO_INCREMENT=3 DEFAULT CHARSET=utf8mb4 /*!80016 COMMENT='new' */;\n" +
+	"/*!40101 SET character_set_client = @saved_cs_client */;\n" +
+	"\n" +
+	"LOCK TABLES `t1` WRITE;\n" +
+	"/*!40000 ALTER TABLE `t1` DISABLE KEYS */;\n" +
+	"INSERT INTO `t1` VALUES (1,'a;b'),(2,'it''s');\n" +
+	"/*!40000 ALTER TABLE `t1` ENABLE KEYS */;\n" +
+	"UNLOCK TABLES;\n" +
+	"/*!50003 SET @saved_sql_mode       = @@sql_mode */ ;\n" +
+	"DELIMITER ;;\n" +
+	"/*!50003 CREATE*/ /*!50017 DEFINER=`root`@`%`*/ /*!50003 TRIGGER `tr` BEFORE INSERT ON `t1` FOR EACH ROW BEGIN\n" +
+	"  SET NEW.name = 'x';\n" +
+	"END */;;\n" +
+	"DELIMITER ;\n" +
+	"/*!50003 SET sql_mode              = @saved_sql_mode */ ;\n"
+
+const testDumpTrigger = "DELIMITER ;;\nCREATE DEFINER=`root`@`%` TRIGGER `shop`.`tr` BEFORE INSERT ON `shop`.`t1` FOR EACH ROW BEGIN\n  SET NEW.name = 'x';\nEND;;\nDELIMITER ;\n"
+
+func TestExecDump(t *testing.T) {
+	vb := NewVirtualDB("")
+	assert.NoError(t, vb.ExecDump(testDump, ExecOption{ServerVersion: 50744}))
+	assertText(t, vb, "CREATE DATABASE IF NOT EXISTS `shop` CHARACTER SET = utf8mb4;\n"+
+		"CREATE TABLE `shop`.`t1` (`id` INT(11) NOT NULL AUTO_INCREMENT,`name` VARCHAR(20) DEFAULT NULL,PRIMARY KEY(`id`)) ENGINE = InnoDB AUTO_INCREMENT = 3 DEFAULT CHARACTER SET = UTF8MB4;\n"+
+		testDumpTrigger)
+
+	vb = NewVirtualDB("")
+	assert.NoError(t, vb.ExecDump(testDump, ExecOption{ServerVersion: 80023}))
+	assertText(t, vb, "CREATE DATABASE IF NOT EXISTS `shop` CHARACTER SET = utf8mb4 ENCRYPTION = 'N';\n"+
+		"CREATE TABLE `shop`.`t1` (`id` INT(11) NOT NULL AUTO_INCREMENT,`name` VARCHAR(20) DEFAULT NULL,PRIMARY KEY(`id`)) ENGINE = InnoDB AUTO_INCREMENT = 3 DEFAULT CHARACTER SET = UTF8MB4 COMMENT = 'new';\n"+
+		testDumpTrigger)
+}
+
+func TestExecDumpError(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	err := vb.ExecDump("INSERT INTO t9 VALUES (1);\nDELIMITER //\nALTER TABLE t9 ADD COLUMN b INT//\n", ExecOption{})
+	assert.EqualError(t, err, "statement #2 at line 3, column 1 (ALTER TABLE t9 ADD COLUMN b INT): not exist table: db1.t9")
+
+	err = vb.ExecDump("create table t1(a int); create table t1(a int); create table t2(a int); create table t2(a int);",
+		ExecOption{ContinueOnError: true})
+	var errs ExecErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Equal(t, 2, len(errs))
+}
+
+func TestExecReader(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	reader := io.MultiReader(strings.NewReader("create table t1(a int);\nINSERT INTO t1 VALUES "),
+		&valuesReader{rows: 100000})
+	assert.NoError(t, vb.ExecReader(reader, ExecOption{}))
+	assertText(t, vb, "CREATE DATABASE `db1`;\n"+
+		"CREATE TABLE `db1`.`t1` (`a` INT);\n"+
+		"CREATE TABLE `db1`.`t2` (`a` INT);\n")
+}
@@ -0,0 +1,192 @@
+package virtualdb
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/opcode"
+	"github.com/pingcap/tidb/types"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+)
+
+// integerDisplayWidths is the default display width of the integer types, signed and unsigned.
+var integerDisplayWidths = map[byte][2]int{
+	mysql.TypeTiny:     {4, 3},
+	mysql.TypeShort:    {6, 5},
+	mysql.TypeInt24:    {9, 8},
+	mysql.TypeLong:     {11, 10},
+	mysql.TypeLonglong: {20, 20},
+}
+
+// NormalizeColumn returns a copy of the column in the canonical form of the
+// server, so that the equivalent definitions, such as INT(11) and INT on MySQL
+// 8.0.19, DECIMAL and DECIMAL(10,0), DEFAULT NULL and no default on nullable
+// columns, DEFAULT '0' and DEFAULT 0, restore to the same SQL. The synonyms,
+// such as INTEGER and BOOL, are resolved by the parser already.
+func NormalizeColumn(col *ast.ColumnDef, flavor Flavor, serverVersion int) *ast.ColumnDef {
+	if col.Tp == nil {
+		return col
+	}
+
+	tp := *col.Tp
+	switch {
+	case mysql.IsIntegerType(tp.Tp):
+		if mysql.HasZerofillFlag(tp.Flag) {
+			tp.Flag |= mysql.UnsignedFlag
+		}
+		// MySQL 8.0.19 deprecates the display width, which is only kept for
+		// ZEROFILL and TINYINT(1).
+		if flavor == FlavorMySQL && serverVersion >= 80019 {
+			if !mysql.HasZerofillFlag(tp.Flag) && !(tp.Tp == mysql.TypeTiny && tp.Flen == 1) {
+				tp.Flen = types.UnspecifiedLength
+			}
+		} else if tp.Flen == types.UnspecifiedLength {
+			widths := integerDisplayWidths[tp.Tp]
+			tp.Flen = widths[0]
+			if mysql.HasUnsignedFlag(tp.Flag) {
+				tp.Flen = widths[1]
+			}
+		}
+	case tp.Tp == mysql.TypeNewDecimal:
+		if tp.Flen == types.UnspecifiedLength {
+			tp.Flen = 10
+		}
+		if tp.Decimal == types.UnspecifiedLength {
+			tp.Decimal = 0
+		}
+	case tp.Tp == mysql.TypeString || tp.Tp == mysql.TypeBit:
+		if tp.Flen == types.UnspecifiedLength {
+			tp.Flen = 1
+		}
+	case tp.Tp == mysql.TypeYear:
+		tp.Flen = types.UnspecifiedLength
+	case tp.Tp == mysql.TypeDatetime || tp.Tp == mysql.TypeTimestamp || tp.Tp == mysql.TypeDuration:
+		if tp.Decimal == types.UnspecifiedLength {
+			tp.Decimal = 0
+		}
+	}
+
+	nullable := !hasOneInOptions(col.Options, ast.ColumnOptionNotNull, ast.ColumnOptionPrimaryKey)
+	options := []*ast.ColumnOption{}
+	for _, op := range col.Options {
+		switch op.Tp {
+		case ast.ColumnOptionNull:
+			continue
+		case ast.ColumnOptionDefaultValue:
+			value, isNull, ok := defaultLiteral(op.Expr)
+			if !ok {
+				break
+			}
+			if isNull {
+				if nullable {
+					continue
+				}
+				break
+			}
+			op = &ast.ColumnOption{Tp: op.Tp, Expr: ast.NewValueExpr(normalizeDefault(&tp, value), "", "")}
+		}
+		options = append(options, op)
+	}
+
+	return &ast.ColumnDef{Name: col.Name, Tp: &tp, Options: options}
+}
+
+// defaultLiteral returns the string of a literal default value.
+func defaultLiteral(expr ast.ExprNode) (value string, isNull bool, ok bool) {
+	negative := false
+	if unary, isUnary := expr.(*ast.UnaryOperationExpr); isUnary && unary.Op == opcode.Minus {
+		negative = true
+		expr = unary.V
+	}
+
+	e, isValue := expr.(*driver.ValueExpr)
+	if !isValue {
+		return "", false, false
+	}
+	if e.Datum.Kind() == types.KindNull {
+		return "", true, !negative
+	}
+	value, err := e.Datum.ToString()
+	if err != nil {
+		return "", false, false
+	}
+	if negative {
+		value = "-" + value
+	}
+	return value, false, true
+}
+
+// normalizeDefault returns the numeric default values in the shortest form, such as 1 of '1.0'.
+func normalizeDefault(ft *types.FieldType, value string) string {
+	trimmed := strings.TrimSpace(value)
+	switch ft.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear:
+		if i, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	case mysql.TypeFloat, mysql.TypeDouble:
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	case mysql.TypeNewDecimal:
+		if _, err := strconv.ParseFloat(trimmed, 64); err == nil && !strings.ContainsAny(trimmed, "eE") {
+			// DECIMAL keeps all digits, only the trailing zeros of the fraction are trimmed.
+			if strings.Contains(trimmed, ".") {
+				trimmed = strings.TrimRight(strings.TrimRight(trimmed, "0"), ".")
+			}
+			return trimmed
+		}
+	}
+	return value
+}
+
+// NormalizePrimaryKey returns a shallow copy of the table with the primary key
+// as a table constraint, so that a column-level PRIMARY KEY and the same
+// table-level PRIMARY KEY restore to the same SQL. Like MySQL the columns of
+// the primary key are NOT NULL, and the primary key has no name.
+func NormalizePrimaryKey(table *ast.CreateTableStmt) *ast.CreateTableStmt {
+	pkColumns, hasPk := getPrimaryKey(table)
+	if !hasPk {
+		return table
+	}
+
+	newTable := *table
+	newTable.Cols = make([]*ast.ColumnDef, 0, len(table.Cols))
+	newTable.Constraints = make([]*ast.Constraint, 0, len(table.Constraints)+1)
+	var primaryKey *ast.Constraint
+	for _, col := range table.Cols {
+		if _, ok := pkColumns[col.Name.Name.L]; !ok {
+			newTable.Cols = append(newTable.Cols, col)
+			continue
+		}
+		if hasOneInOptions(col.Options, ast.ColumnOptionPrimaryKey) && primaryKey == nil {
+			primaryKey = &ast.Constraint{Tp: ast.ConstraintPrimaryKey, Keys: []*ast.IndexPartSpecification{
+				{Column: col.Name, Length: types.UnspecifiedLength},
+			}}
+		}
+
+		newCol := *col
+		newCol.Options = []*ast.ColumnOption{{Tp: ast.ColumnOptionNotNull}}
+		newCol.Options = append(newCol.Options,
+			removeColumnOptions(col.Options, ast.ColumnOptionPrimaryKey, ast.ColumnOptionNotNull, ast.ColumnOptionNull)...)
+		newTable.Cols = append(newTable.Cols, &newCol)
+	}
+
+	for _, constraint := range table.Constraints {
+		if constraint.Tp == ast.ConstraintPrimaryKey {
+			newConstraint := *constraint
+			newConstraint.Name = ""
+			primaryKey = &newConstraint
+			continue
+		}
+		newTable.Constraints = append(newTable.Constraints, constraint)
+	}
+	newTable.Constraints = append([]*ast.Constraint{primaryKey}, newTable.Constraints...)
+
+	return &newTable
+}
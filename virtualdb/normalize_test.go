@@ -0,0 +1,55 @@
+package virtualdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeColumn(t *testing.T) {
+	testCases := []struct {
+		flavor   Flavor
+		version  int
+		column   string
+		expected string
+	}{
+		{FlavorMySQL, 80023, "a INT(11)", "`a` INT"},
+		{FlavorMySQL, 80023, "a INTEGER UNSIGNED", "`a` INT UNSIGNED"},
+		{FlavorMySQL, 80023, "a BOOL", "`a` TINYINT(1)"},
+		{FlavorMySQL, 80023, "a TINYINT(4)", "`a` TINYINT"},
+		{FlavorMySQL, 80023, "a INT(5) ZEROFILL", "`a` INT(5) UNSIGNED ZEROFILL"},
+		{FlavorMySQL, 80000, "a INT", "`a` INT(11)"},
+		{FlavorMySQL, 50744, "a INT UNSIGNED", "`a` INT(10) UNSIGNED"},
+		{FlavorMySQL, 50744, "a BIGINT", "`a` BIGINT(20)"},
+		{FlavorMariaDB, 100600, "a SMALLINT", "`a` SMALLINT(6)"},
+		{FlavorMySQL, 80023, "a NUMERIC", "`a` DECIMAL(10,0)"},
+		{FlavorMySQL, 80023, "a DECIMAL(12)", "`a` DECIMAL(12,0)"},
+		{FlavorMySQL, 80023, "a CHAR", "`a` CHAR(1)"},
+		{FlavorMySQL, 80023, "a YEAR(4)", "`a` YEAR"},
+		{FlavorMySQL, 80023, "a DATETIME", "`a` DATETIME(0)"},
+		{FlavorMySQL, 80023, "a INT NULL DEFAULT NULL", "`a` INT"},
+		{FlavorMySQL, 80023, "a INT NOT NULL DEFAULT NULL", "`a` INT NOT NULL DEFAULT NULL"},
+		{FlavorMySQL, 80023, "a INT DEFAULT '0'", "`a` INT DEFAULT '0'"},
+		{FlavorMySQL, 80023, "a INT DEFAULT 0", "`a` INT DEFAULT '0'"},
+		{FlavorMySQL, 80023, "a INT DEFAULT -1", "`a` INT DEFAULT '-1'"},
+		{FlavorMySQL, 80023, "a DOUBLE DEFAULT '1.50'", "`a` DOUBLE DEFAULT '1.5'"},
+		{FlavorMySQL, 80023, "a DECIMAL(5,2) DEFAULT 1.50", "`a` DECIMAL(5,2) DEFAULT '1.5'"},
+		{FlavorMySQL, 80023, "a BOOL DEFAULT FALSE", "`a` TINYINT(1) DEFAULT '0'"},
+		{FlavorMySQL, 80023, "a VARCHAR(10) DEFAULT '0.0'", "`a` VARCHAR(10) DEFAULT '0.0'"},
+		{FlavorMySQL, 80023, "a DATETIME DEFAULT CURRENT_TIMESTAMP", "`a` DATETIME(0) DEFAULT CURRENT_TIMESTAMP()"},
+	}
+
+	for _, testCase := range testCases {
+		stmt, err := parseCreateTableStmt("CREATE TABLE t (" + testCase.column + ")")
+		assert.NoError(t, err)
+
+		col := stmt.Cols[0]
+		before, _ := restoreToSql(col)
+		actual, _ := restoreToSql(NormalizeColumn(col, testCase.flavor, testCase.version))
+		assert.Equal(t, testCase.expected, actual, testCase.column)
+
+		// the column itself is not changed
+		after, _ := restoreToSql(col)
+		assert.Equal(t, before, after)
+	}
+}
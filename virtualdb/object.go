@@ -0,0 +1,348 @@
+package virtualdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+)
+
+// ObjectType is the type of the schema objects other than tables.
+type ObjectType int
+
+// ObjectTypes.
+const (
+	ObjectView ObjectType = iota
+	ObjectTrigger
+	ObjectProcedure
+	ObjectFunction
+	ObjectEvent
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectView:
+		return "VIEW"
+	case ObjectTrigger:
+		return "TRIGGER"
+	case ObjectProcedure:
+		return "PROCEDURE"
+	case ObjectFunction:
+		return "FUNCTION"
+	case ObjectEvent:
+		return "EVENT"
+	}
+	return ""
+}
+
+type ViewInfo struct {
+	View *ast.CreateViewStmt
+}
+
+// ObjectInfo is a trigger, stored procedure, function or event. The parser
+// doesn't support them, so they are kept as text.
+type ObjectInfo struct {
+	Type ObjectType
+	Name string
+	// Definer is the DEFINER clause as written, such as "`root`@`%`", it is
+	// empty if the statement has none.
+	Definer string
+	// Table, Timing and Event are the table, BEFORE or AFTER, and INSERT,
+	// UPDATE or DELETE of a trigger.
+	Table  string
+	Timing string
+	Event  string
+	// Definition is the text after the name, or after the table of a trigger,
+	// such as the parameters, characteristics and body of a procedure.
+	Definition string
+	// Alters are the clauses of the ALTER statements applied to the object,
+	// which are kept as text too.
+	Alters []string
+}
+
+// CreateSQL returns the CREATE statement of the object in the schema, the
+// names are not qualified if the schema is empty.
+func (o *ObjectInfo) CreateSQL(schemaName string) string {
+	var sb strings.Builder
+	sb.WriteString("CREATE ")
+	if o.Definer != "" {
+		sb.WriteString("DEFINER=" + o.Definer + " ")
+	}
+	sb.WriteString(o.Type.String() + " " + qualifiedName(schemaName, o.Name))
+	if o.Type == ObjectTrigger {
+		sb.WriteString(" " + o.Timing + " " + o.Event + " ON " + qualifiedName(schemaName, o.Table))
+	}
+	sb.WriteString(" " + o.Definition)
+	return sb.String()
+}
+
+// AlterSQLs returns the ALTER statements of the object in the schema, the name
+// is not qualified if the schema is empty.
+func (o *ObjectInfo) AlterSQLs(schemaName string) []string {
+	sqls := []string{}
+	for _, alter := range o.Alters {
+		sqls = append(sqls, "ALTER "+o.Type.String()+" "+qualifiedName(schemaName, o.Name)+" "+alter)
+	}
+	return sqls
+}
+
+// DropSQL returns the DROP statement of the object in the schema, the name is
+// not qualified if the schema is empty.
+func (o *ObjectInfo) DropSQL(schemaName string) string {
+	return "DROP " + o.Type.String() + " " + qualifiedName(schemaName, o.Name)
+}
+
+// ObjectStmt is a statement of a trigger, stored procedure, function or event,
+// which the parser doesn't support, it restores to its SQL.
+type ObjectStmt struct {
+	// StmtNode is always nil, it is embedded to implement ast.StmtNode.
+	ast.StmtNode
+	SQL string
+}
+
+func (n *ObjectStmt) Restore(ctx *format.RestoreCtx) error {
+	ctx.WritePlain(n.SQL)
+	return nil
+}
+
+func (n *ObjectStmt) Accept(v ast.Visitor) (ast.Node, bool) {
+	newNode, _ := v.Enter(n)
+	return v.Leave(newNode)
+}
+
+func (n *ObjectStmt) Text() string {
+	return n.SQL
+}
+
+func (n *ObjectStmt) SetText(text string) {}
+
+// objectKey returns the key of a trigger, routine or event name, which are not
+// case sensitive.
+func objectKey(name string) string {
+	return strings.ToLower(name)
+}
+
+func quoteName(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// qualifiedName quotes the name, it is qualified by the schema unless the
+// schema is empty.
+func qualifiedName(schemaName, name string) string {
+	if schemaName == "" {
+		return quoteName(name)
+	}
+	return quoteName(schemaName) + "." + quoteName(name)
+}
+
+func newSchemaInfo(schema *ast.CreateDatabaseStmt) *SchemaInfo {
+	return &SchemaInfo{
+		Schema:     schema,
+		Tables:     map[string]*TableInfo{},
+		Views:      map[string]*ViewInfo{},
+		Triggers:   map[string]*ObjectInfo{},
+		Procedures: map[string]*ObjectInfo{},
+		Functions:  map[string]*ObjectInfo{},
+		Events:     map[string]*ObjectInfo{},
+	}
+}
+
+// objects returns the triggers, procedures, functions or events of the schema.
+func (s *SchemaInfo) objects(tp ObjectType) map[string]*ObjectInfo {
+	switch tp {
+	case ObjectTrigger:
+		return s.Triggers
+	case ObjectProcedure:
+		return s.Procedures
+	case ObjectFunction:
+		return s.Functions
+	case ObjectEvent:
+		return s.Events
+	}
+	return nil
+}
+
+func sortedObjectNames(objects map[string]*ObjectInfo) []string {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copyObjectInfo copies the object, its fields are all values.
+func copyObjectInfo(object *ObjectInfo) *ObjectInfo {
+	newObject := *object
+	newObject.Alters = append([]string(nil), object.Alters...)
+	return &newObject
+}
+
+// execObjectStmt executes the statement of a trigger, stored procedure,
+// function or event, or ALTER VIEW. The node is the ObjectStmt of the events.
+func (c *VirtualDB) execObjectStmt(stmt *objectStmt, node ast.StmtNode) error {
+	if stmt.tp == ObjectView {
+		return c.alterView(stmt.definition, node)
+	}
+	base := BaseEvent{node}
+
+	schemaName := stmt.schema
+	if schemaName == "" {
+		schemaName = c.currentSchema
+	}
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return unknownDatabaseError(schemaName)
+	}
+	objects := schema.objects(stmt.tp)
+	key := objectKey(stmt.name)
+	object, exist := objects[key]
+
+	switch stmt.action {
+	case "CREATE":
+		if exist && !stmt.orReplace {
+			if stmt.ifNotExists {
+				return nil
+			}
+			return duplicateObjectError(schemaName, stmt.tp, stmt.name)
+		}
+		object = &ObjectInfo{
+			Type:       stmt.tp,
+			Name:       stmt.name,
+			Definer:    stmt.definer,
+			Timing:     stmt.timing,
+			Event:      stmt.event,
+			Definition: stmt.definition,
+		}
+		if stmt.tp == ObjectTrigger {
+			table, err := c.triggerTable(schemaName, stmt)
+			if err != nil {
+				return err
+			}
+			object.Table = table
+		}
+		if oldObject, replaced := objects[key]; replaced {
+			c.emit(&ObjectAltered{ObjectChange{base, schemaName, oldObject, object}, schemaName})
+		} else {
+			c.emit(&ObjectCreated{ObjectChange{base, schemaName, nil, object}})
+		}
+		objects[key] = object
+		return nil
+
+	case "DROP":
+		if !exist {
+			if stmt.ifExists {
+				return nil
+			}
+			return unknownObjectError(schemaName, stmt.tp, stmt.name)
+		}
+		delete(objects, key)
+		c.emit(&ObjectDropped{ObjectChange{base, schemaName, object, nil}})
+		return nil
+	}
+
+	// ALTER PROCEDURE, FUNCTION or EVENT
+	if !exist {
+		return unknownObjectError(schemaName, stmt.tp, stmt.name)
+	}
+	oldObject := object
+	object = copyObjectInfo(object)
+	if stmt.definer != "" {
+		object.Definer = stmt.definer
+	}
+	if stmt.definition != "" {
+		object.Alters = append(object.Alters, stmt.definition)
+	}
+	if stmt.rename == "" {
+		objects[key] = object
+		c.emit(&ObjectAltered{ObjectChange{base, schemaName, oldObject, object}, schemaName})
+		return nil
+	}
+
+	newSchemaName := stmt.renameSchema
+	if newSchemaName == "" {
+		newSchemaName = schemaName
+	}
+	newSchema, exist := c.getSchema(newSchemaName)
+	if !exist {
+		return unknownDatabaseError(newSchemaName)
+	}
+	newObjects := newSchema.objects(stmt.tp)
+	if _, exist := newObjects[objectKey(stmt.rename)]; exist {
+		return duplicateObjectError(newSchemaName, stmt.tp, stmt.rename)
+	}
+	delete(objects, key)
+	object.Name = stmt.rename
+	newObjects[objectKey(stmt.rename)] = object
+	c.emit(&ObjectAltered{ObjectChange{base, schemaName, oldObject, object}, newSchemaName})
+	return nil
+}
+
+// triggerTable returns the name of the table of a trigger as it is stored.
+func (c *VirtualDB) triggerTable(schemaName string, stmt *objectStmt) (string, error) {
+	if stmt.tableSchema != "" && c.nameKey(stmt.tableSchema) != c.nameKey(schemaName) {
+		return "", newObjectError(ErrTriggerInWrongSchema, schemaName, ObjectTrigger, stmt.name,
+			fmt.Sprintf(TriggerTableSchemaErrorPattern, schemaName, stmt.name, stmt.tableSchema, stmt.table))
+	}
+	table, exist, err := c.getTable(schemaName, stmt.table)
+	if err != nil {
+		return "", err
+	}
+	if !exist {
+		if c.hasView(schemaName, stmt.table) {
+			return "", newSchemaError(ErrTriggerOnView, schemaName, stmt.table,
+				fmt.Sprintf(TriggerOnViewErrorPattern, stmt.name, schemaName, stmt.table))
+		}
+		return "", noSuchTableError(schemaName, stmt.table)
+	}
+	return table.Table.Table.Name.O, nil
+}
+
+// dropTableTriggers drops the triggers of the table dropped by the statement.
+func (c *VirtualDB) dropTableTriggers(stmt ast.StmtNode, schemaName, tableName string) {
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return
+	}
+	for key, trigger := range schema.Triggers {
+		if c.nameKey(trigger.Table) == c.nameKey(tableName) {
+			delete(schema.Triggers, key)
+			c.emit(&ObjectDropped{ObjectChange{BaseEvent{stmt}, schemaName, trigger, nil}})
+		}
+	}
+}
+
+// hasTableTriggers reports whether the table has triggers.
+func (c *VirtualDB) hasTableTriggers(schemaName, tableName string) bool {
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return false
+	}
+	for _, trigger := range schema.Triggers {
+		if c.nameKey(trigger.Table) == c.nameKey(tableName) {
+			return true
+		}
+	}
+	return false
+}
+
+// renameTableTriggers moves the triggers to the table renamed by the statement
+// in the same schema.
+func (c *VirtualDB) renameTableTriggers(stmt ast.StmtNode, schemaName, tableName, newTableName string) {
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return
+	}
+	for key, trigger := range schema.Triggers {
+		if c.nameKey(trigger.Table) != c.nameKey(tableName) {
+			continue
+		}
+		// the triggers are replaced rather than modified like the tables.
+		newTrigger := copyObjectInfo(trigger)
+		newTrigger.Table = c.storedName(newTableName)
+		schema.Triggers[key] = newTrigger
+		c.emit(&ObjectAltered{ObjectChange{BaseEvent{stmt}, schemaName, trigger, newTrigger}, schemaName})
+	}
+}
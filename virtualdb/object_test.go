@@ -0,0 +1,187 @@
+package virtualdb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestView(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("CREATE TABLE t1 (a INT, b INT); CREATE TABLE t2 (a INT, c INT);"))
+	assert.NoError(t, vb.ExecSQL("CREATE VIEW b_view AS SELECT t1.a, b + 1 AS total FROM t1 JOIN t2 ON t1.a = t2.a WHERE c > 0 ORDER BY total;"))
+	assert.NoError(t, vb.ExecSQL("CREATE VIEW a_view (x) AS SELECT total FROM b_view;"))
+	assertText(t, vb, "CREATE DATABASE `db1`;\n"+
+		"CREATE TABLE `db1`.`t1` (`a` INT,`b` INT);\n"+
+		"CREATE TABLE `db1`.`t2` (`a` INT,`c` INT);\n"+
+		"CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`b_view` AS SELECT `t1`.`a`,`b`+1 AS `total` FROM `db1`.`t1` JOIN `db1`.`t2` ON `t1`.`a`=`t2`.`a` WHERE `c`>0 ORDER BY `total`;\n"+
+		"CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY DEFINER VIEW `db1`.`a_view` (`x`) AS SELECT `total` FROM `db1`.`b_view`;\n")
+
+	testCases := []struct {
+		sql  string
+		kind ErrorKind
+	}{
+		{"CREATE VIEW v AS SELECT a FROM t9", ErrNoSuchTable},
+		{"CREATE VIEW v AS SELECT d FROM t1", ErrUnknownColumn},
+		{"CREATE VIEW v (x, y) AS SELECT a FROM t1", ErrViewWrongList},
+		{"CREATE VIEW t1 AS SELECT 1", ErrTableExists},
+		{"CREATE VIEW a_view AS SELECT 1", ErrTableExists},
+		{"CREATE OR REPLACE VIEW t1 AS SELECT 1", ErrWrongObject},
+		{"CREATE TABLE a_view (a INT)", ErrTableExists},
+		{"ALTER VIEW v AS SELECT 1", ErrNoSuchTable},
+		{"ALTER VIEW t1 AS SELECT 1", ErrWrongObject},
+		{"DROP VIEW t1", ErrWrongObject},
+		{"DROP VIEW v", ErrUnknownTable},
+		{"DROP TABLE a_view", ErrUnknownTable},
+	}
+	for _, testCase := range testCases {
+		err := vb.ExecSQL(testCase.sql)
+		var schemaErr *SchemaError
+		if assert.True(t, errors.As(err, &schemaErr), testCase.sql) {
+			assert.Equal(t, testCase.kind, schemaErr.Kind, testCase.sql)
+		}
+	}
+
+	// the subqueries and derived tables are not resolved
+	assert.NoError(t, vb.ExecSQL("CREATE VIEW v AS SELECT x.n FROM (SELECT a AS n FROM t1) AS x WHERE x.n IN (SELECT c FROM t2);"))
+	assert.NoError(t, vb.ExecSQL("ALTER SQL SECURITY INVOKER VIEW v AS SELECT c FROM t2;"))
+	views, _ := vb.GetViews("db1")
+	sql, _ := restoreToSql(views["v"].View)
+	assert.Equal(t, "CREATE ALGORITHM = UNDEFINED DEFINER = CURRENT_USER SQL SECURITY INVOKER VIEW `db1`.`v` AS SELECT `c` FROM `db1`.`t2`", sql)
+	assert.NoError(t, vb.ExecSQL("DROP VIEW v, a_view;"))
+
+	// the views are validated again since their tables may be changed
+	assert.NoError(t, vb.ExecSQL("ALTER TABLE t2 DROP COLUMN c;"))
+	errs := vb.Validate()
+	if assert.Equal(t, 1, len(errs)) {
+		assert.True(t, errors.Is(errs[0], &SchemaError{Kind: ErrUnknownColumn, Table: "b_view", Column: "c"}))
+	}
+}
+
+const testObjects = `CREATE TABLE t1 (a INT);
+CREATE TRIGGER tr1 BEFORE INSERT ON t1 FOR EACH ROW SET NEW.a = 1;
+DELIMITER //
+CREATE DEFINER = 'admin'@'localhost' PROCEDURE p1(IN x INT)
+BEGIN
+  SELECT x;
+  SELECT x + 1;
+END //
+DELIMITER ;
+CREATE FUNCTION f1(x INT) RETURNS INT DETERMINISTIC RETURN x + 1;
+CREATE EVENT e1 ON SCHEDULE EVERY 1 DAY DO DELETE FROM t1;
+`
+
+func TestObjects(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL(testObjects))
+	text := "CREATE DATABASE `db1`;\n" +
+		"CREATE TABLE `db1`.`t1` (`a` INT);\n" +
+		"CREATE FUNCTION `db1`.`f1` (x INT) RETURNS INT DETERMINISTIC RETURN x + 1;\n" +
+		"DELIMITER ;;\n" +
+		"CREATE DEFINER=`admin`@`localhost` PROCEDURE `db1`.`p1` (IN x INT)\nBEGIN\n  SELECT x;\n  SELECT x + 1;\nEND;;\n" +
+		"DELIMITER ;\n" +
+		"CREATE TRIGGER `db1`.`tr1` BEFORE INSERT ON `db1`.`t1` FOR EACH ROW SET NEW.a = 1;\n" +
+		"CREATE EVENT `db1`.`e1` ON SCHEDULE EVERY 1 DAY DO DELETE FROM t1;\n"
+	assertText(t, vb, text)
+
+	// the text can be executed again
+	newVb := NewVirtualDB("")
+	assert.NoError(t, newVb.ExecSQL(text))
+	assertText(t, newVb, text)
+
+	testCases := []struct {
+		sql  string
+		kind ErrorKind
+	}{
+		{"CREATE TRIGGER tr1 AFTER DELETE ON t1 FOR EACH ROW SET @a = 1", ErrTriggerExists},
+		{"CREATE TRIGGER tr2 AFTER DELETE ON t9 FOR EACH ROW SET @a = 1", ErrNoSuchTable},
+		{"CREATE TRIGGER tr2 AFTER DELETE ON db2.t1 FOR EACH ROW SET @a = 1", ErrTriggerInWrongSchema},
+		{"DROP TRIGGER tr9", ErrTriggerDoesNotExist},
+		{"CREATE PROCEDURE P1() SELECT 1", ErrRoutineExists},
+		{"DROP FUNCTION p1", ErrRoutineDoesNotExist},
+		{"ALTER PROCEDURE p9 COMMENT 'x'", ErrRoutineDoesNotExist},
+		{"CREATE EVENT e1 ON SCHEDULE EVERY 1 DAY DO SELECT 1", ErrEventExists},
+		{"DROP EVENT e9", ErrEventDoesNotExist},
+		{"CREATE FUNCTION db9.f2() RETURNS INT RETURN 1", ErrUnknownDatabase},
+	}
+	for _, testCase := range testCases {
+		err := vb.ExecSQL(testCase.sql)
+		var schemaErr *SchemaError
+		if assert.True(t, errors.As(err, &schemaErr), testCase.sql) {
+			assert.Equal(t, testCase.kind, schemaErr.Kind, testCase.sql)
+		}
+	}
+	assert.NoError(t, vb.ExecSQL("DROP TRIGGER IF EXISTS tr9; DROP PROCEDURE IF EXISTS p9;"))
+
+	assert.NoError(t, vb.ExecSQL("ALTER PROCEDURE p1 COMMENT 'test'; ALTER EVENT e1 RENAME TO e2 DISABLE;"))
+	assert.NoError(t, vb.ExecSQL("ALTER TABLE t1 RENAME TO t2;"))
+	triggers, _ := vb.GetObjects("db1", ObjectTrigger)
+	assert.Equal(t, "t2", triggers["tr1"].Table)
+	procedures, _ := vb.GetObjects("db1", ObjectProcedure)
+	assert.Equal(t, []string{"COMMENT 'test'"}, procedures["p1"].Alters)
+	events, _ := vb.GetObjects("db1", ObjectEvent)
+	assert.Equal(t, []string{"DISABLE"}, events["e2"].Alters)
+
+	// a table with triggers can't be moved to another schema, and its triggers
+	// are dropped with it
+	assert.NoError(t, vb.ExecSQL("CREATE DATABASE db2;"))
+	err := vb.ExecSQL("ALTER TABLE t2 RENAME TO db2.t2;")
+	assert.True(t, errors.Is(err, &SchemaError{Kind: ErrTriggerInWrongSchema}))
+	assert.NoError(t, vb.ExecSQL("DROP TABLE t2;"))
+	triggers, _ = vb.GetObjects("db1", ObjectTrigger)
+	assert.Empty(t, triggers)
+}
+
+func TestParseObjectStmt(t *testing.T) {
+	stmt, ok := parseObjectStmt("CREATE DEFINER=`root`@`%` TRIGGER `db1`.`tr` AFTER update ON `t``1` FOR EACH ROW SET @a = 1")
+	if assert.True(t, ok) {
+		assert.Equal(t, &objectStmt{action: "CREATE", tp: ObjectTrigger, schema: "db1", name: "tr", definer: "`root`@`%`",
+			table: "t`1", timing: "AFTER", event: "UPDATE", definition: "FOR EACH ROW SET @a = 1"}, stmt)
+	}
+	stmt, ok = parseObjectStmt("ALTER DEFINER = root@localhost EVENT e1 ON SCHEDULE EVERY 2 DAY RENAME TO db2.e2 COMMENT 'rename to x' DO SELECT 'RENAME TO'")
+	if assert.True(t, ok) {
+		assert.Equal(t, &objectStmt{action: "ALTER", tp: ObjectEvent, name: "e1", definer: "`root`@`localhost`",
+			definition: "ON SCHEDULE EVERY 2 DAY COMMENT 'rename to x' DO SELECT 'RENAME TO'", renameSchema: "db2", rename: "e2"}, stmt)
+	}
+	stmt, ok = parseObjectStmt("DROP FUNCTION IF EXISTS f1")
+	if assert.True(t, ok) {
+		assert.Equal(t, &objectStmt{action: "DROP", tp: ObjectFunction, ifExists: true, name: "f1"}, stmt)
+	}
+	stmt, ok = parseObjectStmt("ALTER ALGORITHM = MERGE VIEW v AS SELECT 1")
+	if assert.True(t, ok) {
+		assert.Equal(t, &objectStmt{action: "ALTER", tp: ObjectView, definition: "ALGORITHM = MERGE VIEW v AS SELECT 1"}, stmt)
+	}
+
+	for _, sql := range []string{"CREATE VIEW v AS SELECT 1", "CREATE DEFINER = CURRENT_USER SQL SECURITY INVOKER VIEW v AS SELECT 1",
+		"DROP VIEW v", "ALTER TABLE t1 ADD COLUMN a INT", "CREATE TABLE trigger (a INT)"} {
+		_, ok := parseObjectStmt(sql)
+		assert.False(t, ok, sql)
+	}
+}
+
+func TestObjectSQLWithoutSchema(t *testing.T) {
+	trigger := &ObjectInfo{Type: ObjectTrigger, Name: "tr1", Table: "t1", Timing: "BEFORE", Event: "INSERT",
+		Definition: "FOR EACH ROW SET NEW.a = 1"}
+	assert.Equal(t, "CREATE TRIGGER `tr1` BEFORE INSERT ON `t1` FOR EACH ROW SET NEW.a = 1", trigger.CreateSQL(""))
+	assert.Equal(t, "DROP TRIGGER `db1`.`tr1`", trigger.DropSQL("db1"))
+
+	procedure := &ObjectInfo{Type: ObjectProcedure, Name: "p1", Definition: "() SELECT 1", Alters: []string{"COMMENT 'x'"}}
+	assert.Equal(t, []string{"ALTER PROCEDURE `p1` COMMENT 'x'"}, procedure.AlterSQLs(""))
+	assert.Equal(t, "DROP PROCEDURE `p1`", procedure.DropSQL(""))
+}
+
+func TestTextOfViewsCallingFunctions(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("CREATE TABLE t1 (a INT); CREATE FUNCTION f1(x INT) RETURNS INT DETERMINISTIC RETURN x + 1;"+
+		"CREATE VIEW v1 AS SELECT f1(a) AS b FROM t1;"))
+	text, err := vb.Text()
+	assert.NoError(t, err)
+
+	// the functions are created before the views calling them.
+	assert.True(t, strings.Index(text, "CREATE FUNCTION") < strings.Index(text, "VIEW `db1`.`v1`"), text)
+	newVb := NewVirtualDB("")
+	assert.NoError(t, newVb.ExecSQL(text))
+	assertText(t, newVb, text)
+}
@@ -0,0 +1,49 @@
+package virtualdb
+
+// DefaultServerVersion is the MySQL version used to evaluate the executable
+// comments of dumps, in the form of "/*!80000 ... */", and decides the default
+// charsets, it is 8.0.40.
+const DefaultServerVersion = 80040
+
+// ExecOption is used for executing SQL by ExecSQLWithOpt and ExecDump.
+type ExecOption struct {
+	// ContinueOnError skips the failed statements and executes the rest, the
+	// failures are returned as ExecErrors.
+	ContinueOnError bool
+	// ServerVersion is the MySQL version of ExecDump, such as 50744 for 5.7.44,
+	// the executable comments for later versions are ignored. 0 means the
+	// version of the VirtualDB.
+	ServerVersion int
+}
+
+// Flavor is the kind of server, whose canonical column definitions differ.
+type Flavor int
+
+// Flavors.
+const (
+	FlavorMySQL Flavor = iota
+	FlavorMariaDB
+)
+
+// LowerCaseTableNames is the mode of the MySQL variable lower_case_table_names,
+// which controls how the schema and table names are stored and compared.
+type LowerCaseTableNames int
+
+// LowerCaseTableNames modes.
+const (
+	// CaseSensitive stores the names as given and compares them case-sensitively.
+	CaseSensitive LowerCaseTableNames = iota
+	// LowerCaseStored stores the names in lowercase and compares them case-insensitively.
+	LowerCaseStored
+	// LowerCaseCompared stores the names as given and compares them case-insensitively.
+	LowerCaseCompared
+)
+
+// DBOption is used for creating a VirtualDB by NewVirtualDBWithOpt.
+type DBOption struct {
+	LowerCaseTableNames LowerCaseTableNames
+	// ServerVersion is the MySQL version the VirtualDB emulates, such as 50744
+	// for 5.7.44, which decides the default charsets and collations. 0 means
+	// DefaultServerVersion.
+	ServerVersion int
+}
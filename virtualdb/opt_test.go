@@ -0,0 +1,46 @@
+package virtualdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowerCaseTableNames(t *testing.T) {
+	input := "create database DB2; use db2; create table Users(ID int); alter table USERS add column Name varchar(10); create table db2.Orders(a int);"
+
+	vb := NewVirtualDBWithOpt("db1", DBOption{LowerCaseTableNames: CaseSensitive})
+	assert.EqualError(t, errorsUnwrap(vb.ExecSQL(input)), "not exist schema: db2")
+
+	vb = NewVirtualDBWithOpt("db1", DBOption{LowerCaseTableNames: LowerCaseStored})
+	assert.NoError(t, vb.ExecSQL(input))
+	assertText(t, vb, "CREATE DATABASE `db1`;\n"+
+		"CREATE DATABASE `db2`;\n"+
+		"CREATE TABLE `db2`.`orders` (`a` INT);\n"+
+		"CREATE TABLE `db2`.`users` (`ID` INT,`Name` VARCHAR(10));\n")
+
+	vb = NewVirtualDBWithOpt("db1", DBOption{LowerCaseTableNames: LowerCaseCompared})
+	assert.NoError(t, vb.ExecSQL(input))
+	assertText(t, vb, "CREATE DATABASE `db1`;\n"+
+		"CREATE DATABASE `DB2`;\n"+
+		"CREATE TABLE `DB2`.`Orders` (`a` INT);\n"+
+		"CREATE TABLE `DB2`.`Users` (`ID` INT,`Name` VARCHAR(10));\n")
+	assert.EqualError(t, errorsUnwrap(vb.ExecSQL("create table USERS(a int);")), "duplicate table: db2.USERS")
+
+	col, exist, err := vb.GetColumn("Db2", "uSERS", "name")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+	assert.Equal(t, "Name", col.Name.Name.O)
+
+	// renaming in case only keeps the table.
+	assert.NoError(t, vb.ExecSQL("alter table users rename to USERS;"))
+	tables, _ := vb.GetTableStmts("db2")
+	assert.Equal(t, "USERS", tables["users"].Table.Table.Name.O)
+}
+
+func errorsUnwrap(err error) error {
+	if stmtErr, ok := err.(*StmtError); ok {
+		return stmtErr.Err
+	}
+	return err
+}
@@ -0,0 +1,404 @@
+package virtualdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+)
+
+// alterPartitions applies the partition specs of an ALTER TABLE to the table,
+// the partitions are changed in place like the other parts of the copied table.
+func alterPartitions(table *ast.CreateTableStmt, specs []*ast.AlterTableSpec, schemaName, tableName string) error {
+	for _, spec := range specs {
+		var err error
+		switch spec.Tp {
+		case ast.AlterTablePartition:
+			table.Partition = spec.Partition
+			err = checkPartitionDefinitions(table.Partition, schemaName, tableName)
+		case ast.AlterTableRemovePartitioning:
+			if table.Partition == nil {
+				return notPartitionedError(schemaName, tableName)
+			}
+			table.Partition = nil
+		case ast.AlterTableAddPartitions:
+			err = addPartitions(table.Partition, spec, schemaName, tableName)
+		case ast.AlterTableDropPartition:
+			err = dropPartitions(table.Partition, spec, schemaName, tableName)
+		case ast.AlterTableCoalescePartitions:
+			err = coalescePartitions(table.Partition, spec, schemaName, tableName)
+		case ast.AlterTableReorganizePartition:
+			err = reorganizePartitions(table.Partition, spec, schemaName, tableName)
+		case ast.AlterTableTruncatePartition, ast.AlterTableCheckPartitions, ast.AlterTableOptimizePartition,
+			ast.AlterTableRepairPartition, ast.AlterTableRebuildPartition, ast.AlterTableExchangePartition,
+			ast.AlterTableImportPartitionTablespace, ast.AlterTableDiscardPartitionTablespace:
+			// the maintenance of the data doesn't change the definition, only the names are checked.
+			if table.Partition == nil {
+				return notPartitionedError(schemaName, tableName)
+			}
+			if !spec.OnAllPartitions {
+				err = checkPartitionNames(table.Partition, spec.PartitionNames, schemaName, tableName)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if table.Partition == nil {
+		return nil
+	}
+	for _, name := range getPartitionColumns(table.Partition) {
+		if !hasColumn(table.Cols, strings.ToLower(name)) {
+			return partitionColumnError(schemaName, tableName, name)
+		}
+	}
+	return nil
+}
+
+// partitionCount returns the number of partitions, the partitions of HASH and
+// KEY may be given by number only.
+func partitionCount(partition *ast.PartitionOptions) int {
+	if len(partition.Definitions) != 0 {
+		return len(partition.Definitions)
+	}
+	if partition.Num == 0 {
+		return 1
+	}
+	return int(partition.Num)
+}
+
+// isRangeOrList reports whether the partitions are defined by their values.
+func isRangeOrList(partition *ast.PartitionOptions) bool {
+	return partition.Tp == model.PartitionTypeRange || partition.Tp == model.PartitionTypeList
+}
+
+func addPartitions(partition *ast.PartitionOptions, spec *ast.AlterTableSpec, schemaName, tableName string) error {
+	if partition == nil {
+		return notPartitionedError(schemaName, tableName)
+	}
+	if isRangeOrList(partition) && len(spec.PartDefinitions) == 0 {
+		return newPartitionError(ErrPartitionsMustBeDefined, schemaName, tableName, "", partition.Tp.String(),
+			fmt.Sprintf(PartitionsNotDefinedErrorPattern, partition.Tp, schemaName, tableName))
+	}
+
+	switch {
+	case len(spec.PartDefinitions) != 0 && (len(partition.Definitions) != 0 || isRangeOrList(partition)):
+		partition.Definitions = append(partition.Definitions, spec.PartDefinitions...)
+	case len(spec.PartDefinitions) != 0:
+		partition.Num = uint64(partitionCount(partition) + len(spec.PartDefinitions))
+		return nil
+	case len(partition.Definitions) != 0:
+		// like MySQL the added partitions of HASH and KEY are named by their position.
+		for i := uint64(0); i < spec.Num; i++ {
+			partition.Definitions = append(partition.Definitions, &ast.PartitionDefinition{
+				Name:   model.NewCIStr("p" + strconv.Itoa(len(partition.Definitions))),
+				Clause: &ast.PartitionDefinitionClauseNone{},
+			})
+		}
+	default:
+		partition.Num = uint64(partitionCount(partition)) + spec.Num
+		return nil
+	}
+	partition.Num = uint64(len(partition.Definitions))
+	return checkPartitionDefinitions(partition, schemaName, tableName)
+}
+
+func dropPartitions(partition *ast.PartitionOptions, spec *ast.AlterTableSpec, schemaName, tableName string) error {
+	if partition == nil {
+		return notPartitionedError(schemaName, tableName)
+	}
+	if !isRangeOrList(partition) {
+		return newPartitionError(ErrOnlyOnRangeList, schemaName, tableName, "", "DROP",
+			fmt.Sprintf(PartitionTypeErrorPattern, "DROP", partition.Tp, schemaName, tableName))
+	}
+
+	dropped := map[string]struct{}{}
+	for _, name := range spec.PartitionNames {
+		if getPartitionIndex(partition, name.O) < 0 {
+			if spec.IfExists {
+				continue
+			}
+			return newPartitionError(ErrWrongPartitionList, schemaName, tableName, name.O, "DROP",
+				fmt.Sprintf(NotExistPartitionErrorPattern, name.O, schemaName, tableName))
+		}
+		dropped[name.L] = struct{}{}
+	}
+	if len(dropped) == len(partition.Definitions) {
+		return newPartitionError(ErrDropLastPartition, schemaName, tableName, "", "",
+			fmt.Sprintf(DropLastPartitionErrorPattern, schemaName, tableName))
+	}
+
+	definitions := []*ast.PartitionDefinition{}
+	for _, def := range partition.Definitions {
+		if _, ok := dropped[def.Name.L]; !ok {
+			definitions = append(definitions, def)
+		}
+	}
+	partition.Definitions = definitions
+	partition.Num = uint64(len(definitions))
+	return nil
+}
+
+func coalescePartitions(partition *ast.PartitionOptions, spec *ast.AlterTableSpec, schemaName, tableName string) error {
+	if partition == nil {
+		return notPartitionedError(schemaName, tableName)
+	}
+	if isRangeOrList(partition) {
+		return newPartitionError(ErrCoalesceOnlyOnHash, schemaName, tableName, "", "",
+			fmt.Sprintf(PartitionTypeErrorPattern, "COALESCE", partition.Tp, schemaName, tableName))
+	}
+	count := partitionCount(partition)
+	if int(spec.Num) >= count {
+		return newPartitionError(ErrDropLastPartition, schemaName, tableName, "", "",
+			fmt.Sprintf(DropLastPartitionErrorPattern, schemaName, tableName))
+	}
+	if len(partition.Definitions) != 0 {
+		partition.Definitions = partition.Definitions[:count-int(spec.Num)]
+	}
+	partition.Num = uint64(count) - spec.Num
+	return nil
+}
+
+// reorganizePartitions replaces the partitions with the new definitions at the
+// position of the first one, the partitions of RANGE must be adjacent and keep
+// the range unless the last partition is reorganized.
+func reorganizePartitions(partition *ast.PartitionOptions, spec *ast.AlterTableSpec, schemaName, tableName string) error {
+	if partition == nil {
+		return notPartitionedError(schemaName, tableName)
+	}
+	// REORGANIZE PARTITION without names rebuilds the partitions of HASH and KEY.
+	if len(spec.PartitionNames) == 0 {
+		return nil
+	}
+
+	indexes := []int{}
+	for _, name := range spec.PartitionNames {
+		index := getPartitionIndex(partition, name.O)
+		if index < 0 {
+			return newPartitionError(ErrWrongPartitionList, schemaName, tableName, name.O, "REORGANIZE",
+				fmt.Sprintf(NotExistPartitionErrorPattern, name.O, schemaName, tableName))
+		}
+		indexes = append(indexes, index)
+	}
+	first, last := indexes[0], indexes[0]
+	for _, index := range indexes {
+		if index < first {
+			first = index
+		}
+		if index > last {
+			last = index
+		}
+	}
+
+	if partition.Tp == model.PartitionTypeRange {
+		if last-first+1 != len(indexes) {
+			return reorgOutsideRangeError(schemaName, tableName)
+		}
+		oldClause, _ := partition.Definitions[last].Clause.(*ast.PartitionDefinitionClauseLessThan)
+		newClause, _ := spec.PartDefinitions[len(spec.PartDefinitions)-1].Clause.(*ast.PartitionDefinitionClauseLessThan)
+		if oldClause != nil && newClause != nil {
+			cmp, ok := compareRangeValues(newClause.Exprs, oldClause.Exprs)
+			if ok && (cmp < 0 || (cmp > 0 && last != len(partition.Definitions)-1)) {
+				return reorgOutsideRangeError(schemaName, tableName)
+			}
+		}
+	}
+
+	reorganized := map[int]struct{}{}
+	for _, index := range indexes {
+		reorganized[index] = struct{}{}
+	}
+	definitions := []*ast.PartitionDefinition{}
+	for i, def := range partition.Definitions {
+		if i == first {
+			definitions = append(definitions, spec.PartDefinitions...)
+		}
+		if _, ok := reorganized[i]; !ok {
+			definitions = append(definitions, def)
+		}
+	}
+	partition.Definitions = definitions
+	partition.Num = uint64(len(definitions))
+	return checkPartitionDefinitions(partition, schemaName, tableName)
+}
+
+func checkPartitionNames(partition *ast.PartitionOptions, names []model.CIStr, schemaName, tableName string) error {
+	for _, name := range names {
+		if getPartitionIndex(partition, name.O) < 0 {
+			return newPartitionError(ErrUnknownPartition, schemaName, tableName, name.O, "",
+				fmt.Sprintf(NotExistPartitionErrorPattern, name.O, schemaName, tableName))
+		}
+	}
+	return nil
+}
+
+// getPartitionIndex returns the position of the named partition, or -1.
+func getPartitionIndex(partition *ast.PartitionOptions, name string) int {
+	for i, def := range partition.Definitions {
+		if strings.EqualFold(def.Name.O, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkPartitionDefinitions checks the names of the partitions are unique, the
+// values of RANGE are increasing and the values of LIST are not repeated.
+func checkPartitionDefinitions(partition *ast.PartitionOptions, schemaName, tableName string) error {
+	names := map[string]struct{}{}
+	values := map[string]struct{}{}
+	var prev []ast.ExprNode
+	for _, def := range partition.Definitions {
+		if _, ok := names[def.Name.L]; ok {
+			return newPartitionError(ErrDuplicatePartition, schemaName, tableName, def.Name.O, "",
+				fmt.Sprintf(DuplicatePartitionErrorPattern, def.Name.O, schemaName, tableName))
+		}
+		names[def.Name.L] = struct{}{}
+
+		switch clause := def.Clause.(type) {
+		case *ast.PartitionDefinitionClauseLessThan:
+			if prev != nil {
+				if cmp, ok := compareRangeValues(prev, clause.Exprs); ok && cmp >= 0 {
+					return newPartitionError(ErrRangeNotIncreasing, schemaName, tableName, def.Name.O, "",
+						fmt.Sprintf(RangeNotIncreasingErrorPattern, def.Name.O, schemaName, tableName))
+				}
+			}
+			prev = clause.Exprs
+		case *ast.PartitionDefinitionClauseIn:
+			for _, value := range clause.Values {
+				text := restoreExprs(value)
+				if _, ok := values[text]; ok {
+					return newPartitionError(ErrDuplicateListValue, schemaName, tableName, def.Name.O, "",
+						fmt.Sprintf(ListDuplicateValueErrorPattern, text, schemaName, tableName))
+				}
+				values[text] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+func restoreExprs(exprs []ast.ExprNode) string {
+	texts := []string{}
+	for _, expr := range exprs {
+		text, _ := restoreToSql(expr)
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, ",")
+}
+
+// compareRangeValues compares the values of VALUES LESS THAN, it returns false
+// if they can't be compared without evaluating the expressions.
+func compareRangeValues(a, b []ast.ExprNode) (int, bool) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		cmp, ok := compareRangeValue(a[i], b[i])
+		if !ok || cmp != 0 {
+			return cmp, ok
+		}
+	}
+	return len(a) - len(b), true
+}
+
+func compareRangeValue(a, b ast.ExprNode) (int, bool) {
+	_, aMax := a.(*ast.MaxValueExpr)
+	_, bMax := b.(*ast.MaxValueExpr)
+	switch {
+	case aMax && bMax:
+		return 0, true
+	case aMax:
+		return 1, true
+	case bMax:
+		return -1, true
+	}
+
+	// the same function of constants such as TO_DAYS('2020-01-01') is
+	// compared by its arguments, it is monotonic for the partition functions.
+	aFunc, aOk := a.(*ast.FuncCallExpr)
+	bFunc, bOk := b.(*ast.FuncCallExpr)
+	if aOk && bOk {
+		if aFunc.FnName.L != bFunc.FnName.L || len(aFunc.Args) != 1 || len(bFunc.Args) != 1 {
+			return 0, false
+		}
+		return compareRangeValue(aFunc.Args[0], bFunc.Args[0])
+	}
+
+	aValue, aOk := a.(*driver.ValueExpr)
+	bValue, bOk := b.(*driver.ValueExpr)
+	if !aOk || !bOk {
+		return 0, false
+	}
+	cmp, err := aValue.Datum.CompareDatum(&stmtctx.StatementContext{}, &bValue.Datum)
+	if err != nil {
+		return 0, false
+	}
+	return cmp, true
+}
+
+// getPartitionColumns returns the columns used by the partition and subpartition
+// functions, KEY() without columns uses the primary key and has none.
+func getPartitionColumns(partition *ast.PartitionOptions) []string {
+	collector := &columnNameCollector{}
+	for _, method := range []*ast.PartitionMethod{&partition.PartitionMethod, partition.Sub} {
+		if method == nil {
+			continue
+		}
+		if method.Expr != nil {
+			method.Expr.Accept(collector)
+		}
+		for _, col := range method.ColumnNames {
+			collector.columns = append(collector.columns, col.Name.O)
+		}
+	}
+	return collector.columns
+}
+
+// validatePartition checks the partition function of the table, like MySQL
+// every unique key must include all the columns of the partition function.
+func validatePartition(schemaName, tableName string, table *ast.CreateTableStmt) []error {
+	if table.Partition == nil {
+		return nil
+	}
+	errs := []error{}
+	if err := checkPartitionDefinitions(table.Partition, schemaName, tableName); err != nil {
+		errs = append(errs, err)
+	}
+
+	columns := getPartitionColumns(table.Partition)
+	for _, name := range columns {
+		if !hasColumn(table.Cols, strings.ToLower(name)) {
+			errs = append(errs, partitionColumnError(schemaName, tableName, name))
+		}
+	}
+	for _, key := range getTableKeys(table) {
+		if key.Tp != ast.ConstraintPrimaryKey && key.Tp != ast.ConstraintUniq &&
+			key.Tp != ast.ConstraintUniqKey && key.Tp != ast.ConstraintUniqIndex {
+			continue
+		}
+		for _, name := range columns {
+			if !hasKeyColumn(key, name) {
+				keyType := "UNIQUE INDEX"
+				if key.Tp == ast.ConstraintPrimaryKey {
+					keyType = "PRIMARY KEY"
+				}
+				errs = append(errs, newPartitionError(ErrUniqueKeyNeedAllPartitionColumns, schemaName, tableName, "", keyType,
+					fmt.Sprintf(UniqueKeyPartitionErrorPattern, getIndexName(key), schemaName, tableName)))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+func hasKeyColumn(key *ast.Constraint, columnName string) bool {
+	for _, part := range key.Keys {
+		if part.Column != nil && strings.EqualFold(part.Column.Name.O, columnName) {
+			return true
+		}
+	}
+	return false
+}
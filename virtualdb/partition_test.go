@@ -0,0 +1,166 @@
+package virtualdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRangeTable = "create table t1(a int, d date) partition by range (to_days(d)) (" +
+	"partition p202001 values less than (to_days('2020-02-01')), " +
+	"partition p202002 values less than (to_days('2020-03-01')), " +
+	"partition pmax values less than maxvalue);"
+
+const testRangeText = "CREATE TABLE `db1`.`t1` (`a` INT,`d` DATE) PARTITION BY RANGE (TO_DAYS(`d`)) (" +
+	"PARTITION `p202001` VALUES LESS THAN (TO_DAYS('2020-02-01'))," +
+	"PARTITION `p202002` VALUES LESS THAN (TO_DAYS('2020-03-01'))," +
+	"PARTITION `pmax` VALUES LESS THAN (MAXVALUE));\n"
+
+func TestAlterPartition(t *testing.T) {
+	cases := []alterCase{
+		{
+			name:   "create a partitioned table",
+			input:  testRangeTable,
+			expect: testRangeText,
+		},
+		{
+			name: "add a range partition",
+			input: "create table t1(a int) partition by range (a) (partition p0 values less than (10));" +
+				"alter table t1 add partition (partition p1 values less than (20));",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT) PARTITION BY RANGE (`a`) (" +
+				"PARTITION `p0` VALUES LESS THAN (10),PARTITION `p1` VALUES LESS THAN (20));\n",
+		},
+		{
+			name: "add a range partition which is not increasing",
+			input: "create table t1(a int) partition by range (a) (partition p0 values less than (10));" +
+				"alter table t1 add partition (partition p1 values less than (5));",
+			expectError: "range of partition p1 in db1.t1 is not greater than the previous partition",
+		},
+		{
+			name:        "add a range partition after maxvalue",
+			input:       testRangeTable + "alter table t1 add partition (partition p202003 values less than (to_days('2020-04-01')));",
+			expectError: "range of partition p202003 in db1.t1 is not greater than the previous partition",
+		},
+		{
+			name:        "add a duplicate partition",
+			input:       testRangeTable + "alter table t1 add partition (partition pmax values less than (1));",
+			expectError: "duplicate partition pmax in db1.t1",
+		},
+		{
+			name: "add a list partition with a used value",
+			input: "create table t1(a int) partition by list (a) (partition p0 values in (1, 2));" +
+				"alter table t1 add partition (partition p1 values in (2, 3));",
+			expectError: "value 2 is in more than one partition of db1.t1",
+		},
+		{
+			name:  "drop partitions",
+			input: testRangeTable + "alter table t1 drop partition p202001, p202002;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`d` DATE) PARTITION BY RANGE (TO_DAYS(`d`)) (" +
+				"PARTITION `pmax` VALUES LESS THAN (MAXVALUE));\n",
+		},
+		{
+			name:        "drop all partitions",
+			input:       testRangeTable + "alter table t1 drop partition p202001, p202002, pmax;",
+			expectError: "can't remove all partitions of db1.t1",
+		},
+		{
+			name:        "drop a partition which doesn't exist",
+			input:       testRangeTable + "alter table t1 drop partition p1;",
+			expectError: "not exist partition p1 in db1.t1",
+		},
+		{
+			name:        "drop a hash partition",
+			input:       "create table t1(a int) partition by hash (a) partitions 4; alter table t1 drop partition p0;",
+			expectError: "DROP PARTITION can't be used on HASH partitions of db1.t1",
+		},
+		{
+			name: "reorganize the last partition",
+			input: testRangeTable + "alter table t1 reorganize partition pmax into (" +
+				"partition p202003 values less than (to_days('2020-04-01')), partition pmax values less than maxvalue);",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`d` DATE) PARTITION BY RANGE (TO_DAYS(`d`)) (" +
+				"PARTITION `p202001` VALUES LESS THAN (TO_DAYS('2020-02-01'))," +
+				"PARTITION `p202002` VALUES LESS THAN (TO_DAYS('2020-03-01'))," +
+				"PARTITION `p202003` VALUES LESS THAN (TO_DAYS('2020-04-01'))," +
+				"PARTITION `pmax` VALUES LESS THAN (MAXVALUE));\n",
+		},
+		{
+			name: "reorganize a partition outside its range",
+			input: testRangeTable + "alter table t1 reorganize partition p202001 into (" +
+				"partition p1 values less than (to_days('2020-01-15')));",
+			expectError: "reorganized partitions of db1.t1 change the range",
+		},
+		{
+			name:   "add and coalesce hash partitions",
+			input:  "create table t1(a int) partition by hash (a) partitions 4; alter table t1 add partition partitions 2; alter table t1 coalesce partition 3;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT) PARTITION BY HASH (`a`) PARTITIONS 3;\n",
+		},
+		{
+			name:        "coalesce all hash partitions",
+			input:       "create table t1(a int) partition by key (a) partitions 2; alter table t1 coalesce partition 2;",
+			expectError: "can't remove all partitions of db1.t1",
+		},
+		{
+			name:        "coalesce range partitions",
+			input:       testRangeTable + "alter table t1 coalesce partition 1;",
+			expectError: "COALESCE PARTITION can't be used on RANGE partitions of db1.t1",
+		},
+		{
+			name:   "partition an existing table",
+			input:  "create table t1(a int); alter table t1 partition by key (a) partitions 2;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT) PARTITION BY KEY (`a`) PARTITIONS 2;\n",
+		},
+		{
+			name:   "remove partitioning",
+			input:  testRangeTable + "alter table t1 remove partitioning;",
+			expect: "CREATE TABLE `db1`.`t1` (`a` INT,`d` DATE);\n",
+		},
+		{
+			name:        "remove partitioning of a table which is not partitioned",
+			input:       "create table t1(a int); alter table t1 remove partitioning;",
+			expectError: "db1.t1 is not partitioned",
+		},
+		{
+			name:   "truncate a partition",
+			input:  testRangeTable + "alter table t1 truncate partition p202001;",
+			expect: testRangeText,
+		},
+		{
+			name:        "truncate a partition which doesn't exist",
+			input:       testRangeTable + "alter table t1 truncate partition p1;",
+			expectError: "not exist partition p1 in db1.t1",
+		},
+		{
+			name:   "partition by a column in upper case",
+			input:  "create table t1(A int); alter table t1 partition by hash (A) partitions 2;",
+			expect: "CREATE TABLE `db1`.`t1` (`A` INT) PARTITION BY HASH (`A`) PARTITIONS 2;\n",
+		},
+		{
+			name:        "drop a column of the partition function",
+			input:       testRangeTable + "alter table t1 drop column d;",
+			expectError: "not exist column d used in partition function of db1.t1",
+		},
+	}
+	testAlterCases(t, cases)
+}
+
+func TestPartitionError(t *testing.T) {
+	testSchemaError(t, testRangeTable+"alter table t1 drop partition p1;", ErrWrongPartitionList,
+		"ERROR 1507 (HY000): Error in list of partitions to DROP")
+	testSchemaError(t, testRangeTable+"alter table t1 truncate partition p1;", ErrUnknownPartition,
+		"ERROR 1735 (HY000): Unknown partition 'p1' in table 't1'")
+	testSchemaError(t, "create table t1(a int) partition by hash (a); alter table t1 drop partition p0;", ErrOnlyOnRangeList,
+		"ERROR 1512 (HY000): DROP PARTITION can only be used on RANGE/LIST partitions")
+}
+
+func TestValidatePartition(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int, b int, primary key (a)) partition by hash (b) partitions 2;"+
+		"create table t2(a int, b int, primary key (a, b)) partition by hash (b) partitions 2;"))
+
+	errs := vb.Validate()
+	assert.Equal(t, 1, len(errs))
+	assert.True(t, errors.Is(errs[0], &SchemaError{Kind: ErrUniqueKeyNeedAllPartitionColumns, Table: "t1"}))
+	assert.Equal(t, "ERROR 1503 (HY000): A PRIMARY KEY must include all columns in the table's partitioning function",
+		errs[0].(*SchemaError).MySQLMessage())
+}
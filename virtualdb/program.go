@@ -0,0 +1,342 @@
+package virtualdb
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sqlToken is a word, quoted name, string or punctuation of a statement.
+type sqlToken struct {
+	text  string
+	start int
+	end   int
+}
+
+// is reports whether the token is one of the keywords.
+func (t sqlToken) is(keywords ...string) bool {
+	for _, keyword := range keywords {
+		if strings.EqualFold(t.text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the name or string of the token without quotes.
+func (t sqlToken) value() string {
+	if len(t.text) < 2 {
+		return t.text
+	}
+	quote := t.text[0]
+	if quote != '`' && quote != '\'' && quote != '"' {
+		return t.text
+	}
+	s := t.text[1 : len(t.text)-1]
+	s = strings.Replace(s, string([]byte{quote, quote}), string(quote), -1)
+	if quote != '`' {
+		s = strings.Replace(s, `\`+string(quote), string(quote), -1)
+	}
+	return s
+}
+
+// tokenReader reads the tokens of the header of a statement, the comments are
+// removed by stmtScanner already.
+type tokenReader struct {
+	text string
+	pos  int
+}
+
+func (r *tokenReader) next() (sqlToken, bool) {
+	for r.pos < len(r.text) && unicode.IsSpace(rune(r.text[r.pos])) {
+		r.pos++
+	}
+	if r.pos >= len(r.text) {
+		return sqlToken{start: r.pos, end: r.pos}, false
+	}
+
+	start := r.pos
+	switch quote := r.text[r.pos]; {
+	case quote == '`' || quote == '\'' || quote == '"':
+		r.pos++
+		for r.pos < len(r.text) {
+			ch := r.text[r.pos]
+			r.pos++
+			if ch == '\\' && quote != '`' {
+				r.pos++
+				continue
+			}
+			if ch == quote {
+				if r.pos < len(r.text) && r.text[r.pos] == quote {
+					r.pos++
+					continue
+				}
+				break
+			}
+		}
+		if r.pos > len(r.text) {
+			r.pos = len(r.text)
+		}
+	case isWordByte(quote):
+		for r.pos < len(r.text) && isWordByte(r.text[r.pos]) {
+			r.pos++
+		}
+	default:
+		r.pos++
+	}
+	return sqlToken{text: r.text[start:r.pos], start: start, end: r.pos}, true
+}
+
+// peek returns the next token without reading it.
+func (r *tokenReader) peek() (sqlToken, bool) {
+	pos := r.pos
+	token, ok := r.next()
+	r.pos = pos
+	return token, ok
+}
+
+// accept reads the next tokens if they are the keywords.
+func (r *tokenReader) accept(keywords ...string) bool {
+	pos := r.pos
+	for _, keyword := range keywords {
+		token, ok := r.next()
+		if !ok || !token.is(keyword) {
+			r.pos = pos
+			return false
+		}
+	}
+	return true
+}
+
+// rest returns the text after the read tokens.
+func (r *tokenReader) rest() string {
+	return strings.TrimSpace(r.text[r.pos:])
+}
+
+func isWordByte(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 0x80 ||
+		(ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// CanonicalText returns the SQL with the tokens separated by one space and the
+// keywords and unquoted names in upper case, so the definitions which only
+// differ in whitespace and case have the same text.
+func CanonicalText(sql string) string {
+	r := &tokenReader{text: sql}
+	tokens := []string{}
+	for {
+		token, ok := r.next()
+		if !ok {
+			return strings.Join(tokens, " ")
+		}
+		if isWordByte(token.text[0]) {
+			tokens = append(tokens, strings.ToUpper(token.text))
+		} else {
+			tokens = append(tokens, token.text)
+		}
+	}
+}
+
+// readName reads a name which may be qualified by the schema.
+func (r *tokenReader) readName() (schemaName, name string, ok bool) {
+	token, ok := r.next()
+	if !ok {
+		return "", "", false
+	}
+	name = token.value()
+	if dot, ok := r.peek(); ok && dot.text == "." {
+		r.next()
+		token, ok := r.next()
+		if !ok {
+			return "", "", false
+		}
+		return name, token.value(), true
+	}
+	return "", name, true
+}
+
+// readDefiner reads the user of "DEFINER = user" and returns it in the form of
+// `user`@`host`, or CURRENT_USER.
+func (r *tokenReader) readDefiner() (string, bool) {
+	if !r.accept("=") {
+		return "", false
+	}
+	user, ok := r.next()
+	if !ok {
+		return "", false
+	}
+	if user.is("CURRENT_USER") {
+		r.accept("(", ")")
+		return "CURRENT_USER", true
+	}
+	definer := quoteName(user.value())
+	if at, ok := r.peek(); ok && at.text == "@" {
+		r.next()
+		host, ok := r.next()
+		if !ok {
+			return "", false
+		}
+		definer += "@" + quoteName(host.value())
+	} else if i := strings.Index(user.value(), "@"); i >= 0 {
+		definer = quoteName(user.value()[:i]) + "@" + quoteName(user.value()[i+1:])
+	}
+	return definer, true
+}
+
+// objectStmt is a CREATE, ALTER or DROP statement of a trigger, stored
+// procedure, function or event, or ALTER VIEW which the parser doesn't support.
+type objectStmt struct {
+	action      string
+	tp          ObjectType
+	ifExists    bool
+	ifNotExists bool
+	orReplace   bool
+	schema      string
+	name        string
+	definer     string
+	// tableSchema, table, timing and event are the table, BEFORE or AFTER,
+	// and INSERT, UPDATE or DELETE of a trigger.
+	tableSchema string
+	table       string
+	timing      string
+	event       string
+	// definition is the text after the name or the table of a trigger, for
+	// ALTER VIEW it is the text after ALTER.
+	definition string
+	// renameSchema and rename are the new name of ALTER EVENT ... RENAME TO.
+	renameSchema string
+	rename       string
+}
+
+var objectTypeKeywords = map[string]ObjectType{
+	"VIEW":      ObjectView,
+	"TRIGGER":   ObjectTrigger,
+	"PROCEDURE": ObjectProcedure,
+	"FUNCTION":  ObjectFunction,
+	"EVENT":     ObjectEvent,
+}
+
+// readObjectType reads the type of the object, the views are only accepted by
+// ALTER since the parser supports CREATE VIEW and DROP VIEW.
+func (r *tokenReader) readObjectType(action string) (ObjectType, bool) {
+	token, ok := r.next()
+	if !ok {
+		return 0, false
+	}
+	tp, ok := objectTypeKeywords[strings.ToUpper(token.text)]
+	if !ok || (tp == ObjectView && action != "ALTER") {
+		return 0, false
+	}
+	return tp, true
+}
+
+// parseObjectStmt parses the statements the parser doesn't support by their
+// header, it returns false if the statement is not one of them.
+func parseObjectStmt(text string) (*objectStmt, bool) {
+	r := &tokenReader{text: text}
+	action, ok := r.next()
+	if !ok || !action.is("CREATE", "ALTER", "DROP") {
+		return nil, false
+	}
+	stmt := &objectStmt{action: strings.ToUpper(action.text)}
+
+	switch stmt.action {
+	case "DROP":
+		tp, ok := r.readObjectType(stmt.action)
+		if !ok {
+			return nil, false
+		}
+		stmt.tp = tp
+		stmt.ifExists = r.accept("IF", "EXISTS")
+		if stmt.schema, stmt.name, ok = r.readName(); !ok {
+			return nil, false
+		}
+		return stmt, true
+
+	case "ALTER":
+		start := r.pos
+		for {
+			if r.accept("DEFINER") {
+				if stmt.definer, ok = r.readDefiner(); !ok {
+					return nil, false
+				}
+				continue
+			}
+			if r.accept("ALGORITHM", "=") || r.accept("SQL", "SECURITY") {
+				r.next()
+				continue
+			}
+			break
+		}
+		if stmt.tp, ok = r.readObjectType(stmt.action); !ok {
+			return nil, false
+		}
+		if stmt.tp == ObjectView {
+			stmt.definition = strings.TrimSpace(text[start:])
+			return stmt, true
+		}
+		if stmt.tp == ObjectTrigger {
+			return nil, false
+		}
+		if stmt.schema, stmt.name, ok = r.readName(); !ok {
+			return nil, false
+		}
+		stmt.definition = r.rest()
+		if stmt.tp == ObjectEvent {
+			stmt.readRename(r)
+		}
+		return stmt, true
+	}
+
+	stmt.orReplace = r.accept("OR", "REPLACE")
+	if r.accept("DEFINER") {
+		if stmt.definer, ok = r.readDefiner(); !ok {
+			return nil, false
+		}
+	}
+	if stmt.tp, ok = r.readObjectType(stmt.action); !ok {
+		return nil, false
+	}
+	stmt.ifNotExists = r.accept("IF", "NOT", "EXISTS")
+	if stmt.schema, stmt.name, ok = r.readName(); !ok {
+		return nil, false
+	}
+	if stmt.tp == ObjectTrigger {
+		timing, ok := r.next()
+		if !ok || !timing.is("BEFORE", "AFTER") {
+			return nil, false
+		}
+		event, ok := r.next()
+		if !ok || !event.is("INSERT", "UPDATE", "DELETE") || !r.accept("ON") {
+			return nil, false
+		}
+		stmt.timing, stmt.event = strings.ToUpper(timing.text), strings.ToUpper(event.text)
+		if stmt.tableSchema, stmt.table, ok = r.readName(); !ok {
+			return nil, false
+		}
+	}
+	stmt.definition = r.rest()
+	return stmt, true
+}
+
+// readRename removes "RENAME TO name" from the definition of ALTER EVENT, it
+// is before the body of DO.
+func (stmt *objectStmt) readRename(r *tokenReader) {
+	definitionStart := r.pos
+	for {
+		start := r.pos
+		token, ok := r.next()
+		if !ok || token.is("DO") {
+			return
+		}
+		if !token.is("RENAME") || !r.accept("TO") {
+			continue
+		}
+		schemaName, name, ok := r.readName()
+		if !ok {
+			return
+		}
+		stmt.renameSchema, stmt.rename = schemaName, name
+		stmt.definition = strings.TrimSpace(strings.TrimSpace(r.text[definitionStart:start]) + " " + r.rest())
+		return
+	}
+}
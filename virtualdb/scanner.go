@@ -0,0 +1,334 @@
+package virtualdb
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// rawStmt is the text of a statement split by stmtScanner.
+type rawStmt struct {
+	// index is the ordinal of the statement, starting from 1.
+	index int
+	// line and column are the start position of the statement, starting from 1.
+	line   int
+	column int
+	text   string
+}
+
+// stmtScanner splits SQL into statements like the mysql client: it handles
+// quotes, comments and the DELIMITER command. The executable comments
+// "/*!NNNNN ... */" are kept when NNNNN is not later than the server version,
+// other comments are removed.
+//
+// The input is read incrementally, and the text of the DML statements is not
+// kept except its beginning, so the memory is bounded by the largest DDL.
+type stmtScanner struct {
+	r             *bufio.Reader
+	serverVersion int
+	delimiter     string
+	index         int
+	// line and column are the position of the next rune.
+	line   int
+	column int
+}
+
+func newStmtScanner(r io.Reader, serverVersion int) *stmtScanner {
+	return &stmtScanner{
+		r:             bufio.NewReader(r),
+		serverVersion: serverVersion,
+		delimiter:     ";",
+		line:          1,
+		column:        1,
+	}
+}
+
+func (s *stmtScanner) readRune() (rune, error) {
+	r, _, err := s.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r == '\n' {
+		s.line++
+		s.column = 1
+	} else {
+		s.column++
+	}
+	return r, nil
+}
+
+// peek returns the next n bytes, it returns less at the end of input.
+func (s *stmtScanner) peek(n int) string {
+	b, _ := s.r.Peek(n)
+	return string(b)
+}
+
+func (s *stmtScanner) skip(n int) {
+	for i := 0; i < n; i++ {
+		if _, err := s.readRune(); err != nil {
+			return
+		}
+	}
+}
+
+// skipUntil skips the input until the end of the token, the token is skipped too.
+func (s *stmtScanner) skipUntil(token string) error {
+	for {
+		if s.peek(len(token)) == token {
+			s.skip(len(token))
+			return nil
+		}
+		if _, err := s.readRune(); err != nil {
+			return err
+		}
+	}
+}
+
+// readLine returns the rest of the current line.
+func (s *stmtScanner) readLine() (string, error) {
+	var sb strings.Builder
+	for {
+		r, err := s.readRune()
+		if err != nil || r == '\n' {
+			return sb.String(), err
+		}
+		sb.WriteRune(r)
+	}
+}
+
+// readVersion reads the version of an executable comment, 0 if there is none.
+func (s *stmtScanner) readVersion() int {
+	digits := 0
+	for digits < 6 {
+		b := s.peek(digits + 1)
+		if len(b) <= digits || b[digits] < '0' || b[digits] > '9' {
+			break
+		}
+		digits++
+	}
+	// the version has 5 digits, or 6 digits since MySQL 8.0.
+	if digits < 5 {
+		return 0
+	}
+	version, _ := strconv.Atoi(s.peek(digits))
+	s.skip(digits)
+	return version
+}
+
+// maxDataStmtBuffer is the max bytes kept for the text of a DML statement, only
+// the tail is kept to match the delimiter.
+const maxDataStmtBuffer = 4096
+
+// next returns the next statement, or io.EOF at the end of input. The text of
+// the DML statements is truncated to its beginning.
+func (s *stmtScanner) next() (*rawStmt, error) {
+	var sb strings.Builder
+	stmt := &rawStmt{}
+	started := false
+	// checked is set after the first word, discard is set if it starts a DML
+	// statement, whose text is replaced by the prefix.
+	checked := false
+	discard := false
+	prefix := ""
+	write := func(r rune) {
+		sb.WriteRune(r)
+		if discard && sb.Len() > maxDataStmtBuffer {
+			tail := sb.String()[sb.Len()-len(s.delimiter):]
+			sb.Reset()
+			sb.WriteString(tail)
+		}
+	}
+	text := func() string {
+		if discard {
+			return prefix
+		}
+		return sb.String()
+	}
+	// quote is the open quote, 0 if not in a string or a quoted identifier.
+	var quote rune
+	inExecComment := false
+	// normalRunes is the number of trailing runes not in quotes, the delimiter is
+	// only matched there.
+	normalRunes := 0
+
+	for {
+		line, column := s.line, s.column
+		r, err := s.readRune()
+		if err == io.EOF {
+			stmt.text = strings.TrimSpace(text())
+			if stmt.text == "" {
+				return nil, io.EOF
+			}
+			s.index++
+			stmt.index = s.index
+			return stmt, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if quote != 0 {
+			write(r)
+			normalRunes = 0
+			switch {
+			case r == '\\' && quote != '`':
+				if next, err := s.readRune(); err == nil {
+					write(next)
+				}
+			case r == quote && s.peek(1) == string(quote):
+				s.skip(1)
+				write(r)
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		if !started {
+			if unicode.IsSpace(r) {
+				continue
+			}
+			if (r == 'd' || r == 'D') && isDelimiterCommand(s.peek(9)) {
+				rest, err := s.readLine()
+				if fields := strings.Fields(rest); len(fields) > 1 {
+					s.delimiter = fields[1]
+				}
+				if err != nil && err != io.EOF {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		switch {
+		case r == '#' || (r == '-' && s.peek(1) == "-" && isCommentSpace(s.peek(2))):
+			if _, err := s.readLine(); err != nil && err != io.EOF {
+				return nil, err
+			}
+			if started {
+				sb.WriteRune('\n')
+			}
+			normalRunes = 0
+			continue
+		case r == '/' && s.peek(2) == "*!":
+			s.skip(2)
+			version := s.readVersion()
+			if version > s.serverVersion {
+				if err := s.skipUntil("*/"); err != nil && err != io.EOF {
+					return nil, err
+				}
+				sb.WriteRune(' ')
+			} else {
+				inExecComment = true
+				if !started {
+					started = true
+					stmt.line, stmt.column = line, column
+				}
+			}
+			normalRunes = 0
+			continue
+		case r == '/' && s.peek(2) == "*+":
+			// optimizer hints are kept.
+		case r == '/' && s.peek(1) == "*":
+			if err := s.skipUntil("*/"); err != nil && err != io.EOF {
+				return nil, err
+			}
+			sb.WriteRune(' ')
+			normalRunes = 0
+			continue
+		case r == '*' && inExecComment && s.peek(1) == "/":
+			s.skip(1)
+			inExecComment = false
+			sb.WriteRune(' ')
+			normalRunes = 0
+			continue
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		}
+
+		if !started {
+			started = true
+			stmt.line, stmt.column = line, column
+		}
+		if word := strings.TrimSpace(sb.String()); !checked && word != "" && !unicode.IsLetter(r) {
+			checked = true
+			if dmlStmtPattern.MatchString(word) {
+				discard = true
+				prefix = word
+				sb.Reset()
+			}
+		}
+		write(r)
+		if quote != 0 {
+			normalRunes = 0
+			continue
+		}
+		normalRunes++
+		if normalRunes >= len(s.delimiter) && strings.HasSuffix(sb.String(), s.delimiter) {
+			stmtText := sb.String()
+			stmtText = strings.TrimSpace(stmtText[:len(stmtText)-len(s.delimiter)])
+			if discard {
+				stmtText = strings.TrimSpace(prefix)
+			}
+			if stmtText == "" {
+				sb.Reset()
+				started = false
+				checked = false
+				normalRunes = 0
+				continue
+			}
+			s.index++
+			stmt.index = s.index
+			stmt.text = stmtText
+			return stmt, nil
+		}
+	}
+}
+
+// isDelimiterCommand reports whether the text after "D" is "ELIMITER" followed by a whitespace.
+func isDelimiterCommand(text string) bool {
+	return len(text) == 9 && strings.EqualFold(text[:8], "ELIMITER") && unicode.IsSpace(rune(text[8]))
+}
+
+// isCommentSpace reports whether "--" followed by s starts a comment.
+func isCommentSpace(s string) bool {
+	return len(s) < 2 || s[1] == ' ' || s[1] == '\t' || s[1] == '\n' || s[1] == '\r'
+}
+
+var (
+	dataStmtPattern = regexp.MustCompile(`(?i)^(INSERT|REPLACE|UPDATE|DELETE|LOCK|UNLOCK|SET|LOAD)\b`)
+	dmlStmtPattern  = regexp.MustCompile(`(?i)^(INSERT|REPLACE|UPDATE|DELETE|LOAD)$`)
+	// objectStmtPattern matches the statements of ExecSQL which need to be split
+	// by stmtScanner, such as CREATE TRIGGER, ALTER VIEW and DELIMITER.
+	objectStmtPattern = regexp.MustCompile(`(?im)^\s*(DELIMITER\s|(CREATE|ALTER|DROP)\b.*?\b(VIEW|TRIGGER|PROCEDURE|FUNCTION|EVENT)\b)`)
+)
+
+// isSkippedDumpStmt reports whether the statement of a dump doesn't change the
+// schema, such as the data and session statements.
+func isSkippedDumpStmt(text string) bool {
+	return dataStmtPattern.MatchString(text)
+}
+
+// hasObjectStmt reports whether sql has the statements the parser doesn't support.
+func hasObjectStmt(sql string) bool {
+	return objectStmtPattern.MatchString(sql)
+}
+
+// scanStmts splits sql into statements.
+func scanStmts(sql string, serverVersion int) ([]*rawStmt, error) {
+	scanner := newStmtScanner(strings.NewReader(sql), serverVersion)
+	raws := []*rawStmt{}
+	for {
+		raw, err := scanner.next()
+		if err == io.EOF {
+			return raws, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	}
+}
@@ -0,0 +1,109 @@
+package virtualdb
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testScanStmts(t *testing.T, serverVersion int, input string, expect ...string) {
+	scanner := newStmtScanner(strings.NewReader(input), serverVersion)
+	actual := []string{}
+	for {
+		stmt, err := scanner.next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		actual = append(actual, stmt.text)
+	}
+	if len(expect) == 0 {
+		expect = []string{}
+	}
+	assert.Equal(t, expect, actual)
+}
+
+func TestStmtScanner(t *testing.T) {
+	testScanStmts(t, DefaultServerVersion, "select 1; select 2;select 3",
+		"select 1", "select 2", "select 3")
+
+	testScanStmts(t, DefaultServerVersion, `select ';', "a;b", 'it''s;', 'x\';', `+"`a;`"+`;`,
+		`select ';', "a;b", 'it''s;', 'x\';', `+"`a;`")
+
+	testScanStmts(t, DefaultServerVersion, "-- comment;\n# comment;\nselect 1 /* ; */ + 1; -- tail\nselect 2--1;",
+		"select 1   + 1", "select 2--1")
+
+	testScanStmts(t, DefaultServerVersion, "select /*+ MAX_EXECUTION_TIME(1) */ 1;",
+		"select /*+ MAX_EXECUTION_TIME(1) */ 1")
+
+	testScanStmts(t, 50744, "/*!40101 SET NAMES utf8 */;\n/*!80016 SET x = 1 */;\nCREATE DATABASE /*!32312 IF NOT EXISTS*/ d /*!80016 DEFAULT ENCRYPTION='N' */;",
+		"SET NAMES utf8", "CREATE DATABASE  IF NOT EXISTS  d")
+
+	testScanStmts(t, DefaultServerVersion, `DELIMITER ;;
+CREATE TRIGGER tr BEFORE INSERT ON t1 FOR EACH ROW BEGIN SET NEW.a = 1; SET NEW.b = 2; END ;;
+DELIMITER ;
+select 1;`,
+		"CREATE TRIGGER tr BEFORE INSERT ON t1 FOR EACH ROW BEGIN SET NEW.a = 1; SET NEW.b = 2; END",
+		"select 1")
+
+	testScanStmts(t, DefaultServerVersion, "delimiter\t$$\nselect 1$$\nDELIMITER\t;\nselect 2;",
+		"select 1", "select 2")
+}
+
+func TestStmtScannerPosition(t *testing.T) {
+	scanner := newStmtScanner(strings.NewReader("select 1;\n  -- c\n  /*!40101 select 2 */;  select 3;"), DefaultServerVersion)
+	positions := [][3]int{}
+	for {
+		stmt, err := scanner.next()
+		if err != nil {
+			break
+		}
+		positions = append(positions, [3]int{stmt.index, stmt.line, stmt.column})
+	}
+	assert.Equal(t, [][3]int{{1, 1, 1}, {2, 3, 3}, {3, 3, 26}}, positions)
+}
+
+// valuesReader generates the rows of an INSERT without keeping them in memory.
+type valuesReader struct {
+	rows int
+	buf  []byte
+}
+
+func (r *valuesReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.rows == 0 {
+			return 0, io.EOF
+		}
+		r.rows--
+		r.buf = []byte("(1,'a;b',\"c'd\"),")
+		if r.rows == 0 {
+			r.buf = []byte("(1,'x');\ncreate table t2(a int);\n")
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func TestStmtScannerSkipsDML(t *testing.T) {
+	reader := io.MultiReader(strings.NewReader("create table t1(a int);\n/*!40000 INSERT INTO t1 VALUES "),
+		&valuesReader{rows: 100000})
+	scanner := newStmtScanner(reader, DefaultServerVersion)
+
+	texts := []string{}
+	for {
+		stmt, err := scanner.next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		texts = append(texts, stmt.text)
+	}
+	assert.Equal(t, []string{"create table t1(a int)", "INSERT", "create table t2(a int)"}, texts)
+}
@@ -0,0 +1,660 @@
+package virtualdb
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+	"github.com/pingcap/parser/mysql"
+)
+
+// ResultSet is the result of a SHOW statement, with the column names and the
+// rows of MySQL. The values which are not Valid are NULL.
+type ResultSet struct {
+	Columns []string
+	Rows    [][]sql.NullString
+}
+
+// Query answers the SHOW statements like MySQL: SHOW DATABASES, SHOW [FULL]
+// TABLES, SHOW CREATE TABLE, SHOW [FULL] COLUMNS, DESCRIBE and SHOW INDEX. The
+// other statements and the WHERE clauses are not supported.
+func (c *VirtualDB) Query(node ast.StmtNode) (*ResultSet, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	switch s := node.(type) {
+	case *ast.ShowStmt:
+		if s.Where != nil {
+			break
+		}
+		switch s.Tp {
+		case ast.ShowDatabases:
+			return c.showDatabases(s)
+		case ast.ShowTables:
+			return c.showTables(s)
+		case ast.ShowCreateTable:
+			return c.showCreateTable(s)
+		case ast.ShowColumns:
+			return c.showColumns(s)
+		case ast.ShowIndex:
+			return c.showIndex(s)
+		}
+
+	case *ast.ExplainStmt:
+		// DESCRIBE is parsed as EXPLAIN of SHOW COLUMNS.
+		if s, ok := s.Stmt.(*ast.ShowStmt); ok && s.Tp == ast.ShowColumns {
+			return c.showColumns(s)
+		}
+	}
+	return nil, fmt.Errorf("stmt not support")
+}
+
+// QuerySQL answers a SHOW statement, see Query.
+func (c *VirtualDB) QuerySQL(sql string) (*ResultSet, error) {
+	stmt, err := parser.New().ParseOneStmt(sql, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return c.Query(stmt)
+}
+
+func (c *VirtualDB) showDatabases(s *ast.ShowStmt) (*ResultSet, error) {
+	match, title, err := c.likeMatcher(s.Pattern, "Database", c.lowerCaseTableNames != CaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+	rs := &ResultSet{Columns: []string{title}}
+	for _, schemaKey := range sortedSchemaNames(c.schemas) {
+		name := c.schemas[schemaKey].Schema.Name
+		// the unnamed default schema is not a real database.
+		if schemaKey != "" && match(name) {
+			rs.Rows = append(rs.Rows, []sql.NullString{validString(name)})
+		}
+	}
+	return rs, nil
+}
+
+func (c *VirtualDB) showTables(s *ast.ShowStmt) (*ResultSet, error) {
+	schemaName := s.DBName
+	if schemaName == "" {
+		schemaName = c.currentSchema
+	}
+	if schemaName == "" {
+		return nil, noDatabaseSelectedError()
+	}
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return nil, unknownDatabaseError(schemaName)
+	}
+	match, title, err := c.likeMatcher(s.Pattern, "Tables_in_"+schema.Schema.Name,
+		c.lowerCaseTableNames != CaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &ResultSet{Columns: []string{title}}
+	if s.Full {
+		rs.Columns = append(rs.Columns, "Table_type")
+	}
+	is := c.schemaInformation(schema)
+	for _, table := range is.Tables {
+		if !match(table.TableName) {
+			continue
+		}
+		row := []sql.NullString{validString(table.TableName)}
+		if s.Full {
+			row = append(row, validString(table.TableType))
+		}
+		rs.Rows = append(rs.Rows, row)
+	}
+	return rs, nil
+}
+
+func (c *VirtualDB) showCreateTable(s *ast.ShowStmt) (*ResultSet, error) {
+	schema, table, view, err := c.showTable(s)
+	if err != nil {
+		return nil, err
+	}
+	if view != nil {
+		text, err := restoreToSql(view)
+		if err != nil {
+			return nil, err
+		}
+		charset := ServerDefaultCharset(c.serverVersion)
+		return &ResultSet{
+			Columns: []string{"View", "Create View", "character_set_client", "collation_connection"},
+			Rows: [][]sql.NullString{{
+				validString(view.ViewName.Name.O), validString(text),
+				validString(charset), validString(DefaultCollation(charset, c.serverVersion)),
+			}},
+		}, nil
+	}
+	return &ResultSet{
+		Columns: []string{"Table", "Create Table"},
+		Rows: [][]sql.NullString{{
+			validString(table.Table.Name.O), validString(c.tableDefinition(schema.Schema, table)),
+		}},
+	}, nil
+}
+
+func (c *VirtualDB) showColumns(s *ast.ShowStmt) (*ResultSet, error) {
+	schema, table, view, err := c.showTable(s)
+	if err != nil {
+		return nil, err
+	}
+	pattern := s.Pattern
+	if s.Column != nil {
+		// DESCRIBE t col matches the columns by the pattern col.
+		pattern = &ast.PatternLikeExpr{Pattern: ast.NewValueExpr(s.Column.Name.O, "", "")}
+	}
+	match, _, err := c.likeMatcher(pattern, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	is := &InformationSchema{}
+	if view != nil {
+		c.addViewRows(is, schema.Schema, view)
+	} else {
+		c.addTableRows(is, schema.Schema, table)
+	}
+	rs := &ResultSet{Columns: []string{"Field", "Type", "Null", "Key", "Default", "Extra"}}
+	if s.Full {
+		rs.Columns = []string{"Field", "Type", "Collation", "Null", "Key", "Default", "Extra", "Privileges", "Comment"}
+	}
+	for _, col := range is.Columns {
+		if !match(col.ColumnName) {
+			continue
+		}
+		row := []sql.NullString{validString(col.ColumnName), validString(col.ColumnType)}
+		if s.Full {
+			row = append(row, nullString(col.CollationName))
+		}
+		row = append(row, validString(col.IsNullable), validString(col.ColumnKey), nullString(col.ColumnDefault),
+			validString(col.Extra))
+		if s.Full {
+			row = append(row, validString(col.Privileges), validString(col.ColumnComment))
+		}
+		rs.Rows = append(rs.Rows, row)
+	}
+	return rs, nil
+}
+
+func (c *VirtualDB) showIndex(s *ast.ShowStmt) (*ResultSet, error) {
+	schema, table, _, err := c.showTable(s)
+	if err != nil {
+		return nil, err
+	}
+	rs := &ResultSet{Columns: []string{"Table", "Non_unique", "Key_name", "Seq_in_index", "Column_name",
+		"Collation", "Cardinality", "Sub_part", "Packed", "Null", "Index_type", "Comment", "Index_comment"}}
+	// the visibility of the indexes is added in MySQL 8.0, the functional key parts in 8.0.13.
+	if c.serverVersion >= 80000 {
+		rs.Columns = append(rs.Columns, "Visible")
+	}
+	if c.serverVersion >= 80013 {
+		rs.Columns = append(rs.Columns, "Expression")
+	}
+	// the views have no indexes.
+	if table == nil {
+		return rs, nil
+	}
+
+	is := &InformationSchema{}
+	c.addTableRows(is, schema.Schema, table)
+	for _, index := range is.Statistics {
+		var subPart sql.NullString
+		if index.SubPart != nil {
+			subPart = validString(fmt.Sprint(*index.SubPart))
+		}
+		row := []sql.NullString{
+			validString(index.TableName), validString(fmt.Sprint(index.NonUnique)), validString(index.IndexName),
+			validString(fmt.Sprint(index.SeqInIndex)), nullString(index.ColumnName), nullString(index.Collation),
+			validString("0"), subPart, {}, validString(index.Nullable), validString(index.IndexType),
+			validString(index.Comment), validString(index.IndexComment),
+		}
+		if c.serverVersion >= 80000 {
+			row = append(row, validString(index.IsVisible))
+		}
+		if c.serverVersion >= 80013 {
+			row = append(row, nullString(index.Expression))
+		}
+		rs.Rows = append(rs.Rows, row)
+	}
+	return rs, nil
+}
+
+// showTable returns the table or view which the SHOW statement shows.
+func (c *VirtualDB) showTable(s *ast.ShowStmt) (*SchemaInfo, *ast.CreateTableStmt, *ast.CreateViewStmt, error) {
+	schemaName := s.DBName
+	if schemaName == "" {
+		schemaName = c.getSchemaName(s.Table)
+	}
+	if schemaName == "" {
+		return nil, nil, nil, noDatabaseSelectedError()
+	}
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return nil, nil, nil, unknownDatabaseError(schemaName)
+	}
+	tableKey := c.nameKey(s.Table.Name.O)
+	if info, ok := schema.Tables[tableKey]; ok {
+		return schema, info.Table, nil, nil
+	}
+	if info, ok := schema.Views[tableKey]; ok {
+		return schema, nil, info.View, nil
+	}
+	return nil, nil, nil, noSuchTableError(schemaName, s.Table.Name.O)
+}
+
+// schemaInformation returns the rows of TABLES of the schema.
+func (c *VirtualDB) schemaInformation(schema *SchemaInfo) *InformationSchema {
+	is := &InformationSchema{}
+	for _, name := range sortedTableAndViewNames(schema) {
+		if info, ok := schema.Tables[name]; ok {
+			is.Tables = append(is.Tables, TablesRow{TableName: info.Table.Table.Name.O, TableType: "BASE TABLE"})
+			continue
+		}
+		is.Tables = append(is.Tables, TablesRow{TableName: schema.Views[name].View.ViewName.Name.O, TableType: "VIEW"})
+	}
+	return is
+}
+
+// likeMatcher returns the function matching the names by the pattern of LIKE,
+// and the title of the column with the pattern, such as "Database (db%)".
+func (c *VirtualDB) likeMatcher(like *ast.PatternLikeExpr, title string, ignoreCase bool) (func(string) bool, string, error) {
+	if like == nil {
+		return func(string) bool { return true }, title, nil
+	}
+	pattern, ok := defaultLiteralString(like.Pattern)
+	if !ok {
+		return nil, "", fmt.Errorf("stmt not support")
+	}
+
+	escape := rune(like.Escape)
+	if escape == 0 {
+		escape = '\\'
+	}
+	var sb strings.Builder
+	if ignoreCase {
+		sb.WriteString("(?i)")
+	}
+	sb.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == escape:
+			escaped = true
+		case r == '%':
+			sb.WriteString(".*")
+		case r == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, "", err
+	}
+	return re.MatchString, fmt.Sprintf("%s (%s)", title, pattern), nil
+}
+
+// tableDefinition returns the statement of SHOW CREATE TABLE, in the layout of
+// MySQL with one column, index or constraint per line.
+func (c *VirtualDB) tableDefinition(schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt) string {
+	table = NormalizePrimaryKey(table)
+	tableCharset, tableCollation := TableCharset(schema, table, c.serverVersion)
+
+	lines := []string{}
+	for i, col := range table.Cols {
+		lines = append(lines, c.columnDefinition(schema, table, col, i+1, tableCharset))
+	}
+	for _, index := range tableIndexes(table) {
+		lines = append(lines, indexDefinition(index))
+	}
+	for _, constraint := range table.Constraints {
+		switch constraint.Tp {
+		case ast.ConstraintForeignKey:
+			lines = append(lines, c.foreignKeyDefinition(schema.Name, table, constraint))
+		case ast.ConstraintCheck:
+			line := fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quoteName(constraint.Name), showExpr(constraint.Expr))
+			if !constraint.Enforced {
+				line += " /*!80016 NOT ENFORCED */"
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CREATE TABLE %s (\n  %s\n)", quoteName(table.Table.Name.O), strings.Join(lines, ",\n  "))
+
+	engine := "InnoDB"
+	var autoIncrement, keyBlockSize uint64
+	rowFormat, comment := "", ""
+	for _, op := range table.Options {
+		switch op.Tp {
+		case ast.TableOptionEngine:
+			engine = engineName(op.StrValue)
+		case ast.TableOptionAutoIncrement:
+			autoIncrement = op.UintValue
+		case ast.TableOptionRowFormat:
+			rowFormat = strings.ToUpper(rowFormatNames[op.UintValue])
+		case ast.TableOptionKeyBlockSize:
+			keyBlockSize = op.UintValue
+		case ast.TableOptionComment:
+			comment = op.StrValue
+		}
+	}
+	fmt.Fprintf(&sb, " ENGINE=%s", engine)
+	if autoIncrement > 1 {
+		fmt.Fprintf(&sb, " AUTO_INCREMENT=%d", autoIncrement)
+	}
+	fmt.Fprintf(&sb, " DEFAULT CHARSET=%s", tableCharset)
+	// MySQL 8.0 always shows the collation, 5.7 only shows the collation which
+	// is not the default of the charset.
+	if c.serverVersion >= 80000 || tableCollation != DefaultCollation(tableCharset, c.serverVersion) {
+		fmt.Fprintf(&sb, " COLLATE=%s", tableCollation)
+	}
+	if rowFormat != "" {
+		fmt.Fprintf(&sb, " ROW_FORMAT=%s", rowFormat)
+	}
+	if keyBlockSize > 0 {
+		fmt.Fprintf(&sb, " KEY_BLOCK_SIZE=%d", keyBlockSize)
+	}
+	if comment != "" {
+		fmt.Fprintf(&sb, " COMMENT=%s", quoteString(comment))
+	}
+	if table.Partition != nil {
+		sb.WriteString("\n")
+		sb.WriteString(partitionDefinition(table.Partition, engine))
+	}
+	return sb.String()
+}
+
+// partitionDefinition returns the PARTITION BY clause in SHOW CREATE TABLE, each
+// partition is in its own line with the engine of the table.
+func partitionDefinition(partition *ast.PartitionOptions, engine string) string {
+	// the COLUMNS partitioning is added in MySQL 5.5.
+	version := 50100
+	if partition.Expr == nil && (partition.Tp == model.PartitionTypeRange || partition.Tp == model.PartitionTypeList) {
+		version = 50500
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "/*!%d PARTITION BY %s", version, partitionMethod(&partition.PartitionMethod))
+	if partition.Sub != nil {
+		fmt.Fprintf(&sb, "\nSUBPARTITION BY %s", partitionMethod(partition.Sub))
+		if partition.Sub.Num > 0 && !hasSubPartitions(partition.Definitions) {
+			fmt.Fprintf(&sb, "\nSUBPARTITIONS %d", partition.Sub.Num)
+		}
+	}
+	if len(partition.Definitions) == 0 {
+		if partition.Num > 0 {
+			fmt.Fprintf(&sb, "\nPARTITIONS %d", partition.Num)
+		}
+		sb.WriteString(" */")
+		return sb.String()
+	}
+
+	definitions := []string{}
+	for _, def := range partition.Definitions {
+		line := "PARTITION " + def.Name.O + partitionValues(def.Clause)
+		if comment, ok := def.Comment(); ok {
+			line += " COMMENT = " + quoteString(comment)
+		}
+		if len(def.Sub) == 0 {
+			definitions = append(definitions, line+" ENGINE = "+engine)
+			continue
+		}
+		subs := []string{}
+		for _, sub := range def.Sub {
+			subs = append(subs, "SUBPARTITION "+sub.Name.O+" ENGINE = "+engine)
+		}
+		definitions = append(definitions, line+"\n ("+strings.Join(subs, ",\n  ")+")")
+	}
+	fmt.Fprintf(&sb, "\n(%s) */", strings.Join(definitions, ",\n "))
+	return sb.String()
+}
+
+// partitionMethod returns the method of PARTITION BY or SUBPARTITION BY, such
+// as "RANGE (`a`)" or "RANGE  COLUMNS(`a`,`b`)" like MySQL.
+func partitionMethod(method *ast.PartitionMethod) string {
+	prefix := ""
+	if method.Linear {
+		prefix = "LINEAR "
+	}
+	if method.Expr != nil {
+		return fmt.Sprintf("%s%s (%s)", prefix, method.Tp.String(), showExpr(method.Expr))
+	}
+
+	columns := []string{}
+	for _, col := range method.ColumnNames {
+		columns = append(columns, quoteName(col.Name.O))
+	}
+	if method.Tp == model.PartitionTypeRange || method.Tp == model.PartitionTypeList {
+		return fmt.Sprintf("%s%s  COLUMNS(%s)", prefix, method.Tp.String(), strings.Join(columns, ","))
+	}
+	return fmt.Sprintf("%s%s (%s)", prefix, method.Tp.String(), strings.Join(columns, ","))
+}
+
+// partitionValues returns the VALUES clause of a partition, MAXVALUE alone is
+// not enclosed by parentheses.
+func partitionValues(clause ast.PartitionDefinitionClause) string {
+	switch c := clause.(type) {
+	case *ast.PartitionDefinitionClauseLessThan:
+		if len(c.Exprs) == 1 {
+			if _, ok := c.Exprs[0].(*ast.MaxValueExpr); ok {
+				return " VALUES LESS THAN MAXVALUE"
+			}
+		}
+		return " VALUES LESS THAN (" + partitionValueList(c.Exprs) + ")"
+	case *ast.PartitionDefinitionClauseIn:
+		values := []string{}
+		for _, value := range c.Values {
+			if len(value) == 1 {
+				values = append(values, partitionValueList(value))
+			} else {
+				values = append(values, "("+partitionValueList(value)+")")
+			}
+		}
+		return " VALUES IN (" + strings.Join(values, ",") + ")"
+	}
+	return ""
+}
+
+func partitionValueList(exprs []ast.ExprNode) string {
+	values := []string{}
+	for _, expr := range exprs {
+		if _, ok := expr.(*ast.MaxValueExpr); ok {
+			values = append(values, "MAXVALUE")
+		} else {
+			values = append(values, showExpr(expr))
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+func hasSubPartitions(definitions []*ast.PartitionDefinition) bool {
+	for _, def := range definitions {
+		if len(def.Sub) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// columnDefinition returns the definition of the column in SHOW CREATE TABLE.
+func (c *VirtualDB) columnDefinition(schema *ast.CreateDatabaseStmt, table *ast.CreateTableStmt, col *ast.ColumnDef,
+	position int, tableCharset string) string {
+
+	row := c.columnsRow(schema, table, col, position, "")
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s", quoteName(row.ColumnName), row.ColumnType)
+	if row.CharacterSetName != nil {
+		if *row.CharacterSetName != tableCharset {
+			fmt.Fprintf(&sb, " CHARACTER SET %s", *row.CharacterSetName)
+		}
+		if *row.CollationName != DefaultCollation(*row.CharacterSetName, c.serverVersion) {
+			fmt.Fprintf(&sb, " COLLATE %s", *row.CollationName)
+		}
+	}
+	generated := getGeneratedOption(col)
+	if generated != nil {
+		kind := "VIRTUAL"
+		if generated.Stored {
+			kind = "STORED"
+		}
+		fmt.Fprintf(&sb, " GENERATED ALWAYS AS (%s) %s", showExpr(generated.Expr), kind)
+	}
+
+	nullable := row.IsNullable == "YES"
+	if !nullable {
+		sb.WriteString(" NOT NULL")
+	} else if col.Tp.Tp == mysql.TypeTimestamp {
+		sb.WriteString(" NULL")
+	}
+	autoIncrement := hasOneInOptions(col.Options, ast.ColumnOptionAutoIncrement)
+	hasDefault := false
+	for _, op := range col.Options {
+		if op.Tp == ast.ColumnOptionDefaultValue {
+			hasDefault = true
+			fmt.Fprintf(&sb, " DEFAULT %s", showDefault(op.Expr))
+		}
+	}
+	// the nullable columns default to NULL, except the columns which can't have a default.
+	if !hasDefault && nullable && generated == nil && !autoIncrement && !isBlobType(col.Tp.Tp) {
+		sb.WriteString(" DEFAULT NULL")
+	}
+	for _, op := range col.Options {
+		if op.Tp == ast.ColumnOptionOnUpdate {
+			fmt.Fprintf(&sb, " ON UPDATE %s", defaultExprText(op.Expr))
+		}
+	}
+	if autoIncrement {
+		sb.WriteString(" AUTO_INCREMENT")
+	}
+	if row.ColumnComment != "" {
+		fmt.Fprintf(&sb, " COMMENT %s", quoteString(row.ColumnComment))
+	}
+	return sb.String()
+}
+
+// indexDefinition returns the definition of the index in SHOW CREATE TABLE.
+func indexDefinition(index *ast.Constraint) string {
+	var sb strings.Builder
+	name := quoteName(getIndexName(index))
+	switch index.Tp {
+	case ast.ConstraintPrimaryKey:
+		sb.WriteString("PRIMARY KEY")
+	case ast.ConstraintUniq, ast.ConstraintUniqKey, ast.ConstraintUniqIndex:
+		sb.WriteString("UNIQUE KEY " + name)
+	case ast.ConstraintFulltext:
+		sb.WriteString("FULLTEXT KEY " + name)
+	default:
+		sb.WriteString("KEY " + name)
+	}
+	fmt.Fprintf(&sb, " (%s)", keyPartsDefinition(index.Keys))
+
+	if op := index.Option; op != nil {
+		if op.Tp != model.IndexTypeInvalid {
+			sb.WriteString(" USING " + strings.ToUpper(op.Tp.String()))
+		}
+		if op.KeyBlockSize > 0 {
+			fmt.Fprintf(&sb, " KEY_BLOCK_SIZE=%d", op.KeyBlockSize)
+		}
+		if op.ParserName.O != "" {
+			fmt.Fprintf(&sb, " /*!50100 WITH PARSER %s */", quoteName(op.ParserName.O))
+		}
+		if op.Comment != "" {
+			fmt.Fprintf(&sb, " COMMENT %s", quoteString(op.Comment))
+		}
+		if op.Visibility == ast.IndexVisibilityInvisible {
+			sb.WriteString(" /*!80000 INVISIBLE */")
+		}
+	}
+	return sb.String()
+}
+
+// foreignKeyDefinition returns the definition of the foreign key in SHOW CREATE
+// TABLE, the referenced table is qualified when it is in another schema.
+func (c *VirtualDB) foreignKeyDefinition(schemaName string, table *ast.CreateTableStmt, fk *ast.Constraint) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CONSTRAINT %s FOREIGN KEY (%s)", quoteName(foreignKeyName(table, fk)), keyPartsDefinition(fk.Keys))
+	if fk.Refer == nil {
+		return sb.String()
+	}
+	refTable := quoteName(fk.Refer.Table.Name.O)
+	if refSchema := fk.Refer.Table.Schema.O; refSchema != "" && c.nameKey(refSchema) != c.nameKey(schemaName) {
+		refTable = quoteName(refSchema) + "." + refTable
+	}
+	fmt.Fprintf(&sb, " REFERENCES %s (%s)", refTable, keyPartsDefinition(fk.Refer.IndexPartSpecifications))
+	// RESTRICT and NO ACTION are the default, which MySQL doesn't show.
+	if op := fk.Refer.OnDelete; op != nil && isShownReferOption(op.ReferOpt) {
+		sb.WriteString(" ON DELETE " + op.ReferOpt.String())
+	}
+	if op := fk.Refer.OnUpdate; op != nil && isShownReferOption(op.ReferOpt) {
+		sb.WriteString(" ON UPDATE " + op.ReferOpt.String())
+	}
+	return sb.String()
+}
+
+func isShownReferOption(op ast.ReferOptionType) bool {
+	return op == ast.ReferOptionCascade || op == ast.ReferOptionSetNull || op == ast.ReferOptionSetDefault
+}
+
+func keyPartsDefinition(keys []*ast.IndexPartSpecification) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key.Column == nil {
+			parts = append(parts, "("+showExpr(key.Expr)+")")
+			continue
+		}
+		part := quoteName(key.Column.Name.O)
+		if key.Length > 0 {
+			part += fmt.Sprintf("(%d)", key.Length)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+// showDefault returns the default value in SHOW CREATE TABLE, the literals are
+// quoted and the other expressions are in parentheses.
+func showDefault(expr ast.ExprNode) string {
+	value, isNull, ok := defaultLiteral(expr)
+	switch {
+	case isNull:
+		return "NULL"
+	case ok:
+		return quoteString(value)
+	}
+	if text := defaultExprText(expr); strings.HasPrefix(text, "CURRENT_TIMESTAMP") {
+		return text
+	}
+	return "(" + showExpr(expr) + ")"
+}
+
+// quoteString quotes the string like MySQL shows the comments and defaults.
+func quoteString(s string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "'", "''", "\n", "\\n", "\r", "\\r", "\x00", "\\0")
+	return "'" + replacer.Replace(s) + "'"
+}
+
+func validString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return validString(*s)
+}
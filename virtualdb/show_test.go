@@ -0,0 +1,255 @@
+package virtualdb
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newShowDB(t *testing.T) *VirtualDB {
+	vb := NewVirtualDBWithOpt("", DBOption{ServerVersion: 80040})
+	err := vb.ExecSQL("create database db1; create database db2; use db1;" +
+		"create table t1(id int unsigned auto_increment, name varchar(20) not null default 'x' comment 'it''s', " +
+		"price decimal(10,2), b text, ts timestamp default current_timestamp on update current_timestamp, " +
+		"c char(2) charset latin1, d varchar(5) collate utf8mb4_bin, n int as (id + 1) stored, " +
+		"primary key(id), key(price), unique key uk(name), fulltext (b), key idx((n * 2 + 1)) invisible comment 'x', " +
+		"check (id > 0)) auto_increment=10 comment 'tbl';" +
+		"create table t2(id int, a int unsigned, foreign key (a) references t1 (id) on delete cascade) " +
+		"partition by hash(id) partitions 4;" +
+		"create view v1 as select id from t1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vb
+}
+
+func resultRows(rs *ResultSet) [][]string {
+	rows := [][]string{}
+	for _, row := range rs.Rows {
+		values := []string{}
+		for _, v := range row {
+			if v.Valid {
+				values = append(values, v.String)
+			} else {
+				values = append(values, "NULL")
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows
+}
+
+func TestShowCreateTable(t *testing.T) {
+	vb := newShowDB(t)
+
+	rs, err := vb.QuerySQL("show create table t1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Table", "Create Table"}, rs.Columns)
+		assert.Equal(t, [][]sql.NullString{{validString("t1"), validString("CREATE TABLE `t1` (\n" +
+			"  `id` int unsigned NOT NULL AUTO_INCREMENT,\n" +
+			"  `name` varchar(20) NOT NULL DEFAULT 'x' COMMENT 'it''s',\n" +
+			"  `price` decimal(10,2) DEFAULT NULL,\n" +
+			"  `b` text,\n" +
+			"  `ts` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+			"  `c` char(2) CHARACTER SET latin1 DEFAULT NULL,\n" +
+			"  `d` varchar(5) COLLATE utf8mb4_bin DEFAULT NULL,\n" +
+			"  `n` int GENERATED ALWAYS AS ((`id` + 1)) STORED,\n" +
+			"  PRIMARY KEY (`id`),\n" +
+			"  UNIQUE KEY `uk` (`name`),\n" +
+			"  KEY `price` (`price`),\n" +
+			"  KEY `idx` ((((`n` * 2) + 1))) COMMENT 'x' /*!80000 INVISIBLE */,\n" +
+			"  FULLTEXT KEY `b` (`b`),\n" +
+			"  CONSTRAINT `t1_chk_1` CHECK ((`id` > 0))\n" +
+			") ENGINE=InnoDB AUTO_INCREMENT=10 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='tbl'")}},
+			rs.Rows)
+	}
+
+	rs, err = vb.QuerySQL("show create table db1.t2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CREATE TABLE `t2` (\n"+
+			"  `id` int DEFAULT NULL,\n"+
+			"  `a` int unsigned DEFAULT NULL,\n"+
+			"  KEY `a` (`a`),\n"+
+			"  CONSTRAINT `t2_ibfk_1` FOREIGN KEY (`a`) REFERENCES `t1` (`id`) ON DELETE CASCADE\n"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci\n"+
+			"/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS 4 */", rs.Rows[0][1].String)
+	}
+
+	rs, err = vb.QuerySQL("show create table v1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"View", "Create View", "character_set_client", "collation_connection"}, rs.Columns)
+	}
+}
+
+func TestShowCreateTable57(t *testing.T) {
+	vb := NewVirtualDBWithOpt("db1", DBOption{ServerVersion: 50744})
+	err := vb.ExecSQL("create table t1(a int not null, b varchar(10)) default charset utf8;")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rs, err := vb.QuerySQL("show create table t1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CREATE TABLE `t1` (\n"+
+			"  `a` int(11) NOT NULL,\n"+
+			"  `b` varchar(10) DEFAULT NULL\n"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8", rs.Rows[0][1].String)
+	}
+}
+
+func TestShowCreateTablePartitions(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	err := vb.ExecSQL("create table t1(a int, d date) engine=myisam partition by range(a) " +
+		"(partition p1 values less than (10) comment 'x', partition p2 values less than maxvalue);" +
+		"create table t2(a int, b varchar(10)) partition by list columns(a, b) " +
+		"(partition p1 values in ((1, 'x'), (2, 'y')), partition p2 values in ((3, 'z')));" +
+		"create table t3(a int, d date) partition by range(year(d)) subpartition by hash(to_days(d)) subpartitions 2 " +
+		"(partition p1 values less than (2000), partition p2 values less than maxvalue);")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	createTable := func(table string) string {
+		rs, err := vb.QuerySQL("show create table " + table)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		return rs.Rows[0][1].String
+	}
+	assert.Equal(t, "CREATE TABLE `t1` (\n"+
+		"  `a` int DEFAULT NULL,\n"+
+		"  `d` date DEFAULT NULL\n"+
+		") ENGINE=MyISAM DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci\n"+
+		"/*!50100 PARTITION BY RANGE (`a`)\n"+
+		"(PARTITION p1 VALUES LESS THAN (10) COMMENT = 'x' ENGINE = MyISAM,\n"+
+		" PARTITION p2 VALUES LESS THAN MAXVALUE ENGINE = MyISAM) */", createTable("t1"))
+	assert.Contains(t, createTable("t2"), "\n/*!50500 PARTITION BY LIST  COLUMNS(`a`,`b`)\n"+
+		"(PARTITION p1 VALUES IN ((1,'x'),(2,'y')) ENGINE = InnoDB,\n"+
+		" PARTITION p2 VALUES IN ((3,'z')) ENGINE = InnoDB) */")
+	assert.Contains(t, createTable("t3"), "\n/*!50100 PARTITION BY RANGE (year(`d`))\n"+
+		"SUBPARTITION BY HASH (to_days(`d`))\n"+
+		"SUBPARTITIONS 2\n"+
+		"(PARTITION p1 VALUES LESS THAN (2000) ENGINE = InnoDB,\n"+
+		" PARTITION p2 VALUES LESS THAN MAXVALUE ENGINE = InnoDB) */")
+}
+
+func TestShowTables(t *testing.T) {
+	vb := newShowDB(t)
+
+	rs, err := vb.QuerySQL("show databases")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Database"}, rs.Columns)
+		assert.Equal(t, [][]string{{"db1"}, {"db2"}}, resultRows(rs))
+	}
+
+	rs, err = vb.QuerySQL("show databases like '%2'")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Database (%2)"}, rs.Columns)
+		assert.Equal(t, [][]string{{"db2"}}, resultRows(rs))
+	}
+
+	rs, err = vb.QuerySQL("show tables")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Tables_in_db1"}, rs.Columns)
+		assert.Equal(t, [][]string{{"t1"}, {"t2"}, {"v1"}}, resultRows(rs))
+	}
+
+	rs, err = vb.QuerySQL("show full tables from db1 like 'v_'")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Tables_in_db1 (v_)", "Table_type"}, rs.Columns)
+		assert.Equal(t, [][]string{{"v1", "VIEW"}}, resultRows(rs))
+	}
+
+	rs, err = vb.QuerySQL("show tables from db2")
+	if assert.NoError(t, err) {
+		assert.Empty(t, rs.Rows)
+	}
+}
+
+func TestShowColumns(t *testing.T) {
+	vb := newShowDB(t)
+
+	rs, err := vb.QuerySQL("describe t2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Field", "Type", "Null", "Key", "Default", "Extra"}, rs.Columns)
+		assert.Equal(t, [][]string{
+			{"id", "int", "YES", "", "NULL", ""},
+			{"a", "int unsigned", "YES", "MUL", "NULL", ""},
+		}, resultRows(rs))
+	}
+
+	rs, err = vb.QuerySQL("desc t1 ts")
+	if assert.NoError(t, err) {
+		assert.Equal(t, [][]string{
+			{"ts", "timestamp", "YES", "", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED on update CURRENT_TIMESTAMP"},
+		}, resultRows(rs))
+	}
+
+	rs, err = vb.QuerySQL("show full columns from t1 from db1 like 'n%'")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Field", "Type", "Collation", "Null", "Key", "Default", "Extra", "Privileges", "Comment"},
+			rs.Columns)
+		assert.Equal(t, [][]string{
+			{"name", "varchar(20)", "utf8mb4_0900_ai_ci", "NO", "UNI", "x", "", columnPrivileges, "it's"},
+			{"n", "int", "NULL", "YES", "", "NULL", "STORED GENERATED", columnPrivileges, ""},
+		}, resultRows(rs))
+	}
+
+	rs, err = vb.QuerySQL("show columns from v1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, [][]string{{"id", "int unsigned", "NO", "", "NULL", ""}}, resultRows(rs))
+	}
+}
+
+func TestShowIndex(t *testing.T) {
+	vb := newShowDB(t)
+
+	rs, err := vb.QuerySQL("show index from t1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"Table", "Non_unique", "Key_name", "Seq_in_index", "Column_name", "Collation",
+			"Cardinality", "Sub_part", "Packed", "Null", "Index_type", "Comment", "Index_comment", "Visible",
+			"Expression"}, rs.Columns)
+		assert.Equal(t, [][]string{
+			{"t1", "0", "PRIMARY", "1", "id", "A", "0", "NULL", "NULL", "", "BTREE", "", "", "YES", "NULL"},
+			{"t1", "0", "uk", "1", "name", "A", "0", "NULL", "NULL", "", "BTREE", "", "", "YES", "NULL"},
+			{"t1", "1", "price", "1", "price", "A", "0", "NULL", "NULL", "YES", "BTREE", "", "", "YES", "NULL"},
+			{"t1", "1", "idx", "1", "NULL", "A", "0", "NULL", "NULL", "YES", "BTREE", "", "x", "NO", "((`n` * 2) + 1)"},
+			{"t1", "1", "b", "1", "b", "NULL", "0", "NULL", "NULL", "YES", "FULLTEXT", "", "", "YES", "NULL"},
+		}, resultRows(rs))
+	}
+
+	// MySQL 8.0.0 - 8.0.12 have no Expression column.
+	vb = NewVirtualDBWithOpt("db1", DBOption{ServerVersion: 80000})
+	if err := vb.ExecSQL("create table t1(a int, b varchar(20), key b(b(10)));"); err != nil {
+		t.Fatal(err)
+	}
+	rs, err = vb.QuerySQL("show keys in t1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Visible", rs.Columns[len(rs.Columns)-1])
+		assert.Equal(t, [][]string{
+			{"t1", "1", "b", "1", "b", "A", "0", "10", "NULL", "YES", "BTREE", "", "", "YES"},
+		}, resultRows(rs))
+	}
+}
+
+func TestShowError(t *testing.T) {
+	vb := newShowDB(t)
+
+	_, err := vb.QuerySQL("show create table t3")
+	assert.EqualError(t, err, "not exist table: db1.t3")
+	var schemaErr *SchemaError
+	if errors.As(err, &schemaErr) {
+		assert.Equal(t, "ERROR 1146 (42S02): Table 'db1.t3' doesn't exist", schemaErr.MySQLMessage())
+	}
+
+	_, err = vb.QuerySQL("show tables from db3")
+	assert.EqualError(t, err, "not exist schema: db3")
+
+	_, err = NewVirtualDB("").QuerySQL("show tables")
+	assert.True(t, errors.As(err, &schemaErr))
+	assert.Equal(t, "ERROR 1046 (3D000): No database selected", schemaErr.MySQLMessage())
+
+	_, err = vb.QuerySQL("show tables where Tables_in_db1 = 't1'")
+	assert.EqualError(t, err, "stmt not support")
+}
@@ -0,0 +1,104 @@
+package virtualdb
+
+import "github.com/pingcap/parser"
+
+// Snapshot is a copy of the state of a VirtualDB, it can be restored any times.
+type Snapshot struct {
+	db *VirtualDB
+}
+
+// Clone returns a copy of the VirtualDB, changes to the copy don't affect the
+// original one.
+func (c *VirtualDB) Clone() (*VirtualDB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clone(), nil
+}
+
+// clone copies the VirtualDB, the caller must hold the lock. The statements are
+// replaced rather than modified in place, so only the maps are copied and the
+// tables, views and objects are shared with the copy.
+func (c *VirtualDB) clone() *VirtualDB {
+	newDB := &VirtualDB{
+		currentSchema:       c.currentSchema,
+		schemas:             make(map[string]*SchemaInfo, len(c.schemas)),
+		lowerCaseTableNames: c.lowerCaseTableNames,
+		serverVersion:       c.serverVersion,
+	}
+	for schemaName, schema := range c.schemas {
+		newSchema := newSchemaInfo(schema.Schema)
+		for tableName, table := range schema.Tables {
+			newSchema.Tables[tableName] = table
+		}
+		for viewName, view := range schema.Views {
+			newSchema.Views[viewName] = view
+		}
+		for _, tp := range []ObjectType{ObjectTrigger, ObjectProcedure, ObjectFunction, ObjectEvent} {
+			for name, object := range schema.objects(tp) {
+				newSchema.objects(tp)[name] = object
+			}
+		}
+		newDB.schemas[schemaName] = newSchema
+	}
+	return newDB
+}
+
+// Snapshot saves the current state, which can be restored by Restore.
+func (c *VirtualDB) Snapshot() (*Snapshot, error) {
+	db, err := c.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{db: db}, nil
+}
+
+// Restore rolls back the VirtualDB to the state of the snapshot.
+func (c *VirtualDB) Restore(snapshot *Snapshot) error {
+	db, err := snapshot.db.Clone()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.currentSchema = db.currentSchema
+	c.schemas = db.schemas
+	return nil
+}
+
+// ExecSQLAtomic executes the statements in sql as a whole, the VirtualDB is
+// unchanged if any statement fails.
+func (c *VirtualDB) ExecSQLAtomic(sql string) error {
+	p := parser.New()
+	stmts, _, parseErr := p.Parse(sql, "", "")
+	var raws []*rawStmt
+	if parseErr != nil {
+		if !hasObjectStmt(sql) {
+			return parseErr
+		}
+		var err error
+		if raws, err = scanStmts(sql, c.serverVersion); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	db := c.clone()
+	// the events are queued in the copy and sent only if all the statements succeed.
+	db.observers = c.observers
+	var err error
+	if parseErr != nil {
+		err = db.execRawStmts(p, raws, ExecOption{})
+	} else {
+		err = db.execStmts(sql, stmts, ExecOption{})
+	}
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	c.currentSchema = db.currentSchema
+	c.schemas = db.schemas
+	observers, events := db.takeEvents()
+	c.unlockAndNotify(observers, events)
+	return nil
+}
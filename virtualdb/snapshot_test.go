@@ -0,0 +1,71 @@
+package virtualdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertText(t *testing.T, vb *VirtualDB, expect string) {
+	actual, err := vb.Text()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Equal(t, expect, actual)
+}
+
+func TestClone(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create database db2 charset utf8; create table t1(a int, index i1(a));"))
+
+	newDB, err := vb.Clone()
+	assert.NoError(t, err)
+	assert.NoError(t, newDB.ExecSQL("alter table t1 add column b int, drop index i1, rename column a to x; use db2; create table t2(a int);"))
+
+	assertText(t, vb, "CREATE DATABASE `db1`;\n"+
+		"CREATE TABLE `db1`.`t1` (`a` INT,INDEX `i1`(`a`));\n"+
+		"CREATE DATABASE `db2` CHARACTER SET = utf8;\n")
+	assertText(t, newDB, "CREATE DATABASE `db1`;\n"+
+		"CREATE TABLE `db1`.`t1` (`x` INT,`b` INT);\n"+
+		"CREATE DATABASE `db2` CHARACTER SET = utf8;\n"+
+		"CREATE TABLE `db2`.`t2` (`a` INT);\n")
+
+	// the clone keeps its own tables and current schema.
+	assert.NoError(t, vb.ExecSQL("create table t3(a int);"))
+	_, ok := vb.GetTableStmts("db1")
+	assert.True(t, ok)
+	_, ok = newDB.GetTableStmts("db2")
+	assert.True(t, ok)
+	assert.Error(t, newDB.ExecSQL("alter table t3 add column b int;"))
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int);"))
+
+	snapshot, err := vb.Snapshot()
+	assert.NoError(t, err)
+	assert.NoError(t, vb.ExecSQL("alter table t1 add column b int; create database db2; use db2;"))
+	assert.NoError(t, vb.Restore(snapshot))
+	assertText(t, vb, "CREATE DATABASE `db1`;\nCREATE TABLE `db1`.`t1` (`a` INT);\n")
+
+	// the snapshot can be restored again after changes.
+	assert.NoError(t, vb.ExecSQL("drop table t1;"))
+	assert.NoError(t, vb.Restore(snapshot))
+	assertText(t, vb, "CREATE DATABASE `db1`;\nCREATE TABLE `db1`.`t1` (`a` INT);\n")
+}
+
+func TestExecSQLAtomic(t *testing.T) {
+	vb := NewVirtualDB("db1")
+	assert.NoError(t, vb.ExecSQL("create table t1(a int);"))
+
+	err := vb.ExecSQLAtomic("alter table t1 add column b int; create table t2(a int); alter table t3 add column a int;")
+	assert.Error(t, err)
+	assertText(t, vb, "CREATE DATABASE `db1`;\nCREATE TABLE `db1`.`t1` (`a` INT);\n")
+
+	assert.NoError(t, vb.ExecSQLAtomic("alter table t1 add column b int; create table t2(a int);"))
+	assertText(t, vb, "CREATE DATABASE `db1`;\n"+
+		"CREATE TABLE `db1`.`t1` (`a` INT,`b` INT);\n"+
+		"CREATE TABLE `db1`.`t2` (`a` INT);\n")
+}
@@ -6,10 +6,17 @@ import (
 	"github.com/pingcap/parser/ast"
 	"github.com/pingcap/parser/format"
 	_ "github.com/pingcap/tidb/types/parser_driver"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
-func restoreToSql(stmt ast.StmtNode) (string, error) {
+// restorer is the part of AST which can be restored to SQL.
+type restorer interface {
+	Restore(ctx *format.RestoreCtx) error
+}
+
+func restoreToSql(stmt restorer) (string, error) {
 	var sb strings.Builder
 	ctx := format.NewRestoreCtx(format.DefaultRestoreFlags, &sb)
 	err := stmt.Restore(ctx)
@@ -19,6 +26,29 @@ func restoreToSql(stmt ast.StmtNode) (string, error) {
 	return sb.String(), nil
 }
 
+// columnNameCollector collects the names of the columns used by an expression.
+type columnNameCollector struct {
+	columns []string
+}
+
+func (v *columnNameCollector) Enter(n ast.Node) (ast.Node, bool) {
+	if col, ok := n.(*ast.ColumnName); ok {
+		v.columns = append(v.columns, col.Name.O)
+	}
+	return n, false
+}
+
+func (v *columnNameCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// getExprColumns returns the names of the columns used by the expression.
+func getExprColumns(expr ast.ExprNode) []string {
+	collector := &columnNameCollector{}
+	expr.Accept(collector)
+	return collector.columns
+}
+
 // parseCreateTableStmt parse create table sql text to CreateTableStmt ast.
 func parseCreateTableStmt(sql string) (*ast.CreateTableStmt, error) {
 	t, err := parseOneSql(sql)
@@ -32,6 +62,15 @@ func parseCreateTableStmt(sql string) (*ast.CreateTableStmt, error) {
 	return createStmt, nil
 }
 
+// copyCreateTableStmt deep copies the table by restoring it to SQL and parsing it.
+func copyCreateTableStmt(table *ast.CreateTableStmt) (*ast.CreateTableStmt, error) {
+	sql, err := restoreToSql(table)
+	if err != nil {
+		return nil, err
+	}
+	return parseCreateTableStmt(sql)
+}
+
 func parseOneSql(sql string) (ast.StmtNode, error) {
 	p := parser.New()
 	stmt, err := p.ParseOneStmt(sql, "", "")
@@ -94,3 +133,77 @@ func hasPrimaryKey(stmt *ast.CreateTableStmt) bool {
 	_, hasPk := getPrimaryKey(stmt)
 	return hasPk
 }
+
+func countPrimaryKey(stmt *ast.CreateTableStmt) int {
+	count := 0
+	for _, constraint := range stmt.Constraints {
+		if constraint.Tp == ast.ConstraintPrimaryKey {
+			count++
+		}
+	}
+	for _, col := range stmt.Cols {
+		if hasOneInOptions(col.Options, ast.ColumnOptionPrimaryKey) {
+			count++
+		}
+	}
+	return count
+}
+
+func sortedSchemaNames(schemas map[string]*SchemaInfo) []string {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedTableNames(tables map[string]*TableInfo) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// locateStmt returns the offset of the statement text in sql, the search
+// starts from offset because the statements are parsed in order.
+func locateStmt(sql, text string, offset int) int {
+	text = strings.TrimSpace(text)
+	if i := strings.Index(sql[offset:], text); i >= 0 {
+		return offset + i
+	}
+	return offset
+}
+
+func newStmtError(sql string, index, offset int, text string, err error) *StmtError {
+	line := strings.Count(sql[:offset], "\n") + 1
+	column := utf8.RuneCountInString(sql[strings.LastIndex(sql[:offset], "\n")+1:offset]) + 1
+	return &StmtError{
+		Index:   index,
+		Line:    line,
+		Column:  column,
+		Snippet: getSnippet(text),
+		Err:     err,
+	}
+}
+
+func newRawStmtError(raw *rawStmt, err error) *StmtError {
+	return &StmtError{
+		Index:   raw.index,
+		Line:    raw.line,
+		Column:  raw.column,
+		Snippet: getSnippet(raw.text),
+		Err:     err,
+	}
+}
+
+// getSnippet returns the statement text in one line, long text is truncated.
+func getSnippet(text string) string {
+	snippet := []rune(strings.Join(strings.Fields(text), " "))
+	if len(snippet) > maxSnippetLength {
+		return string(snippet[:maxSnippetLength]) + "..."
+	}
+	return string(snippet)
+}
@@ -0,0 +1,344 @@
+package virtualdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/opcode"
+	"github.com/pingcap/tidb/sessionctx/stmtctx"
+	"github.com/pingcap/tidb/types"
+	driver "github.com/pingcap/tidb/types/parser_driver"
+)
+
+// MaxKeyLength is the max length in bytes of an InnoDB index.
+const MaxKeyLength = 3072
+
+// Validate checks the definitions of all tables and views and returns the problems MySQL
+// would reject, ordered by schema and table name.
+func (c *VirtualDB) Validate() []error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	errs := []error{}
+	for _, schemaName := range sortedSchemaNames(c.schemas) {
+		schema := c.schemas[schemaName]
+		for _, tableName := range sortedTableNames(schema.Tables) {
+			table := schema.Tables[tableName].Table
+			errs = append(errs, validateTable(schemaName, tableName, schema.Schema, table, c.serverVersion)...)
+		}
+		// the tables used by a view may be dropped or altered after it is created.
+		for _, view := range c.sortedViews() {
+			if view.schema != schemaName {
+				continue
+			}
+			if err := c.validateView(schemaName, view.name, view.view); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+func validateTable(schemaName, tableName string, schema *ast.CreateDatabaseStmt,
+	table *ast.CreateTableStmt, serverVersion int) []error {
+
+	errs := []error{}
+
+	cols := map[string]*ast.ColumnDef{}
+	for _, col := range table.Cols {
+		columnName := col.Name.Name.L
+		if _, ok := cols[columnName]; ok {
+			errs = append(errs, duplicateColumnError(schemaName, tableName, col.Name.Name.O))
+			continue
+		}
+		cols[columnName] = col
+	}
+
+	indexNames := map[string]struct{}{}
+	for _, constraint := range table.Constraints {
+		if !isIndexConstraint(constraint) || constraint.Name == "" {
+			continue
+		}
+		indexName := strings.ToLower(constraint.Name)
+		if _, ok := indexNames[indexName]; ok {
+			errs = append(errs, duplicateIndexError(schemaName, tableName, constraint.Name))
+		}
+		indexNames[indexName] = struct{}{}
+	}
+
+	// like InnoDB the auto increment column must be the first column of a key.
+	firstKeyColumns := map[string]struct{}{}
+	pkColumns, _ := getPrimaryKey(table)
+	for _, key := range getTableKeys(table) {
+		keyLength := 0
+		for i, part := range key.Keys {
+			if part.Column == nil {
+				continue
+			}
+			col, ok := cols[part.Column.Name.L]
+			if !ok {
+				errs = append(errs, newKeyError(ErrKeyColumnDoesNotExist, schemaName, tableName, part.Column.Name.O, getIndexName(key),
+					fmt.Sprintf(NotExistKeyColumnErrorPattern, part.Column.Name, getIndexName(key), schemaName, tableName)))
+				continue
+			}
+			if key.Tp == ast.ConstraintForeignKey {
+				continue
+			}
+			if i == 0 {
+				firstKeyColumns[col.Name.Name.L] = struct{}{}
+			}
+
+			if isBlobType(col.Tp.Tp) && part.Length <= 0 && key.Tp != ast.ConstraintFulltext {
+				errs = append(errs, newKeyError(ErrBlobKeyWithoutLength, schemaName, tableName, col.Name.Name.O, getIndexName(key),
+					fmt.Sprintf(BlobKeyWithoutLengthErrorPattern, col.Name.Name, getIndexName(key), schemaName, tableName)))
+				continue
+			}
+			charset, _ := ColumnCharset(schema, table, col, serverVersion)
+			keyLength += getKeyPartLength(col, part, charset)
+		}
+		if key.Tp != ast.ConstraintFulltext && keyLength > MaxKeyLength {
+			errs = append(errs, newIndexError(ErrTooLongKey, schemaName, tableName, getIndexName(key),
+				fmt.Sprintf(TooLongKeyErrorPattern, getIndexName(key), schemaName, tableName, MaxKeyLength)))
+		}
+	}
+
+	autoIncrementCount := 0
+	for _, col := range table.Cols {
+		if !hasOneInOptions(col.Options, ast.ColumnOptionAutoIncrement) {
+			continue
+		}
+		autoIncrementCount++
+		if autoIncrementCount == 2 {
+			errs = append(errs, newColumnError(ErrWrongAutoKey, schemaName, tableName, col.Name.Name.O,
+				fmt.Sprintf(MultipleAutoIncrementErrorPattern, schemaName, tableName)))
+		}
+		if _, ok := firstKeyColumns[col.Name.Name.L]; !ok {
+			errs = append(errs, newColumnError(ErrWrongAutoKey, schemaName, tableName, col.Name.Name.O,
+				fmt.Sprintf(AutoIncrementNotKeyErrorPattern, col.Name.Name, schemaName, tableName)))
+		}
+	}
+
+	for _, col := range table.Cols {
+		if _, ok := pkColumns[col.Name.Name.L]; ok && hasOneInOptions(col.Options, ast.ColumnOptionNull) {
+			errs = append(errs, newColumnError(ErrPrimaryCantHaveNull, schemaName, tableName, col.Name.Name.O,
+				fmt.Sprintf(NullablePrimaryKeyErrorPattern, col.Name.Name, schemaName, tableName)))
+		}
+		if !isValidDefault(col, pkColumns) {
+			errs = append(errs, newColumnError(ErrInvalidDefault, schemaName, tableName, col.Name.Name.O,
+				fmt.Sprintf(InvalidDefaultErrorPattern, col.Name.Name, schemaName, tableName)))
+		}
+	}
+	errs = append(errs, validateGeneratedColumns(schemaName, tableName, table)...)
+	errs = append(errs, validateChecks(schemaName, tableName, table)...)
+	return append(errs, validatePartition(schemaName, tableName, table)...)
+}
+
+// getTableKeys returns the indexes and foreign keys of the table, including the
+// PRIMARY KEY and UNIQUE declared in column definitions.
+func getTableKeys(table *ast.CreateTableStmt) []*ast.Constraint {
+	keys := []*ast.Constraint{}
+	for _, col := range table.Cols {
+		for _, op := range col.Options {
+			tp := ast.ConstraintNoConstraint
+			switch op.Tp {
+			case ast.ColumnOptionPrimaryKey:
+				tp = ast.ConstraintPrimaryKey
+			case ast.ColumnOptionUniqKey:
+				tp = ast.ConstraintUniq
+			default:
+				continue
+			}
+			keys = append(keys, &ast.Constraint{
+				Tp:   tp,
+				Name: col.Name.Name.O,
+				Keys: []*ast.IndexPartSpecification{{Column: col.Name, Length: types.UnspecifiedLength}},
+			})
+		}
+	}
+	for _, constraint := range table.Constraints {
+		if constraint.Tp != ast.ConstraintCheck {
+			keys = append(keys, constraint)
+		}
+	}
+	return keys
+}
+
+// getIndexName returns the name used to report an index in errors.
+func getIndexName(constraint *ast.Constraint) string {
+	switch {
+	case constraint.Tp == ast.ConstraintPrimaryKey:
+		return "PRIMARY"
+	case constraint.Name != "":
+		return constraint.Name
+	case len(constraint.Keys) > 0 && constraint.Keys[0].Column != nil:
+		return constraint.Keys[0].Column.Name.O
+	}
+	return ""
+}
+
+func isBlobType(tp byte) bool {
+	switch tp {
+	case mysql.TypeTinyBlob, mysql.TypeBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob,
+		mysql.TypeJSON, mysql.TypeGeometry:
+		return true
+	}
+	return false
+}
+
+// getKeyPartLength returns the bytes the key part takes in an index.
+func getKeyPartLength(col *ast.ColumnDef, part *ast.IndexPartSpecification, charset string) int {
+	ft := col.Tp
+	switch ft.Tp {
+	case mysql.TypeTiny, mysql.TypeYear:
+		return 1
+	case mysql.TypeShort, mysql.TypeEnum:
+		return 2
+	case mysql.TypeInt24, mysql.TypeDate:
+		return 3
+	case mysql.TypeLong, mysql.TypeFloat:
+		return 4
+	case mysql.TypeLonglong, mysql.TypeDouble, mysql.TypeSet:
+		return 8
+	case mysql.TypeDuration:
+		return 3 + (getFsp(ft)+1)/2
+	case mysql.TypeDatetime:
+		return 5 + (getFsp(ft)+1)/2
+	case mysql.TypeTimestamp:
+		return 4 + (getFsp(ft)+1)/2
+	case mysql.TypeBit:
+		return (ft.Flen + 7) / 8
+	case mysql.TypeNewDecimal:
+		return getDecimalLength(ft.Flen, ft.Decimal)
+	}
+
+	chars := ft.Flen
+	if part.Length > 0 {
+		chars = part.Length
+	}
+	if chars < 0 {
+		chars = 1
+	}
+	// the binary strings and BLOBs have no charset, their lengths are in bytes.
+	if charset == "" {
+		return chars
+	}
+	return chars * getCharsetMaxLen(charset)
+}
+
+func getFsp(ft *types.FieldType) int {
+	if ft.Decimal > 0 {
+		return ft.Decimal
+	}
+	return 0
+}
+
+// getDecimalLength returns the storage bytes of DECIMAL(m, d), 9 digits take 4 bytes.
+func getDecimalLength(m, d int) int {
+	if m <= 0 {
+		m = 10
+	}
+	if d < 0 {
+		d = 0
+	}
+	digitsBytes := []int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+	intDigits := m - d
+	return intDigits/9*4 + digitsBytes[intDigits%9] + d/9*4 + digitsBytes[d%9]
+}
+
+// isValidDefault reports whether the default value of the column fits its type.
+func isValidDefault(col *ast.ColumnDef, pkColumns map[string]struct{}) bool {
+	var expr ast.ExprNode
+	for _, op := range col.Options {
+		if op.Tp == ast.ColumnOptionDefaultValue {
+			expr = op.Expr
+		}
+	}
+	if expr == nil {
+		return true
+	}
+
+	ft := col.Tp
+	negative := false
+	if unary, ok := expr.(*ast.UnaryOperationExpr); ok && unary.Op == opcode.Minus {
+		negative = true
+		expr = unary.V
+	}
+
+	switch e := expr.(type) {
+	case *ast.FuncCallExpr:
+		// DEFAULT CURRENT_TIMESTAMP
+		return ft.Tp == mysql.TypeDatetime || ft.Tp == mysql.TypeTimestamp
+	case *driver.ValueExpr:
+		if e.Datum.Kind() == types.KindNull {
+			_, isPk := pkColumns[col.Name.Name.L]
+			return !isPk && !hasOneInOptions(col.Options, ast.ColumnOptionNotNull)
+		}
+		// BLOB, TEXT, JSON and GEOMETRY columns can't have a literal default value.
+		if isBlobType(ft.Tp) {
+			return false
+		}
+		value, err := e.Datum.ToString()
+		if err != nil {
+			return true
+		}
+		if negative {
+			value = "-" + value
+		}
+		return isValidDefaultValue(ft, e.Datum.Kind(), value)
+	}
+	return true
+}
+
+func isValidDefaultValue(ft *types.FieldType, kind byte, value string) bool {
+	isNumber := kind != types.KindString && kind != types.KindBytes
+	switch ft.Tp {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong,
+		mysql.TypeFloat, mysql.TypeDouble, mysql.TypeNewDecimal, mysql.TypeYear:
+		if isNumber {
+			return true
+		}
+		_, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil
+	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeTimestamp:
+		_, err := types.ParseTime(&stmtctx.StatementContext{}, value, ft.Tp, int8(getFsp(ft)))
+		return err == nil
+	case mysql.TypeDuration:
+		_, err := types.ParseDuration(&stmtctx.StatementContext{}, value, int8(getFsp(ft)))
+		return err == nil
+	case mysql.TypeEnum:
+		for _, elem := range ft.Elems {
+			if strings.EqualFold(elem, value) {
+				return true
+			}
+		}
+		return false
+	case mysql.TypeSet:
+		if value == "" {
+			return true
+		}
+		for _, v := range strings.Split(value, ",") {
+			found := false
+			for _, elem := range ft.Elems {
+				if strings.EqualFold(elem, v) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString:
+		flen := ft.Flen
+		if flen < 0 {
+			flen = 1
+		}
+		return utf8.RuneCountInString(value) <= flen
+	}
+	return true
+}
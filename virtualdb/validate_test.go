@@ -0,0 +1,104 @@
+package virtualdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testValidate(t *testing.T, input string, expectErrors ...string) {
+	vb := NewVirtualDB("db1")
+	if err := vb.ExecSQL(input); err != nil {
+		t.Error(err)
+		return
+	}
+	actual := []string{}
+	for _, err := range vb.Validate() {
+		actual = append(actual, err.Error())
+	}
+	if len(expectErrors) == 0 {
+		expectErrors = []string{}
+	}
+	assert.Equal(t, expectErrors, actual)
+}
+
+func TestValidate(t *testing.T) {
+	testValidate(t, `create table t1(
+id bigint unsigned not null auto_increment,
+name varchar(255) not null default '',
+body text,
+created_at datetime not null default current_timestamp,
+primary key(id),
+unique key uk_name(name),
+index idx_body(body(100)),
+fulltext index ft_body(body));`)
+
+	testValidate(t, "create table t1(a int, index idx_b(b));",
+		"not exist column b used in index idx_b in db1.t1")
+
+	testValidate(t, "create table t1(a int, a varchar(10));",
+		"duplicate column a in db1.t1")
+
+	testValidate(t, "create table t1(a int, b int, index i1(a), index i1(b));",
+		"duplicate index i1 in db1.t1")
+
+	testValidate(t, "create table t1(a int auto_increment, b int auto_increment, primary key(a, b));",
+		"more than one auto increment column in db1.t1",
+		"auto increment column b is not defined as a key in db1.t1")
+
+	testValidate(t, "create table t1(a int auto_increment, b int, index(b));",
+		"auto increment column a is not defined as a key in db1.t1")
+
+	testValidate(t, "create table t1(a int auto_increment unique);")
+
+	testValidate(t, "create table t1(id int auto_increment, b int, key(b, id));",
+		"auto increment column id is not defined as a key in db1.t1")
+	testValidate(t, "create table t1(id int auto_increment, b int, key(b, id), key(id));")
+
+	testValidate(t, "create table t1(a text, b blob, index(a), unique key u1(b));",
+		"blob/text column a used in index a without a key length in db1.t1",
+		"blob/text column b used in index u1 without a key length in db1.t1")
+
+	testValidate(t, "create table t1(a varchar(500), b varchar(500), index i1(a, b)) charset=utf8mb4;",
+		"index i1 is too long in db1.t1, max key length is 3072 bytes")
+
+	testValidate(t, "create table t1(a varchar(500), b varchar(500), index i1(a, b)) charset=utf8;")
+
+	testValidate(t, "create table t1(a varchar(1000) character set latin1, b varchar(1000) character set latin1, index i1(a, b(1000)));")
+
+	testValidate(t, "create table t1(a blob, b varbinary(4000), index(a(3000)), index(b(3000)));")
+
+	testValidate(t, "create table t1(a blob, b text, index i1(a(3000), b(100)));",
+		"index i1 is too long in db1.t1, max key length is 3072 bytes")
+
+	testValidate(t, `create table t1(
+a int default 'abc',
+b int default '12',
+c int not null default null,
+d varchar(3) default 'abcd',
+e text default 'x',
+f datetime default 'yesterday',
+g int default current_timestamp,
+h enum('x', 'y') default 'z',
+i int default -1,
+j date default '2020-01-01');`,
+		"invalid default value for column a in db1.t1",
+		"invalid default value for column c in db1.t1",
+		"invalid default value for column d in db1.t1",
+		"invalid default value for column e in db1.t1",
+		"invalid default value for column f in db1.t1",
+		"invalid default value for column g in db1.t1",
+		"invalid default value for column h in db1.t1")
+
+	testValidate(t, "create table t1(a int null, b int, primary key(a));",
+		"primary key column a can't be null in db1.t1")
+
+	testValidate(t, "create table t1(a int null primary key);",
+		"primary key column a can't be null in db1.t1")
+}
+
+func TestValidateAfterAlter(t *testing.T) {
+	testValidate(t, `create table t1(id int auto_increment, primary key(id));
+alter table t1 drop primary key;`,
+		"auto increment column id is not defined as a key in db1.t1")
+}
@@ -0,0 +1,342 @@
+package virtualdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+)
+
+func (c *VirtualDB) getView(schemaName, viewName string) (*ViewInfo, bool) {
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return nil, false
+	}
+	view, exist := schema.Views[c.nameKey(viewName)]
+	return view, exist
+}
+
+func (c *VirtualDB) hasView(schemaName, viewName string) bool {
+	_, exist := c.getView(schemaName, viewName)
+	return exist
+}
+
+// createView adds the view of CREATE VIEW, the view is validated against the
+// existing tables and views like MySQL.
+func (c *VirtualDB) createView(stmt *ast.CreateViewStmt) error {
+	return c.replaceView(stmt, stmt)
+}
+
+// replaceView adds or replaces the view of CREATE VIEW, the node is the statement
+// of the events. A copy of the statement is stored, so the caller's statement is
+// not changed.
+func (c *VirtualDB) replaceView(stmt *ast.CreateViewStmt, node ast.StmtNode) error {
+	schemaName := c.getSchemaName(stmt.ViewName)
+	viewName := stmt.ViewName.Name.String()
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return unknownDatabaseError(schemaName)
+	}
+
+	tableExist, _ := c.hasTable(schemaName, viewName)
+	if tableExist && stmt.OrReplace {
+		return wrongObjectError(schemaName, viewName)
+	}
+	oldView, viewExist := c.getView(schemaName, viewName)
+	if tableExist || (viewExist && !stmt.OrReplace) {
+		return newSchemaError(ErrTableExists, schemaName, viewName,
+			fmt.Sprintf(DuplicateTableErrorPattern, schemaName, viewName))
+	}
+
+	view, err := copyCreateViewStmt(stmt)
+	if err != nil {
+		return err
+	}
+	// like MySQL the tables are qualified by the current schema when the view is
+	// created, so the definition doesn't depend on the current schema.
+	view.Select.Accept(&tableNameQualifier{schema: c.storedName(c.currentSchema)})
+	view.ViewName = c.storedTableName(schemaName, viewName)
+	view.OrReplace = false
+	if err := c.validateView(schemaName, viewName, view); err != nil {
+		return err
+	}
+	schema.Views[c.nameKey(viewName)] = &ViewInfo{View: view}
+	if viewExist {
+		c.emit(&ViewReplaced{ViewEvent{BaseEvent{node}, schemaName, oldView.View, view}})
+	} else {
+		c.emit(&ViewCreated{ViewEvent{BaseEvent{node}, schemaName, nil, view}})
+	}
+	return nil
+}
+
+// alterView executes ALTER VIEW as CREATE OR REPLACE VIEW, the text is the
+// statement after ALTER and the node is the statement of the events.
+func (c *VirtualDB) alterView(text string, node ast.StmtNode) error {
+	parsed, err := parseOneSql("CREATE OR REPLACE " + text)
+	if err != nil {
+		return err
+	}
+	stmt, ok := parsed.(*ast.CreateViewStmt)
+	if !ok {
+		return fmt.Errorf("stmt not support")
+	}
+	schemaName := c.getSchemaName(stmt.ViewName)
+	viewName := stmt.ViewName.Name.String()
+	if !c.hasView(schemaName, viewName) {
+		if exist, _ := c.hasTable(schemaName, viewName); exist {
+			return wrongObjectError(schemaName, viewName)
+		}
+		return noSuchTableError(schemaName, viewName)
+	}
+	return c.replaceView(stmt, node)
+}
+
+// copyCreateViewStmt copies the statement by restoring and parsing it.
+func copyCreateViewStmt(stmt *ast.CreateViewStmt) (*ast.CreateViewStmt, error) {
+	sql, err := restoreToSql(stmt)
+	if err != nil {
+		return nil, err
+	}
+	node, err := parseOneSql(sql)
+	if err != nil {
+		return nil, err
+	}
+	view, ok := node.(*ast.CreateViewStmt)
+	if !ok {
+		return nil, fmt.Errorf("stmt not support")
+	}
+	return view, nil
+}
+
+// dropViews drops the views of DROP VIEW, like DROP TABLE nothing is dropped
+// if any view doesn't exist.
+func (c *VirtualDB) dropViews(stmt *ast.DropTableStmt) error {
+	errs := []string{}
+	unknownViews := []*ast.TableName{}
+	for _, view := range stmt.Tables {
+		schemaName := c.getSchemaName(view)
+		viewName := view.Name.String()
+		if c.hasView(schemaName, viewName) {
+			continue
+		}
+		if exist, _ := c.hasTable(schemaName, viewName); exist {
+			return wrongObjectError(schemaName, viewName)
+		}
+		if stmt.IfExists {
+			continue
+		}
+		errs = append(errs, fmt.Sprintf(NotExistTableErrorPattern, schemaName, viewName))
+		unknownViews = append(unknownViews, view)
+	}
+	if len(errs) != 0 {
+		return c.unknownTableError(unknownViews, strings.Join(errs, ","))
+	}
+	for _, view := range stmt.Tables {
+		schemaName := c.getSchemaName(view)
+		if info, exist := c.getView(schemaName, view.Name.String()); exist {
+			schema, _ := c.getSchema(schemaName)
+			delete(schema.Views, c.nameKey(view.Name.String()))
+			c.emit(&ViewDropped{ViewEvent{BaseEvent{stmt}, schemaName, info.View, nil}})
+		}
+	}
+	return nil
+}
+
+// tableNameQualifier sets the schema of the unqualified table names.
+type tableNameQualifier struct {
+	schema string
+}
+
+func (v *tableNameQualifier) Enter(n ast.Node) (ast.Node, bool) {
+	if table, ok := n.(*ast.TableName); ok && table.Schema.O == "" {
+		table.Schema = model.NewCIStr(v.schema)
+	}
+	return n, false
+}
+
+func (v *tableNameQualifier) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// viewRefCollector collects the tables, table sources and columns used by a view.
+type viewRefCollector struct {
+	tables  []*ast.TableName
+	sources []*ast.TableSource
+	columns []*ast.ColumnName
+	fields  []*ast.SelectField
+}
+
+func (v *viewRefCollector) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *ast.TableName:
+		v.tables = append(v.tables, node)
+	case *ast.TableSource:
+		v.sources = append(v.sources, node)
+	case *ast.ColumnNameExpr:
+		v.columns = append(v.columns, node.Name)
+	case *ast.SelectField:
+		v.fields = append(v.fields, node)
+	}
+	return n, false
+}
+
+func (v *viewRefCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// validateView checks that the tables and columns used by the view exist, and
+// the column list of the view matches its select.
+func (c *VirtualDB) validateView(schemaName, viewName string, stmt *ast.CreateViewStmt) error {
+	refs := &viewRefCollector{}
+	stmt.Select.Accept(refs)
+
+	// the columns of all the tables are collected, a column only needs to be in
+	// one of them since the subqueries may refer to the outer tables.
+	columns := map[string]struct{}{}
+	checkColumns := true
+	for _, table := range refs.tables {
+		refSchema := table.Schema.String()
+		if info, exist, _ := c.getTable(refSchema, table.Name.String()); exist {
+			for _, col := range info.Table.Cols {
+				columns[col.Name.Name.L] = struct{}{}
+			}
+			continue
+		}
+		view, exist := c.getView(refSchema, table.Name.String())
+		if !exist {
+			return noSuchTableError(refSchema, table.Name.String())
+		}
+		viewColumns, ok := getViewColumns(view.View)
+		if !ok {
+			checkColumns = false
+		}
+		for _, col := range viewColumns {
+			columns[strings.ToLower(col)] = struct{}{}
+		}
+	}
+	for _, source := range refs.sources {
+		// the columns of the derived tables are not resolved.
+		if _, ok := source.Source.(*ast.TableName); !ok {
+			if _, ok := source.Source.(*ast.Join); !ok {
+				checkColumns = false
+			}
+		}
+	}
+
+	if checkColumns {
+		// the aliases of the fields can be used by ORDER BY and HAVING.
+		for _, field := range refs.fields {
+			if field.AsName.L != "" {
+				columns[field.AsName.L] = struct{}{}
+			}
+		}
+		for _, col := range refs.columns {
+			if _, ok := columns[col.Name.L]; !ok {
+				return newColumnError(ErrUnknownColumn, schemaName, viewName, col.Name.O,
+					fmt.Sprintf(NotExistViewColumnErrorPattern, col.Name.O, schemaName, viewName))
+			}
+		}
+	}
+
+	if len(stmt.Cols) != 0 {
+		if selectColumns, ok := getSelectColumns(stmt.Select); ok && len(selectColumns) != len(stmt.Cols) {
+			return newSchemaError(ErrViewWrongList, schemaName, viewName,
+				fmt.Sprintf(ViewColumnCountErrorPattern, schemaName, viewName, len(stmt.Cols), len(selectColumns)))
+		}
+	}
+	return nil
+}
+
+// getViewColumns returns the names of the columns of the view, it returns false
+// if they can't be known without resolving the wildcards.
+func getViewColumns(view *ast.CreateViewStmt) ([]string, bool) {
+	if len(view.Cols) != 0 {
+		names := []string{}
+		for _, col := range view.Cols {
+			names = append(names, col.O)
+		}
+		return names, true
+	}
+	return getSelectColumns(view.Select)
+}
+
+// getSelectColumns returns the names of the fields of the select, it returns
+// false if there is a wildcard.
+func getSelectColumns(node ast.StmtNode) ([]string, bool) {
+	var selectStmt *ast.SelectStmt
+	switch stmt := node.(type) {
+	case *ast.SelectStmt:
+		selectStmt = stmt
+	case *ast.UnionStmt:
+		if stmt.SelectList == nil || len(stmt.SelectList.Selects) == 0 {
+			return nil, false
+		}
+		selectStmt = stmt.SelectList.Selects[0]
+	}
+	if selectStmt == nil || selectStmt.Fields == nil {
+		return nil, false
+	}
+
+	names := []string{}
+	for _, field := range selectStmt.Fields.Fields {
+		switch {
+		case field.WildCard != nil:
+			return nil, false
+		case field.AsName.O != "":
+			names = append(names, field.AsName.O)
+		default:
+			if col, ok := field.Expr.(*ast.ColumnNameExpr); ok {
+				names = append(names, col.Name.Name.O)
+				continue
+			}
+			name, _ := restoreToSql(field.Expr)
+			names = append(names, name)
+		}
+	}
+	return names, true
+}
+
+// viewRef is a view of a schema.
+type viewRef struct {
+	schema string
+	name   string
+	view   *ast.CreateViewStmt
+}
+
+// sortedViews returns the views of all schemas ordered by name, a view is
+// after the views it selects from.
+func (c *VirtualDB) sortedViews() []*viewRef {
+	refs := map[string]*viewRef{}
+	keys := []string{}
+	for schemaKey, schema := range c.schemas {
+		for viewKey, view := range schema.Views {
+			key := schemaKey + "." + viewKey
+			refs[key] = &viewRef{schema: schemaKey, name: viewKey, view: view.View}
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	sorted := []*viewRef{}
+	visited := map[string]bool{}
+	var visit func(key string)
+	visit = func(key string) {
+		ref, ok := refs[key]
+		if !ok || visited[key] {
+			return
+		}
+		visited[key] = true
+		deps := &viewRefCollector{}
+		ref.view.Select.Accept(deps)
+		for _, table := range deps.tables {
+			visit(c.nameKey(table.Schema.String()) + "." + c.nameKey(table.Name.String()))
+		}
+		sorted = append(sorted, ref)
+	}
+	for _, key := range keys {
+		visit(key)
+	}
+	return sorted
+}
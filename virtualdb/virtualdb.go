@@ -2,7 +2,9 @@ package virtualdb
 
 import (
 	"fmt"
+	"io"
 	"strings"
+	"sync"
 
 	"github.com/pingcap/parser"
 	"github.com/pingcap/parser/ast"
@@ -17,32 +19,92 @@ type TableInfo struct {
 type SchemaInfo struct {
 	Schema *ast.CreateDatabaseStmt
 	Tables map[string]*TableInfo
+	// Views are keyed by nameKey like the tables, the other objects are keyed by objectKey.
+	Views      map[string]*ViewInfo
+	Triggers   map[string]*ObjectInfo
+	Procedures map[string]*ObjectInfo
+	Functions  map[string]*ObjectInfo
+	Events     map[string]*ObjectInfo
 }
 
+// VirtualDB is safe for concurrent use. Tables are replaced rather than modified
+// in place, so the statements returned by the getters are never changed by the
+// later statements and must not be modified by callers.
 type VirtualDB struct {
+	mu sync.RWMutex
 	// currentSchema will change after sql "use database"
 	currentSchema string
-	schemas       map[string]*SchemaInfo
+	// schemas and the tables of each schema are keyed by nameKey.
+	schemas             map[string]*SchemaInfo
+	lowerCaseTableNames LowerCaseTableNames
+	serverVersion       int
+
+	observers []Observer
+	// events are queued by the running statements and sent after the lock is released.
+	events []Event
+	// notifySeq numbers the queued events under the lock, notifiedSeq is the
+	// number of the next events to deliver, it is guarded by notifyMu.
+	notifySeq   uint64
+	notifiedSeq uint64
+	notifyMu    sync.Mutex
+	notifyCond  *sync.Cond
 }
 
 func NewVirtualDB(defaultSchema string) *VirtualDB {
-	return &VirtualDB{
-		currentSchema: defaultSchema,
-		schemas: map[string]*SchemaInfo{defaultSchema: {
-			Schema: &ast.CreateDatabaseStmt{
-				Name: defaultSchema,
-			},
-			Tables: map[string]*TableInfo{},
-		}},
+	return NewVirtualDBWithOpt(defaultSchema, DBOption{})
+}
+
+func NewVirtualDBWithOpt(defaultSchema string, opt DBOption) *VirtualDB {
+	c := &VirtualDB{
+		schemas:             map[string]*SchemaInfo{},
+		lowerCaseTableNames: opt.LowerCaseTableNames,
+		serverVersion:       opt.ServerVersion,
+	}
+	if c.serverVersion <= 0 {
+		c.serverVersion = DefaultServerVersion
+	}
+	defaultSchema = c.storedName(defaultSchema)
+	c.currentSchema = defaultSchema
+	c.schemas[c.nameKey(defaultSchema)] = newSchemaInfo(&ast.CreateDatabaseStmt{
+		Name: defaultSchema,
+	})
+	return c
+}
+
+// nameKey returns the key of a schema or table name in the maps, the names
+// are compared by their keys.
+func (c *VirtualDB) nameKey(name string) string {
+	if c.lowerCaseTableNames == CaseSensitive {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// storedName returns the schema or table name as it is stored.
+func (c *VirtualDB) storedName(name string) string {
+	if c.lowerCaseTableNames == LowerCaseStored {
+		return strings.ToLower(name)
 	}
+	return name
+}
 
+// storedTableName returns the table name as it is stored, it is qualified by the
+// name of the schema as the schema is stored.
+func (c *VirtualDB) storedTableName(schemaName, tableName string) *ast.TableName {
+	if schema, exist := c.getSchema(schemaName); exist {
+		schemaName = schema.Schema.Name
+	}
+	return &ast.TableName{
+		Schema: model.NewCIStr(c.storedName(schemaName)),
+		Name:   model.NewCIStr(c.storedName(tableName)),
+	}
 }
 
 func (c *VirtualDB) useSchema(schema string) error {
 	if !c.hasSchema(schema) {
-		return fmt.Errorf(NotExistSchemaErrorPattern, schema)
+		return unknownDatabaseError(schema)
 	}
-	c.currentSchema = schema
+	c.currentSchema = c.storedName(schema)
 	return nil
 }
 
@@ -57,7 +119,7 @@ func (c *VirtualDB) getSchemaName(stmt *ast.TableName) string {
 }
 
 func (c *VirtualDB) getSchema(schemaName string) (*SchemaInfo, bool) {
-	schema, exist := c.schemas[schemaName]
+	schema, exist := c.schemas[c.nameKey(schemaName)]
 	return schema, exist
 }
 
@@ -66,11 +128,15 @@ func (c *VirtualDB) hasSchema(schemaName string) (has bool) {
 	return exist
 }
 
-func (c *VirtualDB) delSchema(name string) error {
-	if !c.hasSchema(name) {
-		return fmt.Errorf(NotExistSchemaErrorPattern, name)
+func (c *VirtualDB) delSchema(stmt *ast.DropDatabaseStmt) error {
+	name := stmt.Name
+	schema, exist := c.getSchema(name)
+	if !exist {
+		return newSchemaError(ErrCantDropDatabase, name, "",
+			fmt.Sprintf(NotExistSchemaErrorPattern, name))
 	}
-	delete(c.schemas, name)
+	delete(c.schemas, c.nameKey(name))
+	c.emit(&SchemaDropped{BaseEvent{stmt}, schema.Schema})
 	return nil
 }
 
@@ -78,24 +144,68 @@ func (c *VirtualDB) addSchema(schema *ast.CreateDatabaseStmt) error {
 	schemaName := schema.Name
 	exist := c.hasSchema(schemaName)
 	if !exist {
-		c.schemas[schemaName] = &SchemaInfo{
-			Schema: schema,
-			Tables: map[string]*TableInfo{},
-		}
+		schema.Name = c.storedName(schema.Name)
+		c.schemas[c.nameKey(schemaName)] = newSchemaInfo(schema)
+		c.emit(&SchemaCreated{BaseEvent{schema}, schema})
 		return nil
 	}
 	if schema.IfNotExists {
 		return nil
 	}
-	return fmt.Errorf(DuplicateSchemaErrorPattern, schemaName)
+	return newSchemaError(ErrDatabaseExists, schemaName, "",
+		fmt.Sprintf(DuplicateSchemaErrorPattern, schemaName))
+}
+
+// alterSchema applies the options of ALTER DATABASE to the schema, the schema
+// statement is replaced so the snapshots and events keep the old one.
+func (c *VirtualDB) alterSchema(stmt *ast.AlterDatabaseStmt) error {
+	name := stmt.Name
+	if stmt.AlterDefaultDatabase {
+		name = c.currentSchema
+	}
+	schema, exist := c.getSchema(name)
+	if !exist {
+		return unknownDatabaseError(name)
+	}
+
+	oldSchema := schema.Schema
+	newSchema := *oldSchema
+	newSchema.Options = mergeDatabaseOptions(oldSchema.Options, stmt.Options)
+	schema.Schema = &newSchema
+	c.emit(&SchemaAltered{BaseEvent{stmt}, oldSchema, &newSchema})
+	return nil
+}
+
+// mergeDatabaseOptions replaces the options of the same type. Like MySQL a new
+// charset without collation resets the collation to the default of the charset,
+// and a new collation without charset changes the charset to that of the collation.
+func mergeDatabaseOptions(options, newOptions []*ast.DatabaseOption) []*ast.DatabaseOption {
+	replaced := map[ast.DatabaseOptionType]struct{}{}
+	for _, op := range newOptions {
+		replaced[op.Tp] = struct{}{}
+		switch op.Tp {
+		case ast.DatabaseOptionCharset:
+			replaced[ast.DatabaseOptionCollate] = struct{}{}
+		case ast.DatabaseOptionCollate:
+			replaced[ast.DatabaseOptionCharset] = struct{}{}
+		}
+	}
+
+	merged := []*ast.DatabaseOption{}
+	for _, op := range options {
+		if _, ok := replaced[op.Tp]; !ok {
+			merged = append(merged, op)
+		}
+	}
+	return append(merged, newOptions...)
 }
 
 func (c *VirtualDB) getTable(schemaName, tableName string) (*TableInfo, bool, error) {
 	schema, SchemaExist := c.getSchema(schemaName)
 	if !SchemaExist {
-		return nil, false, fmt.Errorf(NotExistSchemaErrorPattern, schemaName)
+		return nil, false, unknownDatabaseError(schemaName)
 	}
-	table, tableExist := schema.Tables[tableName]
+	table, tableExist := schema.Tables[c.nameKey(tableName)]
 	return table, tableExist, nil
 }
 
@@ -110,9 +220,10 @@ func (c *VirtualDB) delTable(schemaName, tableName string) error {
 		return err
 	}
 	if !exist {
-		return fmt.Errorf(NotExistTableErrorPattern, schemaName, tableName)
+		return noSuchTableError(schemaName, tableName)
 	}
-	delete(c.schemas[schemaName].Tables, tableName)
+	schema, _ := c.getSchema(schemaName)
+	delete(schema.Tables, c.nameKey(tableName))
 	return nil
 }
 
@@ -121,27 +232,29 @@ func (c *VirtualDB) addTable(info *TableInfo) error {
 	schemaName := c.getSchemaName(table.Table)
 	tableName := table.Table.Name.String()
 
-	if table.Table.Schema.String() == "" {
-		table.Table.Schema = model.NewCIStr(schemaName)
-	}
-
 	exist, err := c.hasTable(schemaName, tableName)
 	if err != nil {
 		return err
 	}
+	// the views and tables share the names.
+	exist = exist || c.hasView(schemaName, tableName)
 	if !exist {
-		c.schemas[schemaName].Tables[tableName] = info
+		table.Table = c.storedTableName(schemaName, tableName)
+		schema, _ := c.getSchema(schemaName)
+		schema.Tables[c.nameKey(tableName)] = info
 		return nil
 	}
 	// if not exists, do nothing
 	if info.Table.IfNotExists {
 		return nil
 	}
-	return fmt.Errorf(DuplicateTableErrorPattern, schemaName, tableName)
+	return newSchemaError(ErrTableExists, schemaName, tableName,
+		fmt.Sprintf(DuplicateTableErrorPattern, schemaName, tableName))
 }
 
 func (c *VirtualDB) dropTables(stmt *ast.DropTableStmt) error {
 	errs := []string{}
+	unknownTables := []*ast.TableName{}
 	tables := []*ast.TableName{}
 	for _, table := range stmt.Tables {
 		schemaName := c.getSchemaName(table)
@@ -149,6 +262,7 @@ func (c *VirtualDB) dropTables(stmt *ast.DropTableStmt) error {
 		exist, err := c.hasTable(schemaName, tableName)
 		if err != nil {
 			errs = append(errs, err.Error())
+			unknownTables = append(unknownTables, table)
 			continue
 		}
 		if !exist {
@@ -160,25 +274,42 @@ func (c *VirtualDB) dropTables(stmt *ast.DropTableStmt) error {
 			tableName := table.Name.String()
 			msg := fmt.Sprintf(NotExistTableErrorPattern, schemaName, tableName)
 			errs = append(errs, msg)
+			unknownTables = append(unknownTables, table)
 			continue
 		}
 
 		tables = append(tables, table)
 	}
 	if len(errs) != 0 {
-		return fmt.Errorf(strings.Join(errs, ","))
+		return c.unknownTableError(unknownTables, strings.Join(errs, ","))
 	}
 	for _, table := range tables {
 		schemaName := c.getSchemaName(table)
 		tableName := table.Name.String()
+		info, _, _ := c.getTable(schemaName, tableName)
 		err := c.delTable(schemaName, tableName)
 		if err != nil {
 			return err
 		}
+		c.dropTableTriggers(stmt, schemaName, tableName)
+		c.emit(&TableDropped{BaseEvent{stmt}, schemaName, info.Table})
 	}
 	return nil
 }
 
+// unknownTableError returns the error of DROP TABLE, like MySQL it lists all
+// the unknown tables in one error.
+func (c *VirtualDB) unknownTableError(tables []*ast.TableName, msg string) error {
+	if len(tables) == 1 {
+		return newSchemaError(ErrUnknownTable, c.getSchemaName(tables[0]), tables[0].Name.String(), msg)
+	}
+	names := []string{}
+	for _, table := range tables {
+		names = append(names, c.getSchemaName(table)+"."+table.Name.String())
+	}
+	return newSchemaError(ErrUnknownTable, "", strings.Join(names, ","), msg)
+}
+
 func (c *VirtualDB) alertTable(alter *ast.AlterTableStmt) error {
 	schemaName := c.getSchemaName(alter.Table)
 	tableName := alter.Table.Name.String()
@@ -187,17 +318,23 @@ func (c *VirtualDB) alertTable(alter *ast.AlterTableStmt) error {
 		return err
 	}
 	if !exist {
-		return fmt.Errorf(NotExistTableErrorPattern, schemaName, tableName)
+		return noSuchTableError(schemaName, tableName)
 	}
 
-	newTable, err := c.mergeAlterToTable(info.Table, alter)
+	newTable, columns, err := c.mergeAlterToTable(info.Table, alter)
 	if err != nil {
 		return err
 	}
 
 	// rename table
+	newSchemaName := c.getSchemaName(newTable.Table)
 	newTableName := newTable.Table.Name.String()
-	if newTableName != tableName {
+	if c.nameKey(newSchemaName) != c.nameKey(schemaName) || c.nameKey(newTableName) != c.nameKey(tableName) {
+		// like MySQL a table with triggers can't be moved to another schema.
+		if c.nameKey(newSchemaName) != c.nameKey(schemaName) && c.hasTableTriggers(schemaName, tableName) {
+			return newSchemaError(ErrTriggerInWrongSchema, schemaName, tableName,
+				fmt.Sprintf(TriggerWrongSchemaErrorPattern, schemaName, tableName, newSchemaName))
+		}
 		newInfo := &TableInfo{Table: newTable}
 		err := c.addTable(newInfo)
 		if err != nil {
@@ -207,284 +344,275 @@ func (c *VirtualDB) alertTable(alter *ast.AlterTableStmt) error {
 		if err != nil {
 			return err
 		}
+		c.renameTableTriggers(alter, schemaName, tableName, newTableName)
 	} else {
-		info.Table = newTable
+		// the new name may differ from the old one in case.
+		newTable.Table = c.storedTableName(newSchemaName, newTableName)
+		schema, _ := c.getSchema(schemaName)
+		schema.Tables[c.nameKey(tableName)] = &TableInfo{Table: newTable}
 	}
+	c.emitAlterEvents(alter, info.Table, newTable, columns)
 	return nil
 }
 
-func (c *VirtualDB) mergeAlterToTable(oldTable *ast.CreateTableStmt,
-	alterTable *ast.AlterTableStmt) (*ast.CreateTableStmt, error) {
-
-	oldTableString, err := restoreToSql(oldTable)
+// createTable adds the table of CREATE TABLE.
+func (c *VirtualDB) createTable(stmt *ast.CreateTableStmt) error {
+	exist, err := c.hasTable(c.getSchemaName(stmt.Table), stmt.Table.Name.String())
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if exist && stmt.IfNotExists {
+		return nil
+	}
+	table, err := c.resolveCreateTable(stmt)
+	if err != nil {
+		return err
+	}
+	normalizeIndexes(table)
+	normalizeChecks(table)
+	if err := c.addTable(&TableInfo{Table: table}); err != nil {
+		return err
+	}
+	if !exist {
+		c.emit(&TableCreated{BaseEvent{stmt}, c.getSchemaName(table.Table), table})
 	}
-	tmpTable, err := parseCreateTableStmt(oldTableString)
+	return nil
+}
+
+func (c *VirtualDB) Exec(node ast.Node) error {
+	c.mu.Lock()
+	err := c.exec(node)
 	if err != nil {
-		return nil, err
+		c.events = nil
 	}
+	observers, events := c.takeEvents()
+	c.unlockAndNotify(observers, events)
+	return err
+}
 
-	schemaName := c.getSchemaName(tmpTable.Table)
-	tableName := tmpTable.Table.Name.String()
+func (c *VirtualDB) exec(node ast.Node) error {
+	switch s := node.(type) {
+	case *ast.UseStmt:
+		return c.useSchema(s.DBName)
 
-	// rename table
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableRenameTable) {
-		tmpTable.Table = spec.NewTable
-	}
-
-	// drop column
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableDropColumn) {
-		colExists := false
-		columnName := spec.OldColumnName.Name.L
-		for i, col := range tmpTable.Cols {
-			if columnName == col.Name.Name.L {
-				colExists = true
-				tmpTable.Cols = append(tmpTable.Cols[:i], tmpTable.Cols[i+1:]...)
-				break
-			}
-		}
-		if !colExists {
-			return nil, fmt.Errorf(NotExistColumnErrorPattern,
-				columnName, schemaName, tableName)
+	case *ast.CreateDatabaseStmt:
+		return c.addSchema(s)
+
+	case *ast.DropDatabaseStmt:
+		return c.delSchema(s)
+
+	case *ast.AlterDatabaseStmt:
+		return c.alterSchema(s)
+
+	case *ast.CreateTableStmt:
+		return c.createTable(s)
+
+	case *ast.DropTableStmt:
+		if s.IsView {
+			return c.dropViews(s)
 		}
+		return c.dropTables(s)
+
+	case *ast.CreateViewStmt:
+		return c.createView(s)
+
+	case *ast.AlterTableStmt:
+		return c.alertTable(s)
+	default:
 	}
+	return nil
+}
 
-	// change column
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableChangeColumn) {
-		colExists := false
-		columnName := spec.OldColumnName.Name.L
-		for i, col := range tmpTable.Cols {
-			if columnName == col.Name.Name.L {
-				colExists = true
-				tmpTable.Cols[i] = spec.NewColumns[0]
-				break
-			}
-		}
-		if !colExists {
-			return nil, fmt.Errorf(NotExistColumnErrorPattern,
-				columnName, schemaName, tableName)
-		}
+func (c *VirtualDB) ExecSQL(sql string) error {
+	return c.ExecSQLWithOpt(sql, ExecOption{})
+}
+
+// ExecSQLWithOpt executes the statements in sql, with ContinueOnError all the
+// statements are executed and the failures are returned as ExecErrors.
+func (c *VirtualDB) ExecSQLWithOpt(sql string, opt ExecOption) error {
+	if opt.ContinueOnError {
+		// split the statements first, so that a syntax error only fails its own statement.
+		return c.execScript(sql, opt)
 	}
 
-	// modify column
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableModifyColumn) {
-		colExists := false
-		columnName := spec.NewColumns[0].Name.Name.L
-		for i, col := range tmpTable.Cols {
-			if columnName == col.Name.Name.L {
-				colExists = true
-				tmpTable.Cols[i] = spec.NewColumns[0]
-				break
-			}
-		}
-		if !colExists {
-			return nil, fmt.Errorf(NotExistColumnErrorPattern,
-				columnName, schemaName, tableName)
+	p := parser.New()
+	stmts, _, err := p.Parse(sql, "", "")
+	if err != nil {
+		if !hasObjectStmt(sql) {
+			return err
 		}
+		return c.execScript(sql, opt)
 	}
 
-	// alter column
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableAlterColumn) {
-		colExists := false
-		newCol := spec.NewColumns[0]
-		columnName := newCol.Name.Name.L
-		for _, col := range tmpTable.Cols {
-			if columnName == col.Name.Name.L {
-				colExists = true
-				// alter table alter column drop default
-				if newCol.Options == nil {
-					for i, op := range col.Options {
-						if op.Tp == ast.ColumnOptionDefaultValue {
-							col.Options = append(col.Options[:i], col.Options[i+1:]...)
-						}
-					}
-				} else {
-					if hasOneInOptions(col.Options, ast.ColumnOptionDefaultValue) {
-						for i, op := range col.Options {
-							if op.Tp == ast.ColumnOptionDefaultValue {
-								col.Options[i] = newCol.Options[0]
-							}
-						}
-					} else {
-						col.Options = append(col.Options, newCol.Options...)
-					}
-				}
-			}
+	c.mu.Lock()
+	err = c.execStmts(sql, stmts, opt)
+	observers, events := c.takeEvents()
+	c.unlockAndNotify(observers, events)
+	return err
+}
+
+// execStmts executes the statements parsed from sql, the caller must hold the lock.
+func (c *VirtualDB) execStmts(sql string, stmts []ast.StmtNode, opt ExecOption) error {
+	errs := ExecErrors{}
+	offset := 0
+	for i, stmt := range stmts {
+		start := locateStmt(sql, stmt.Text(), offset)
+		offset = start + len(strings.TrimSpace(stmt.Text()))
+		eventCount := len(c.events)
+		err := c.exec(stmt)
+		if err == nil {
+			continue
 		}
-		if !colExists {
-			return nil, fmt.Errorf(NotExistColumnErrorPattern,
-				columnName, schemaName, tableName)
+		// the events of a failed statement are discarded.
+		c.events = c.events[:eventCount]
+		stmtErr := newStmtError(sql, i+1, start, stmt.Text(), err)
+		if !opt.ContinueOnError {
+			return stmtErr
 		}
+		errs = append(errs, stmtErr)
+	}
+	if len(errs) != 0 {
+		return errs
 	}
+	return nil
+}
 
-	// add column
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableAddColumns) {
-		for _, newCol := range spec.NewColumns {
-			colExist := false
-			columnName := newCol.Name.Name.L
-			for _, col := range tmpTable.Cols {
-				if columnName == col.Name.Name.L {
-					colExist = true
-					break
-				}
-			}
-			if colExist {
-				return nil, fmt.Errorf(DuplicateColumnErrorPattern,
-					columnName, schemaName, tableName)
-			}
-			tmpTable.Cols = append(tmpTable.Cols, newCol)
-		}
+// execScript executes sql which has the statements the parser doesn't support,
+// such as CREATE TRIGGER and CREATE PROCEDURE, the statements are split like the
+// mysql client so the DELIMITER command is supported.
+func (c *VirtualDB) execScript(sql string, opt ExecOption) error {
+	raws, err := scanStmts(sql, c.serverVersion)
+	if err != nil {
+		return err
 	}
 
-	// drop primary key
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableDropPrimaryKey) {
-		_ = spec
-		if !hasPrimaryKey(tmpTable) {
-			return nil, NoPrimaryKeyError
+	c.mu.Lock()
+	err = c.execRawStmts(parser.New(), raws, opt)
+	observers, events := c.takeEvents()
+	c.unlockAndNotify(observers, events)
+	return err
+}
+
+// execRawStmts executes the split statements, the caller must hold the lock.
+func (c *VirtualDB) execRawStmts(p *parser.Parser, raws []*rawStmt, opt ExecOption) error {
+	errs := ExecErrors{}
+	for _, raw := range raws {
+		if isSkippedDumpStmt(raw.text) {
+			continue
 		}
-		for i, constraint := range tmpTable.Constraints {
-			switch constraint.Tp {
-			case ast.ConstraintPrimaryKey:
-				tmpTable.Constraints = append(tmpTable.Constraints[:i], tmpTable.Constraints[i+1:]...)
-			}
+		eventCount := len(c.events)
+		err := c.execRawStmt(p, raw)
+		if err == nil {
+			continue
 		}
-		for _, col := range tmpTable.Cols {
-			for i, op := range col.Options {
-				switch op.Tp {
-				case ast.ColumnOptionPrimaryKey:
-					col.Options = append(col.Options[:i], col.Options[i+1:]...)
-				}
-			}
+		// the events of a failed statement are discarded.
+		c.events = c.events[:eventCount]
+		stmtErr := newRawStmtError(raw, err)
+		if !opt.ContinueOnError {
+			return stmtErr
 		}
+		errs = append(errs, stmtErr)
 	}
-
-	// drop index
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableDropIndex) {
-		indexName := spec.Name
-		constraintExists := false
-		for i, constraint := range tmpTable.Constraints {
-			if constraint.Name == indexName {
-				constraintExists = true
-				tmpTable.Constraints = append(tmpTable.Constraints[:i], tmpTable.Constraints[i+1:]...)
-			}
-		}
-		if !constraintExists {
-			return nil, fmt.Errorf(NotExistIndexErrorPattern,
-				indexName, schemaName, tableName)
-		}
+	if len(errs) != 0 {
+		return errs
 	}
+	return nil
+}
 
-	// rename index
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableRenameIndex) {
-		oldName := spec.FromKey
-		newName := spec.ToKey
+// ExecDump executes a mysqldump file. Unlike ExecSQL it supports the DELIMITER
+// command, evaluates the executable comments for opt.ServerVersion, and skips
+// the data and session statements such as INSERT, LOCK TABLES and SET without
+// parsing them.
+func (c *VirtualDB) ExecDump(sql string, opt ExecOption) error {
+	return c.ExecReader(strings.NewReader(sql), opt)
+}
 
-		// new name exist
-		newConstraintExist := false
-		for _, constraint := range tmpTable.Constraints {
-			if newName.String() == constraint.Name {
-				newConstraintExist = true
-				constraint.Name = newName.String()
-			}
+// ExecReader is like ExecDump but reads the SQL incrementally, and parses and
+// executes one statement at a time, so large files can be loaded with bounded
+// memory. The lock is taken for each statement rather than the whole input.
+func (c *VirtualDB) ExecReader(r io.Reader, opt ExecOption) error {
+	serverVersion := opt.ServerVersion
+	if serverVersion <= 0 {
+		serverVersion = c.serverVersion
+	}
+	scanner := newStmtScanner(r, serverVersion)
+	errs := ExecErrors{}
+	p := parser.New()
+	for {
+		raw, err := scanner.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
 		}
-		if newConstraintExist {
-			return nil, fmt.Errorf(DuplicateIndexErrorPattern,
-				newName, schemaName, tableName)
+		if isSkippedDumpStmt(raw.text) {
+			continue
 		}
 
-		oldConstraintExists := false
-		for _, constraint := range tmpTable.Constraints {
-			if oldName.String() == constraint.Name {
-				oldConstraintExists = true
-				constraint.Name = newName.String()
-			}
+		c.mu.Lock()
+		err = c.execRawStmt(p, raw)
+		if err != nil {
+			// the events of a failed statement are discarded.
+			c.events = nil
 		}
-		if !oldConstraintExists {
-			return nil, fmt.Errorf(NotExistIndexErrorPattern,
-				oldName, schemaName, tableName)
+		observers, events := c.takeEvents()
+		c.unlockAndNotify(observers, events)
+		if err == nil {
+			continue
 		}
-	}
-
-	// add index
-	for _, spec := range getAlterTableSpecByTp(alterTable.Specs, ast.AlterTableAddConstraint) {
-		switch spec.Constraint.Tp {
-		case ast.ConstraintPrimaryKey:
-			if hasPrimaryKey(tmpTable) {
-				return nil, ExistPrimaryKeyError
-			}
-			tmpTable.Constraints = append(tmpTable.Constraints, spec.Constraint)
-		default:
-			indexName := spec.Constraint.Name
-			constraintExists := false
-			for _, constraint := range tmpTable.Constraints {
-				if indexName == constraint.Name {
-					constraintExists = true
-					break
-				}
-			}
-			if constraintExists {
-				return nil, fmt.Errorf(DuplicateIndexErrorPattern,
-					indexName, schemaName, tableName)
-			}
-			tmpTable.Constraints = append(tmpTable.Constraints, spec.Constraint)
+		stmtErr := newRawStmtError(raw, err)
+		if !opt.ContinueOnError {
+			return stmtErr
 		}
+		errs = append(errs, stmtErr)
 	}
-	return tmpTable, nil
-}
-
-func (c *VirtualDB) Exec(node ast.Node) error {
-	switch s := node.(type) {
-	case *ast.UseStmt:
-		return c.useSchema(s.DBName)
-
-	case *ast.CreateDatabaseStmt:
-		return c.addSchema(s)
-
-	case *ast.DropDatabaseStmt:
-		return c.delSchema(s.Name)
-
-	case *ast.CreateTableStmt:
-		return c.addTable(&TableInfo{
-			Table: s,
-		})
-
-	case *ast.DropTableStmt:
-		return c.dropTables(s)
-
-	case *ast.AlterTableStmt:
-		return c.alertTable(s)
-	default:
+	if len(errs) != 0 {
+		return errs
 	}
 	return nil
 }
 
-func (c *VirtualDB) ExecSQL(sql string) error {
-	p := parser.New()
-	stmts, _, err := p.Parse(sql, "", "")
+// execRawStmt parses and executes the statement, the caller must hold the lock.
+func (c *VirtualDB) execRawStmt(p *parser.Parser, raw *rawStmt) error {
+	if stmt, ok := parseObjectStmt(raw.text); ok {
+		return c.execObjectStmt(stmt, &ObjectStmt{SQL: raw.text})
+	}
+	stmts, _, err := p.Parse(raw.text, "", "")
 	if err != nil {
 		return err
 	}
 	for _, stmt := range stmts {
-		err := c.Exec(stmt)
-		if err != nil {
+		if err := c.exec(stmt); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// Text restores all schemas and their objects to SQL, ordered by name. The
+// functions and procedures follow the tables since the views may call them, the
+// views follow, and a view follows the views it selects from. The triggers and
+// events come last, those objects whose bodies have semicolons are enclosed by
+// DELIMITER commands.
 func (c *VirtualDB) Text() (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	var sb strings.Builder
-	for _, schema := range c.schemas {
-		sql, err := restoreToSql(schema.Schema)
-		if err != nil {
-			return "", err
+	for _, schemaName := range sortedSchemaNames(c.schemas) {
+		schema := c.schemas[schemaName]
+		// the unnamed default schema is not a real database.
+		if schemaName != "" {
+			sql, err := restoreToSql(schema.Schema)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(sql)
+			sb.WriteString(";\n")
 		}
-		sb.WriteString(sql)
-		sb.WriteString(";\n")
-		for _, table := range schema.Tables {
-			sql, err := restoreToSql(table.Table)
+		for _, tableName := range sortedTableNames(schema.Tables) {
+			sql, err := restoreToSql(schema.Tables[tableName].Table)
 			if err != nil {
 				return "", err
 			}
@@ -492,27 +620,159 @@ func (c *VirtualDB) Text() (string, error) {
 			sb.WriteString(";\n")
 		}
 	}
+	c.writeObjects(&sb, ObjectFunction, ObjectProcedure)
+	for _, view := range c.sortedViews() {
+		sql, err := restoreToSql(view.view)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(sql)
+		sb.WriteString(";\n")
+	}
+	c.writeObjects(&sb, ObjectTrigger, ObjectEvent)
 	return sb.String(), nil
 }
 
+// writeObjects writes the objects of the types in all schemas, the caller must
+// hold the lock.
+func (c *VirtualDB) writeObjects(sb *strings.Builder, types ...ObjectType) {
+	for _, schemaName := range sortedSchemaNames(c.schemas) {
+		schema := c.schemas[schemaName]
+		for _, tp := range types {
+			objects := schema.objects(tp)
+			for _, name := range sortedObjectNames(objects) {
+				object := objects[name]
+				writeObjectSQL(sb, object.CreateSQL(schema.Schema.Name))
+				for _, sql := range object.AlterSQLs(schema.Schema.Name) {
+					writeObjectSQL(sb, sql)
+				}
+			}
+		}
+	}
+}
+
+// writeObjectSQL writes the statement of a trigger, routine or event, the
+// DELIMITER command is used if it has semicolons like mysqldump.
+func writeObjectSQL(sb *strings.Builder, sql string) {
+	if !strings.Contains(sql, ";") {
+		sb.WriteString(sql)
+		sb.WriteString(";\n")
+		return
+	}
+	sb.WriteString("DELIMITER ;;\n")
+	sb.WriteString(sql)
+	sb.WriteString(";;\nDELIMITER ;\n")
+}
+
+// GetSchema returns the CREATE DATABASE statement of the schema.
+func (c *VirtualDB) GetSchema(schemaName string) (*ast.CreateDatabaseStmt, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return nil, false
+	}
+	return schema.Schema, true
+}
+
+// ServerVersion returns the MySQL version the VirtualDB emulates.
+func (c *VirtualDB) ServerVersion() int {
+	return c.serverVersion
+}
+
+// GetTableStmts returns the tables of the schema, the map is a copy which is not
+// changed by the later statements.
 func (c *VirtualDB) GetTableStmts(schemaName string) (map[string]*TableInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return nil, false
+	}
+	tables := make(map[string]*TableInfo, len(schema.Tables))
+	for name, table := range schema.Tables {
+		tables[name] = table
+	}
+	return tables, true
+}
+
+// GetTable returns the table of the schema, the names are matched like the
+// statements according to lower_case_table_names.
+func (c *VirtualDB) GetTable(schemaName, tableName string) (*TableInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	table, exist, err := c.getTable(schemaName, tableName)
+	if err != nil || !exist {
+		return nil, false
+	}
+	return table, true
+}
+
+// GetViews returns the views of the schema, the map is a copy which is not
+// changed by the later statements.
+func (c *VirtualDB) GetViews(schemaName string) (map[string]*ViewInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	schema, exist := c.getSchema(schemaName)
+	if !exist {
+		return nil, false
+	}
+	views := make(map[string]*ViewInfo, len(schema.Views))
+	for name, view := range schema.Views {
+		views[name] = view
+	}
+	return views, true
+}
+
+// GetViewNames returns the keys of the views of the schema in GetViews, a view
+// follows the views it selects from.
+func (c *VirtualDB) GetViewNames(schemaName string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := []string{}
+	for _, view := range c.sortedViews() {
+		if view.schema == c.nameKey(schemaName) {
+			names = append(names, view.name)
+		}
+	}
+	return names
+}
+
+// GetObjects returns the triggers, procedures, functions or events of the
+// schema, the map is a copy which is not changed by the later statements.
+func (c *VirtualDB) GetObjects(schemaName string, tp ObjectType) (map[string]*ObjectInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	schema, exist := c.getSchema(schemaName)
 	if !exist {
 		return nil, false
 	}
-	return schema.Tables, true
+	objects := make(map[string]*ObjectInfo, len(schema.objects(tp)))
+	for name, object := range schema.objects(tp) {
+		objects[name] = object
+	}
+	return objects, true
 }
 
 func (c *VirtualDB) GetColumn(schemaName, tableName, columnName string) (*ast.ColumnDef, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	table, exist, err := c.getTable(schemaName, tableName)
 	if err != nil {
 		return nil, false, err
 	}
 	if !exist {
-		return nil, false, fmt.Errorf(NotExistTableErrorPattern, schemaName, tableName)
+		return nil, false, noSuchTableError(schemaName, tableName)
 	}
 	for _, col := range table.Table.Cols {
-		if col.Name.String() == columnName {
+		if strings.EqualFold(col.Name.String(), columnName) {
 			return col, true, nil
 		}
 	}
@@ -520,15 +780,18 @@ func (c *VirtualDB) GetColumn(schemaName, tableName, columnName string) (*ast.Co
 }
 
 func (c *VirtualDB) GetConstraints(schemaName, tableName, constraintName string) (*ast.Constraint, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	table, exist, err := c.getTable(schemaName, tableName)
 	if err != nil {
 		return nil, false, err
 	}
 	if !exist {
-		return nil, false, fmt.Errorf(NotExistTableErrorPattern, schemaName, tableName)
+		return nil, false, noSuchTableError(schemaName, tableName)
 	}
 	for _, con := range table.Table.Constraints {
-		if con.Name == constraintName {
+		if strings.EqualFold(con.Name, constraintName) {
 			return con, true, nil
 		}
 	}
@@ -1,6 +1,7 @@
 package virtualdb
 
 import (
+	"errors"
 	"fmt"
 	_ "github.com/pingcap/tidb/types/parser_driver"
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,11 @@ func testExec(t *testing.T, input string, expect string, expectError string) {
 	vb := NewVirtualDB("")
 	err := vb.ExecSQL(input)
 	if expectError != "" {
+		// compare the error of the statement without its location.
+		var stmtErr *StmtError
+		if errors.As(err, &stmtErr) {
+			err = stmtErr.Err
+		}
 		assert.EqualError(t, err, expectError)
 		return
 	}